@@ -4,8 +4,8 @@ import "time"
 
 type Accumulator interface {
 	// Create a point with a value, decorating it with tags
-	// NOTE: tags is expected to be owned by the caller, don't mutate
-	// it after passing to Add.
+	// NOTE: tags and fields are not kept or modified, the caller can reuse
+	// them after passing them to Add.
 	Add(measurement string,
 		value interface{},
 		tags map[string]string,
@@ -16,6 +16,39 @@ type Accumulator interface {
 		tags map[string]string,
 		t ...time.Time)
 
+	// AddCounter, AddGauge, AddSummary and AddHistogram add a point like
+	// AddFields, setting the type of the metric. See the typed constructors
+	// in metric.go for the fields summaries and histograms are made of.
+	AddCounter(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
+	AddGauge(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
+	AddSummary(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
+	AddHistogram(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
+	// AddError reports an error that occurred while gathering. Inputs should
+	// report every error this way, rather than only returning the last one
+	// from Gather, so that each of them is logged and counted.
+	AddError(err error)
+
 	Debug() bool
 	SetDebug(enabled bool)
+
+	// WithTracking returns an accumulator for the input to track the delivery
+	// of the metrics it adds, with at most maxTracked groups of them
+	// undelivered at a time. See TrackingAccumulator.
+	WithTracking(maxTracked int) TrackingAccumulator
 }
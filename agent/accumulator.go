@@ -2,13 +2,29 @@ package agent
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+var (
+	// totalGatherErrors counts the errors reported by all inputs.
+	totalGatherErrors = selfstat.Register("agent", "gather_errors", map[string]string{})
+	// totalMetricsGathered counts the metrics gathered by all inputs.
+	totalMetricsGathered = selfstat.Register("agent", "metrics_gathered", map[string]string{})
+	// totalGatherMetricsDropped counts the metrics dropped by all inputs
+	// because the metric channel was full.
+	totalGatherMetricsDropped = selfstat.Register("agent", "gather_metrics_dropped", map[string]string{})
+
+	// metricsChannelLen is the number of metrics waiting in the channel
+	// between the inputs and the flusher.
+	metricsChannelLen = selfstat.Register("agent", "metrics_channel_len", map[string]string{})
 )
 
 func NewAccumulator(
@@ -18,6 +34,16 @@ func NewAccumulator(
 	acc := accumulator{}
 	acc.metrics = metrics
 	acc.inputConfig = inputConfig
+	acc.metricsGathered = selfstat.Register(
+		"gather",
+		"metrics_gathered",
+		map[string]string{"input": inputConfig.Name},
+	)
+	acc.metricsDropped = selfstat.Register(
+		"gather",
+		"metrics_dropped",
+		map[string]string{"input": inputConfig.Name},
+	)
 	return &acc
 }
 
@@ -32,7 +58,30 @@ type accumulator struct {
 
 	inputConfig *internal_models.InputConfig
 
+	// input records the errors reported, for the status API, if set
+	input *internal_models.RunningInput
+
 	prefix string
+
+	// precision is what the timestamps of the metrics are rounded to
+	precision time.Duration
+
+	// collectionTime is the time of the metrics added without one, the time
+	// they are added at if it is zero
+	collectionTime time.Time
+
+	// overflow is what is done with a metric when the channel is full
+	overflow internal_models.OverflowPolicy
+
+	// dropping is 1 while the metric channel is full and metrics are dropped,
+	// so that it is only logged when it starts
+	dropping int32
+
+	// metricsGathered counts the metrics gathered by the input
+	metricsGathered selfstat.Stat
+
+	// metricsDropped counts the metrics dropped because the channel was full
+	metricsDropped selfstat.Stat
 }
 
 func (ac *accumulator) Add(
@@ -57,16 +106,76 @@ func (ac *accumulator) AddFields(
 	tags map[string]string,
 	t ...time.Time,
 ) {
+	ac.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (ac *accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (ac *accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (ac *accumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Summary, t...)
+}
+
+func (ac *accumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Histogram, t...)
+}
+
+func (ac *accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t ...time.Time,
+) {
+	if m := ac.makeMetric(measurement, fields, tags, mType, t...); m != nil {
+		ac.addMetric(m)
+	}
+}
+
+// makeMetric returns the metric the input adds, with the input's name
+// override, tags, filters and precision applied, or nil if it is filtered out.
+func (ac *accumulator) makeMetric(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t ...time.Time,
+) telegraf.Metric {
 	if len(fields) == 0 || len(measurement) == 0 {
-		return
+		return nil
 	}
 
 	if !ac.inputConfig.Filter.ShouldNamePass(measurement) {
-		return
+		return nil
 	}
 
 	if !ac.inputConfig.Filter.ShouldTagsPass(tags) {
-		return
+		return nil
 	}
 
 	// Override measurement name if set
@@ -81,22 +190,28 @@ func (ac *accumulator) AddFields(
 		measurement = measurement + ac.inputConfig.MeasurementSuffix
 	}
 
-	if tags == nil {
-		tags = make(map[string]string)
+	// The metric gets its own tags, as inputs may reuse theirs
+	mTags := make(map[string]string,
+		len(tags)+len(ac.inputConfig.Tags)+len(ac.defaultTags))
+	for k, v := range tags {
+		mTags[k] = v
 	}
 	// Apply plugin-wide tags if set
 	for k, v := range ac.inputConfig.Tags {
-		if _, ok := tags[k]; !ok {
-			tags[k] = v
+		if _, ok := mTags[k]; !ok {
+			mTags[k] = v
 		}
 	}
 	// Apply daemon-wide tags if set
 	for k, v := range ac.defaultTags {
-		if _, ok := tags[k]; !ok {
-			tags[k] = v
+		if _, ok := mTags[k]; !ok {
+			mTags[k] = v
 		}
 	}
-	ac.inputConfig.Filter.FilterTags(tags)
+	if !ac.inputConfig.Filter.ShouldValuesPass(mTags, fields) {
+		return nil
+	}
+	ac.inputConfig.Filter.FilterTags(mTags)
 
 	result := make(map[string]interface{})
 	for k, v := range fields {
@@ -107,23 +222,14 @@ func (ac *accumulator) AddFields(
 			}
 		}
 
-		// Validate uint64 and float64 fields
+		// Validate float64 fields
 		switch val := v.(type) {
-		case uint64:
-			// InfluxDB does not support writing uint64
-			if val < uint64(9223372036854775808) {
-				result[k] = int64(val)
-			} else {
-				result[k] = int64(9223372036854775807)
-			}
-			continue
 		case float64:
 			// NaNs are invalid values in influxdb, skip measurement
 			if math.IsNaN(val) || math.IsInf(val, 0) {
 				if ac.debug {
-					log.Printf("Measurement [%s] field [%s] has a NaN or Inf "+
-						"field, skipping",
-						measurement, k)
+					ac.logger().Debugf("Measurement [%s] field [%s] has a NaN "+
+						"or Inf field, skipping", measurement, k)
 				}
 				continue
 			}
@@ -133,29 +239,153 @@ func (ac *accumulator) AddFields(
 	}
 	fields = nil
 	if len(result) == 0 {
-		return
+		return nil
 	}
 
 	var timestamp time.Time
 	if len(t) > 0 {
 		timestamp = t[0]
+	} else if !ac.collectionTime.IsZero() {
+		timestamp = ac.collectionTime
 	} else {
 		timestamp = time.Now()
 	}
+	if ac.precision > 0 {
+		timestamp = timestamp.Round(ac.precision)
+	}
 
 	if ac.prefix != "" {
 		measurement = ac.prefix + measurement
 	}
 
-	m, err := telegraf.NewMetric(measurement, tags, result, timestamp)
+	m, err := telegraf.NewOwnedMetric(measurement, mTags, result, mType, timestamp)
 	if err != nil {
-		log.Printf("Error adding point [%s]: %s\n", measurement, err.Error())
-		return
+		ac.logger().Errorf("Error adding point [%s]: %s", measurement, err)
+		return nil
 	}
+	return m
+}
+
+// addMetric sends the metric to the agent, following the input's overflow
+// policy if the channel is full.
+func (ac *accumulator) addMetric(m telegraf.Metric) {
+	ac.sendMetric(m, ac.overflow)
+}
+
+// sendMetric sends the metric to the agent. If the channel is full, it waits
+// for room, or drops the metric or the oldest one in the channel, depending on
+// the policy. An unbuffered channel is always waited on.
+func (ac *accumulator) sendMetric(
+	m telegraf.Metric,
+	policy internal_models.OverflowPolicy,
+) {
 	if ac.debug {
 		fmt.Println("> " + m.String())
 	}
-	ac.metrics <- m
+
+	if cap(ac.metrics) == 0 {
+		policy = internal_models.OverflowBlock
+	}
+	switch policy {
+	case internal_models.OverflowDropNewest:
+		select {
+		case ac.metrics <- m:
+		default:
+			ac.dropMetric(m)
+			return
+		}
+	case internal_models.OverflowDropOldest:
+		for sent := false; !sent; {
+			select {
+			case ac.metrics <- m:
+				sent = true
+			default:
+				select {
+				case oldest := <-ac.metrics:
+					ac.dropMetric(oldest)
+				default:
+				}
+			}
+		}
+	default:
+		ac.metrics <- m
+	}
+	atomic.StoreInt32(&ac.dropping, 0)
+
+	totalMetricsGathered.Incr(1)
+	if ac.metricsGathered != nil {
+		ac.metricsGathered.Incr(1)
+	}
+}
+
+// dropMetric rejects a metric that could not be sent because the channel was
+// full, and counts it against the input.
+func (ac *accumulator) dropMetric(m telegraf.Metric) {
+	m.Reject()
+	totalGatherMetricsDropped.Incr(1)
+	if ac.metricsDropped != nil {
+		ac.metricsDropped.Incr(1)
+	}
+	if atomic.CompareAndSwapInt32(&ac.dropping, 0, 1) {
+		ac.logger().Warnf("Metric channel full, dropping metrics")
+	}
+}
+
+// AddError logs the error, tagged with the input's name, and counts it against
+// the input.
+func (ac *accumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	totalGatherErrors.Incr(1)
+	inputErrors(ac.inputConfig.Name).Incr(1)
+	if ac.input != nil {
+		ac.input.RecordError(err, time.Now())
+	}
+	ac.logger().Errorf("%s", err)
+}
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// trackingAccumulator is an accumulator that reports the delivery of the
+// metrics added with AddTrackingMetricGroup.
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (ac *trackingAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	metrics := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		// the input's metric is not kept, like the maps given to AddFields
+		m = ac.makeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time())
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+
+	// the metrics are never dropped: waiting for room in the channel is what
+	// pauses the input's reading from its source
+	metrics, id := telegraf.NewTrackingMetricGroup(metrics, ac.onDelivery)
+	for _, m := range metrics {
+		ac.sendMetric(m, internal_models.OverflowBlock)
+	}
+	return id
+}
+
+func (ac *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return ac.delivered
+}
+
+func (ac *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	ac.delivered <- info
 }
 
 func (ac *accumulator) Debug() bool {
@@ -170,6 +400,46 @@ func (ac *accumulator) setDefaultTags(tags map[string]string) {
 	ac.defaultTags = tags
 }
 
+// setPrecision sets the precision the timestamps of the metrics are rounded
+// to, 0 for no rounding.
+func (ac *accumulator) setPrecision(precision time.Duration) {
+	ac.precision = precision
+}
+
+// setOverflow sets what is done with the metrics added while the channel is
+// full.
+func (ac *accumulator) setOverflow(policy internal_models.OverflowPolicy) {
+	ac.overflow = policy
+}
+
+// setInput sets the input whose errors are recorded for the status API.
+func (ac *accumulator) setInput(input *internal_models.RunningInput) {
+	ac.input = input
+}
+
+// setCollectionTime sets the time given to the metrics that are added without
+// one, so that all metrics from a gather have the same time.
+func (ac *accumulator) setCollectionTime(t time.Time) {
+	ac.collectionTime = t
+}
+
+// logger returns the logger of the input, which prefixes its messages with
+// the input's name and alias.
+func (ac *accumulator) logger() telegraf.Logger {
+	return logger.New("inputs", ac.inputConfig.Name, ac.inputConfig.Alias)
+}
+
+// inputErrors returns the stat counting the errors reported by the named input
+// since the agent started.
+func inputErrors(name string) selfstat.Stat {
+	return selfstat.Register("gather", "errors", map[string]string{"input": name})
+}
+
+// gatherTime returns the stat timing the named input's Gather calls.
+func gatherTime(name string) selfstat.Stat {
+	return selfstat.RegisterTiming("gather", "gather_time_ns", map[string]string{"input": name})
+}
+
 func (ac *accumulator) addDefaultTag(key, value string) {
 	if ac.defaultTags == nil {
 		ac.defaultTags = make(map[string]string)
@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/telegraf/internal/models"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAdd(t *testing.T) {
@@ -95,6 +96,27 @@ func TestAddFields(t *testing.T) {
 }
 
 // Test that all Inf fields get dropped, and not added to metrics channel
+func TestAddTypedFields(t *testing.T) {
+	a := accumulator{}
+	a.metrics = make(chan telegraf.Metric, 10)
+	defer close(a.metrics)
+	a.inputConfig = &internal_models.InputConfig{}
+
+	fields := map[string]interface{}{"value": float64(101)}
+	a.AddFields("acctest", fields, map[string]string{})
+	a.AddCounter("acctest", fields, map[string]string{})
+	a.AddGauge("acctest", fields, map[string]string{})
+	a.AddSummary("acctest", fields, map[string]string{})
+	a.AddHistogram("acctest", fields, map[string]string{})
+
+	for _, mType := range []telegraf.ValueType{telegraf.Untyped,
+		telegraf.Counter, telegraf.Gauge, telegraf.Summary, telegraf.Histogram} {
+		testm := <-a.metrics
+		assert.Equal(t, mType, testm.Type())
+		assert.Contains(t, testm.String(), "acctest value=101")
+	}
+}
+
 func TestAddInfFields(t *testing.T) {
 	inf := math.Inf(1)
 	ninf := math.Inf(-1)
@@ -166,16 +188,16 @@ func TestAddUint64Fields(t *testing.T) {
 
 	testm := <-a.metrics
 	actual := testm.String()
-	assert.Contains(t, actual, "acctest usage=99i")
+	assert.Contains(t, actual, "acctest usage=99u")
 
 	testm = <-a.metrics
 	actual = testm.String()
-	assert.Contains(t, actual, "acctest,acc=test usage=99i")
+	assert.Contains(t, actual, "acctest,acc=test usage=99u")
 
 	testm = <-a.metrics
 	actual = testm.String()
 	assert.Equal(t,
-		fmt.Sprintf("acctest,acc=test usage=99i %d", now.UnixNano()),
+		fmt.Sprintf("acctest,acc=test usage=99u %d", now.UnixNano()),
 		actual)
 }
 
@@ -195,16 +217,16 @@ func TestAddUint64Overflow(t *testing.T) {
 
 	testm := <-a.metrics
 	actual := testm.String()
-	assert.Contains(t, actual, "acctest usage=9223372036854775807i")
+	assert.Contains(t, actual, "acctest usage=9223372036854775808u")
 
 	testm = <-a.metrics
 	actual = testm.String()
-	assert.Contains(t, actual, "acctest,acc=test usage=9223372036854775807i")
+	assert.Contains(t, actual, "acctest,acc=test usage=9223372036854775808u")
 
 	testm = <-a.metrics
 	actual = testm.String()
 	assert.Equal(t,
-		fmt.Sprintf("acctest,acc=test usage=9223372036854775807i %d", now.UnixNano()),
+		fmt.Sprintf("acctest,acc=test usage=9223372036854775808u %d", now.UnixNano()),
 		actual)
 }
 
@@ -332,3 +354,213 @@ func TestAccFilterTags(t *testing.T) {
 		fmt.Sprintf("acctest value=101 %d", now.UnixNano()),
 		actual)
 }
+
+func TestAccAddError(t *testing.T) {
+	a := accumulator{}
+	a.inputConfig = &internal_models.InputConfig{Name: "acc_add_error_test"}
+	errors := inputErrors("acc_add_error_test")
+	start := errors.Get()
+
+	a.AddError(fmt.Errorf("foo"))
+	a.AddError(fmt.Errorf("bar"))
+	a.AddError(nil)
+
+	assert.Equal(t, int64(2), errors.Get()-start)
+}
+
+func TestAddPrecision(t *testing.T) {
+	a := accumulator{}
+	a.metrics = make(chan telegraf.Metric, 10)
+	defer close(a.metrics)
+	a.inputConfig = &internal_models.InputConfig{}
+	a.setPrecision(time.Second)
+
+	now := time.Date(2016, time.May, 1, 10, 0, 0, 700000000, time.UTC)
+	a.AddFields("acctest", map[string]interface{}{"value": 101}, nil, now)
+	testm := <-a.metrics
+	assert.Equal(t, now.Add(300*time.Millisecond), testm.Time())
+
+	a.AddFields("acctest", map[string]interface{}{"value": 101}, nil)
+	testm = <-a.metrics
+	assert.Equal(t, int64(0), testm.UnixNano()%int64(time.Second))
+}
+
+func TestAddCollectionTime(t *testing.T) {
+	a := accumulator{}
+	a.metrics = make(chan telegraf.Metric, 10)
+	defer close(a.metrics)
+	a.inputConfig = &internal_models.InputConfig{}
+
+	collected := time.Date(2016, time.May, 1, 10, 0, 10, 0, time.UTC)
+	a.setCollectionTime(collected)
+	a.AddFields("acctest", map[string]interface{}{"value": 101}, nil)
+	a.AddGauge("acctest", map[string]interface{}{"value": 102}, nil)
+	// an explicit timestamp is kept
+	now := time.Now()
+	a.AddFields("acctest", map[string]interface{}{"value": 103}, nil, now)
+
+	assert.Equal(t, collected, (<-a.metrics).Time())
+	assert.Equal(t, collected, (<-a.metrics).Time())
+	assert.Equal(t, now, (<-a.metrics).Time())
+}
+
+func TestAddValueFilter(t *testing.T) {
+	a := accumulator{}
+	a.metrics = make(chan telegraf.Metric, 10)
+	defer close(a.metrics)
+	filter := internal_models.Filter{
+		ValueDrop: []string{`state == "idle"`, "usage > 99"},
+		// the conditions apply before the fields and tags are removed
+		FieldDrop:  []string{"usage"},
+		TagExclude: []string{"state"},
+	}
+	assert.NoError(t, filter.CompileFilter())
+	a.inputConfig = &internal_models.InputConfig{Filter: filter}
+
+	fields := map[string]interface{}{"usage": float64(50), "count": int64(1)}
+	a.AddFields("acctest", fields, map[string]string{"state": "idle"})
+	a.AddFields("acctest", fields, map[string]string{"state": "busy"})
+	a.AddFields("acctest", map[string]interface{}{"usage": float64(100)},
+		map[string]string{"state": "busy"})
+	// so do the conditions on numbers of any kind
+	a.AddFields("acctest", map[string]interface{}{"usage": int(100)},
+		map[string]string{"state": "busy"})
+	a.AddFields("acctest", map[string]interface{}{"usage": uint32(100)},
+		map[string]string{"state": "busy"})
+
+	assert.Len(t, a.metrics, 1)
+	testm := <-a.metrics
+	assert.Equal(t, map[string]interface{}{"count": int64(1)}, testm.Fields())
+	assert.Equal(t, map[string]string{}, testm.Tags())
+}
+
+func TestAddTrackingMetricGroup(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	a := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddTrackingMetricGroup",
+		Filter: internal_models.Filter{
+			IsActive: true,
+			NameDrop: []string{"dropped"},
+		},
+	}, metrics)
+	require.NoError(t, a.inputConfig.Filter.CompileFilter())
+	acc := a.WithTracking(10)
+
+	var group []telegraf.Metric
+	for _, name := range []string{"cpu", "dropped", "mem"} {
+		m, err := telegraf.NewMetric(name, map[string]string{},
+			map[string]interface{}{"value": 1}, time.Unix(0, 0))
+		require.NoError(t, err)
+		group = append(group, m)
+	}
+	id := acc.AddTrackingMetricGroup(group)
+
+	require.Len(t, metrics, 2)
+	(<-metrics).Accept()
+	assert.Len(t, acc.Delivered(), 0)
+	(<-metrics).Accept()
+	require.Len(t, acc.Delivered(), 1)
+	info := <-acc.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+
+	// a group without any metric passing the filters is delivered right away
+	id = acc.AddTrackingMetricGroup(group[1:2])
+	require.Len(t, acc.Delivered(), 1)
+	assert.Equal(t, id, (<-acc.Delivered()).ID())
+}
+
+func TestAddOverflowDropNewest(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 2)
+	a := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddOverflowDropNewest",
+	}, metrics)
+	a.setOverflow(internal_models.OverflowDropNewest)
+	dropped := a.metricsDropped.Get()
+
+	for i := 0; i < 3; i++ {
+		a.AddFields("acctest", map[string]interface{}{"value": i},
+			map[string]string{}, time.Unix(0, 0))
+	}
+
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(0), (<-metrics).Fields()["value"])
+	assert.Equal(t, int64(1), (<-metrics).Fields()["value"])
+	assert.Equal(t, int64(1), a.metricsDropped.Get()-dropped)
+}
+
+func TestAddOverflowDropOldest(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 2)
+	a := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddOverflowDropOldest",
+	}, metrics)
+	a.setOverflow(internal_models.OverflowDropOldest)
+	dropped := a.metricsDropped.Get()
+
+	for i := 0; i < 3; i++ {
+		a.AddFields("acctest", map[string]interface{}{"value": i},
+			map[string]string{}, time.Unix(0, 0))
+	}
+
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(1), (<-metrics).Fields()["value"])
+	assert.Equal(t, int64(2), (<-metrics).Fields()["value"])
+	assert.Equal(t, int64(1), a.metricsDropped.Get()-dropped)
+}
+
+func TestAddOverflowRejectsDroppedTrackedMetrics(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 1)
+	tracking := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddOverflowTracked",
+	}, metrics).WithTracking(1)
+	a := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddOverflowRejectsDroppedTrackedMetrics",
+	}, metrics)
+	a.setOverflow(internal_models.OverflowDropOldest)
+
+	m, err := telegraf.NewMetric("tracked", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, err)
+	id := tracking.AddTrackingMetricGroup([]telegraf.Metric{m})
+
+	a.AddFields("acctest", map[string]interface{}{"value": 1},
+		map[string]string{}, time.Unix(0, 0))
+
+	require.Len(t, tracking.Delivered(), 1)
+	info := <-tracking.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.False(t, info.Delivered())
+	assert.Equal(t, "acctest", (<-metrics).Name())
+}
+
+func TestAddTrackingMetricGroupWaitsWhenFull(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 1)
+	a := NewAccumulator(&internal_models.InputConfig{
+		Name: "TestAddTrackingMetricGroupWaitsWhenFull",
+	}, metrics)
+	a.setOverflow(internal_models.OverflowDropNewest)
+	acc := a.WithTracking(10)
+	dropped := a.metricsDropped.Get()
+
+	a.AddFields("acctest", map[string]interface{}{"value": 1},
+		map[string]string{}, time.Unix(0, 0))
+
+	m, err := telegraf.NewMetric("tracked", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, err)
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+	}()
+
+	select {
+	case <-added:
+		t.Fatal("tracked metric added to a full channel")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, "acctest", (<-metrics).Name())
+	<-added
+	assert.Equal(t, "tracked", (<-metrics).Name())
+	assert.Equal(t, int64(0), a.metricsDropped.Get()-dropped)
+}
@@ -8,79 +8,107 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+	// mu guards Config while it is replaced on reload, for the status API
+	mu sync.RWMutex
+
+	// reloadC passes a new config to Run
+	reloadC chan *config.Config
 }
 
 // NewAgent returns an Agent struct based off the given Config
-func NewAgent(config *config.Config) (*Agent, error) {
+func NewAgent(c *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:  c,
+		reloadC: make(chan *config.Config),
+	}
+
+	if err := prepareConfig(c); err != nil {
+		return nil, err
 	}
+	return a, nil
+}
 
-	if !a.Config.Agent.OmitHostname {
-		if a.Config.Agent.Hostname == "" {
+// prepareConfig sets the agent's hostname, and the host tag, in the config.
+func prepareConfig(c *config.Config) error {
+	if !c.Agent.OmitHostname {
+		if c.Agent.Hostname == "" {
 			hostname, err := os.Hostname()
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			a.Config.Agent.Hostname = hostname
+			c.Agent.Hostname = hostname
 		}
 
-		config.Tags["host"] = a.Config.Agent.Hostname
+		c.Tags["host"] = c.Agent.Hostname
 	}
-
-	return a, nil
+	return nil
 }
 
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
-		o.Quiet = a.Config.Agent.Quiet
-
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+		if err := a.connectOutput(o); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if a.Config.Agent.Debug {
-			log.Printf("Attempting connection to output: %s\n", o.Name)
-		}
-		err := o.Output.Connect()
-		if err != nil {
-			log.Printf("Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
-		}
-		if a.Config.Agent.Debug {
-			log.Printf("Successfully connected to output: %s\n", o.Name)
+// connectOutput opens the output's buffer, starts it if it is a service and
+// connects it.
+func (a *Agent) connectOutput(o *internal_models.RunningOutput) error {
+	o.Quiet = a.Config.Agent.Quiet
+
+	if err := o.OpenBuffer(); err != nil {
+		log.Printf("E! Failed to open buffer of output %s, exiting\n%s\n",
+			o.Name, err.Error())
+		return err
+	}
+
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.Name, err.Error())
+			return err
 		}
 	}
+
+	if a.Config.Agent.Debug {
+		log.Printf("I! Attempting connection to output: %s\n", o.Name)
+	}
+	// outputs that can't be reached yet keep connecting in the
+	// background, buffering their metrics meanwhile
+	o.Connect()
 	return nil
 }
 
 // Close closes the connection to all configured outputs
 func (a *Agent) Close() error {
+	return closeOutputs(a.Config.Outputs)
+}
+
+func closeOutputs(outputs []*internal_models.RunningOutput) error {
 	var err error
-	for _, o := range a.Config.Outputs {
+	for _, o := range outputs {
+		if cerr := o.Close(); cerr != nil {
+			log.Printf("E! Error closing buffer of output %s: %s\n", o.Name, cerr)
+		}
 		err = o.Output.Close()
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
@@ -94,20 +122,148 @@ func panicRecover(input *internal_models.RunningInput) {
 	if err := recover(); err != nil {
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
-		log.Printf("FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
+		log.Printf("E! FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
 			input.Name, err, trace)
-		log.Println("PLEASE REPORT THIS PANIC ON GITHUB with " +
+		log.Println("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new")
 	}
 }
 
+// reportErrors sends the number of errors reported by the input so far as a
+// telegraf_input_errors metric, tagged with the input name. Nothing is sent
+// until the input has reported its first error. It is sent through the input's
+// accumulator, following its metric_overflow policy, but not its filters.
+func (a *Agent) reportErrors(
+	input *internal_models.RunningInput,
+	acc *accumulator,
+) {
+	n := inputErrors(input.Name).Get()
+	if n == 0 {
+		return
+	}
+
+	tags := map[string]string{"input": input.Name}
+	for k, v := range a.Config.Tags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	fields := map[string]interface{}{"errors": n}
+
+	m, err := telegraf.NewCounterMetric("telegraf_input_errors", tags, fields, time.Now())
+	if err != nil {
+		log.Printf("E! Error adding point [telegraf_input_errors]: %s\n", err.Error())
+		return
+	}
+	acc.addMetric(m)
+}
+
+// gatherWithStats runs the input's Gather, reporting any error to the
+// accumulator, and records how long it took and how full the metric channel
+// is afterwards.
+func gatherWithStats(
+	input *internal_models.RunningInput,
+	acc *accumulator,
+	metricC chan telegraf.Metric,
+) {
+	start := time.Now()
+	if err := input.Input.Gather(acc); err != nil {
+		acc.AddError(err)
+	}
+	input.RecordGather(time.Now())
+	gatherTime(input.Name).Incr(time.Since(start).Nanoseconds())
+	metricsChannelLen.Set(int64(len(metricC)))
+}
+
+// gather runs the input's Gather. If it takes longer than the input's gather
+// timeout, it is abandoned: an error is recorded and gather returns while the
+// Gather keeps running. The input is then skipped until that Gather returns,
+// so that Gathers of the same input never overlap.
+func (a *Agent) gather(
+	input *internal_models.RunningInput,
+	acc *accumulator,
+	metricC chan telegraf.Metric,
+) {
+	timeout := input.Config.GatherTimeout
+	if timeout == 0 {
+		timeout = a.Config.Agent.GatherTimeout.Duration
+	}
+	if timeout == 0 {
+		gatherWithStats(input, acc, metricC)
+		return
+	}
+
+	if !input.StartGather() {
+		acc.AddError(fmt.Errorf("skipping gather, the previous one " +
+			"has not returned yet"))
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer panicRecover(input)
+		defer close(done)
+		defer input.EndGather()
+		gatherWithStats(input, acc, metricC)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		acc.AddError(fmt.Errorf("took longer to gather than timeout (%s), "+
+			"abandoning it", timeout))
+	}
+}
+
+// newAccumulator returns an accumulator for the metrics of the input, which
+// rounds their timestamps to the input's precision, or to the agent's, and
+// follows the input's overflow policy, or the agent's.
+func (a *Agent) newAccumulator(
+	input *internal_models.RunningInput,
+	metricC chan telegraf.Metric,
+) *accumulator {
+	acc := NewAccumulator(input.Config, metricC)
+	acc.setInput(input)
+	acc.SetDebug(a.Config.Agent.Debug)
+	acc.setDefaultTags(a.Config.Tags)
+
+	precision := input.Config.Precision
+	if precision == 0 {
+		// the precision was checked when the config was loaded
+		precision, _ = internal.ParsePrecision(a.Config.Agent.Precision)
+	}
+	acc.setPrecision(precision)
+
+	overflow := input.Config.Overflow
+	if overflow == internal_models.OverflowDefault {
+		// the policy was checked when the config was loaded
+		overflow, _ = internal_models.ParseOverflowPolicy(a.Config.Agent.MetricOverflow)
+	}
+	acc.setOverflow(overflow)
+	return acc
+}
+
+// collectionTime returns the time of a collection starting now. With
+// round_interval, it is the start of the interval the collection is in, so
+// that the metrics of every collection are exactly one interval apart.
+func (a *Agent) collectionTime(interval time.Duration) time.Time {
+	now := time.Now()
+	if !a.Config.Agent.RoundInterval || interval <= 0 {
+		return now
+	}
+	ns := now.UnixNano()
+	return time.Unix(0, ns-ns%int64(interval))
+}
+
 // gatherParallel runs the inputs that are using the same reporting interval
 // as the telegraf agent.
 func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
 	var wg sync.WaitGroup
 
 	start := time.Now()
+	now := a.collectionTime(a.Config.Agent.Interval.Duration)
 	counter := 0
 	jitter := a.Config.Agent.CollectionJitter.Duration.Nanoseconds()
 	for _, input := range a.Config.Inputs {
@@ -121,25 +277,22 @@ func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
 			defer panicRecover(input)
 			defer wg.Done()
 
-			acc := NewAccumulator(input.Config, metricC)
-			acc.SetDebug(a.Config.Agent.Debug)
-			acc.setDefaultTags(a.Config.Tags)
+			acc := a.newAccumulator(input, metricC)
+			acc.setCollectionTime(now)
 
 			if jitter != 0 {
 				nanoSleep := rand.Int63n(jitter)
 				d, err := time.ParseDuration(fmt.Sprintf("%dns", nanoSleep))
 				if err != nil {
-					log.Printf("Jittering collection interval failed for plugin %s",
+					log.Printf("W! Jittering collection interval failed for plugin %s",
 						input.Name)
 				} else {
 					time.Sleep(d)
 				}
 			}
 
-			if err := input.Input.Gather(acc); err != nil {
-				log.Printf("Error in input [%s]: %s", input.Name, err)
-			}
-
+			a.gather(input, acc, metricC)
+			a.reportErrors(input, acc)
 		}(input)
 	}
 
@@ -151,7 +304,7 @@ func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
 
 	elapsed := time.Since(start)
 	if !a.Config.Agent.Quiet {
-		log.Printf("Gathered metrics, (%s interval), from %d inputs in %s\n",
+		log.Printf("I! Gathered metrics, (%s interval), from %d inputs in %s\n",
 			a.Config.Agent.Interval.Duration, counter, elapsed)
 	}
 	return nil
@@ -166,23 +319,31 @@ func (a *Agent) gatherSeparate(
 ) error {
 	defer panicRecover(input)
 
+	// Round collection to nearest interval by sleeping
+	if a.Config.Agent.RoundInterval {
+		i := int64(input.Config.Interval)
+		select {
+		case <-shutdown:
+			return nil
+		case <-time.After(time.Duration(i - (time.Now().UnixNano() % i))):
+		}
+	}
 	ticker := time.NewTicker(input.Config.Interval)
+	defer ticker.Stop()
 
 	for {
 		var outerr error
 		start := time.Now()
 
-		acc := NewAccumulator(input.Config, metricC)
-		acc.SetDebug(a.Config.Agent.Debug)
-		acc.setDefaultTags(a.Config.Tags)
+		acc := a.newAccumulator(input, metricC)
+		acc.setCollectionTime(a.collectionTime(input.Config.Interval))
 
-		if err := input.Input.Gather(acc); err != nil {
-			log.Printf("Error in input [%s]: %s", input.Name, err)
-		}
+		a.gather(input, acc, metricC)
+		a.reportErrors(input, acc)
 
 		elapsed := time.Since(start)
 		if !a.Config.Agent.Quiet {
-			log.Printf("Gathered metrics, (separate %s interval), from %s in %s\n",
+			log.Printf("I! Gathered metrics, (separate %s interval), from %s in %s\n",
 				input.Config.Interval, input.Name, elapsed)
 		}
 
@@ -199,65 +360,164 @@ func (a *Agent) gatherSeparate(
 	}
 }
 
-// Test verifies that we can 'Gather' from all inputs with their configured
-// Config struct
-func (a *Agent) Test() error {
-	shutdown := make(chan struct{})
-	defer close(shutdown)
-	metricC := make(chan telegraf.Metric)
+// gatherOnce gathers every input once, all at the same time, and returns once
+// they are done.
+func (a *Agent) gatherOnce(metricC chan telegraf.Metric) {
+	var wg sync.WaitGroup
+	now := time.Now()
+	for _, input := range a.Config.Inputs {
+		wg.Add(1)
+		go func(input *internal_models.RunningInput) {
+			defer panicRecover(input)
+			defer wg.Done()
+
+			acc := a.newAccumulator(input, metricC)
+			acc.setCollectionTime(now)
+			a.gather(input, acc, metricC)
+		}(input)
+	}
+	wg.Wait()
+}
+
+// collectOnce starts the service inputs and gathers every input. If wait is
+// set, it then waits that long and gathers every input a second time, for
+// the service inputs to receive data and the inputs that report the rates
+// between two collections, like cpu. The service inputs are stopped before it
+// returns.
+func (a *Agent) collectOnce(metricC chan telegraf.Metric, wait time.Duration) error {
+	for i, input := range a.Config.Inputs {
+		if err := a.startService(input, metricC); err != nil {
+			for _, started := range a.Config.Inputs[:i] {
+				stopService(started)
+			}
+			return err
+		}
+	}
+
+	a.gatherOnce(metricC)
+	if wait > 0 {
+		time.Sleep(wait)
+		a.gatherOnce(metricC)
+	}
+
+	for _, input := range a.Config.Inputs {
+		stopService(input)
+	}
+	return nil
+}
+
+// Test gathers the metrics of every input, as collectOnce does, and prints
+// them to stdout in the given data format, ie, "influx", instead of writing
+// them to the outputs.
+func (a *Agent) Test(format string, wait time.Duration) error {
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: format,
+	})
+	if err != nil {
+		return err
+	}
 
-	// dummy receiver for the point channel
+	metricC := make(chan telegraf.Metric, 10000)
+	stop := make(chan struct{})
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		for {
 			select {
-			case <-metricC:
-				// do nothing
-			case <-shutdown:
+			case m := <-metricC:
+				printMetric(serializer, m)
+			case <-stop:
+				for n := len(metricC); n > 0; n-- {
+					printMetric(serializer, <-metricC)
+				}
 				return
 			}
 		}
 	}()
 
-	for _, input := range a.Config.Inputs {
-		acc := NewAccumulator(input.Config, metricC)
-		acc.SetDebug(true)
-		acc.setDefaultTags(a.Config.Tags)
+	err = a.collectOnce(metricC, wait)
+	close(stop)
+	<-done
+	return err
+}
 
-		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name)
-		if input.Config.Interval != 0 {
-			fmt.Printf("* Internal: %s\n", input.Config.Interval)
-		}
+// printMetric prints the metric to stdout. It is rejected, so that the
+// messages of the queue consumer inputs are not acknowledged.
+func printMetric(serializer serializers.Serializer, m telegraf.Metric) {
+	defer m.Reject()
+	lines, err := serializer.Serialize(m)
+	if err != nil {
+		log.Printf("E! Error serializing metric [%s]: %s\n", m.Name(), err)
+		return
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
 
-		if err := input.Input.Gather(acc); err != nil {
-			return err
+// Once gathers the metrics of every input, as collectOnce does, and writes
+// them to the outputs, applying the processors and aggregators. It returns an
+// error if any input reported an error or any output failed to write.
+func (a *Agent) Once(wait time.Duration) error {
+	flushInterval := jitterInterval(
+		a.Config.Agent.FlushInterval.Duration,
+		a.Config.Agent.FlushJitter.Duration)
+
+	metricC := make(chan telegraf.Metric, 10000)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := a.flusher(stop, metricC, flushInterval); err != nil {
+			log.Printf("E! Flusher routine failed: %s\n", err.Error())
 		}
+	}()
 
-		// Special instructions for some inputs. cpu, for example, needs to be
-		// run twice in order to return cpu usage percentages.
-		switch input.Name {
-		case "cpu", "mongodb", "procstat":
-			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name)
-			if err := input.Input.Gather(acc); err != nil {
-				return err
-			}
+	err := a.collectOnce(metricC, wait)
+	close(stop)
+	<-done
+	if err != nil {
+		return err
+	}
+
+	var failedInputs, failedOutputs []string
+	for _, input := range a.Config.Inputs {
+		if !input.Status().LastErrorTime.IsZero() {
+			failedInputs = append(failedInputs, input.Name)
+		}
+	}
+	for _, o := range a.Config.Outputs {
+		if err := o.Write(); err != nil {
+			log.Printf("E! Error writing to output [%s]: %s\n", o.Name, err)
+			failedOutputs = append(failedOutputs, o.Name)
 		}
+	}
 
+	var failed []string
+	if len(failedInputs) > 0 {
+		failed = append(failed, "inputs "+strings.Join(failedInputs, ", "))
+	}
+	if len(failedOutputs) > 0 {
+		failed = append(failed, "outputs "+strings.Join(failedOutputs, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Collection failed, errors in %s",
+			strings.Join(failed, " and "))
 	}
 	return nil
 }
 
-// flush writes a list of metrics to all configured outputs
-func (a *Agent) flush() {
+// flush writes the cached metrics of the given outputs
+func (a *Agent) flush(outputs []*internal_models.RunningOutput) {
 	var wg sync.WaitGroup
 
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
+	wg.Add(len(outputs))
+	for _, o := range outputs {
 		go func(output *internal_models.RunningOutput) {
 			defer wg.Done()
 			err := output.Write()
 			if err != nil {
-				log.Printf("Error writing to output [%s]: %s\n",
+				log.Printf("E! Error writing to output [%s]: %s\n",
 					output.Name, err.Error())
 			}
 		}(o)
@@ -266,27 +526,157 @@ func (a *Agent) flush() {
 	wg.Wait()
 }
 
-// flusher monitors the metrics input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) error {
+// runAggregators starts every configured aggregator, pushing their aggregates
+// on each aggregator's period. The aggregates are added to the outputs by a
+// goroutine of their own, so that the flusher can never block on them while
+// adding a metric to an aggregator. The returned channel is closed once every
+// aggregator has made its final push after shutdown.
+func (a *Agent) runAggregators(shutdown chan struct{}) chan struct{} {
+	aggC := make(chan telegraf.Metric, 100)
+	aggDone := make(chan struct{})
+
+	go func() {
+		defer close(aggDone)
+		for m := range aggC {
+			for _, o := range a.Config.Outputs {
+				o.AddMetric(m)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, aggregator := range a.Config.Aggregators {
+		wg.Add(1)
+		go func(aggregator *internal_models.RunningAggregator) {
+			defer wg.Done()
+			acc := NewAccumulator(aggregator.InputConfig(), aggC)
+			acc.SetDebug(a.Config.Agent.Debug)
+			acc.setDefaultTags(a.Config.Tags)
+			aggregator.Run(acc, shutdown)
+		}(aggregator)
+	}
+
+	go func() {
+		wg.Wait()
+		close(aggC)
+	}()
+
+	return aggDone
+}
+
+// flusher starts the aggregators, and monitors the metrics input channel and
+// flushes on the minimum interval, until stop is closed. It then adds the
+// metrics left in the channel to the outputs, and returns once the aggregators
+// have pushed their final aggregates.
+func (a *Agent) flusher(
+	stop chan struct{},
+	metricC chan telegraf.Metric,
+	flushInterval time.Duration,
+) error {
+	aggStop := make(chan struct{})
+	aggDone := a.runAggregators(aggStop)
+
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 200)
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
+	// outputs with a flush_interval of their own are flushed by a goroutine
+	// of their own, the others on the agent's flush interval
+	var outputs []*internal_models.RunningOutput
+	var wg sync.WaitGroup
+	for _, o := range a.Config.Outputs {
+		if o.Config.FlushInterval == 0 {
+			outputs = append(outputs, o)
+			continue
+		}
+		wg.Add(1)
+		go func(o *internal_models.RunningOutput) {
+			defer wg.Done()
+			a.flushOutput(stop, o)
+		}(o)
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-shutdown:
-			log.Println("Hang on, flushing any cached metrics before shutdown")
-			a.flush()
+		case <-stop:
+			// the inputs are done gathering, so the metrics left are added
+			// before the aggregators stop. Only as many as there are now are
+			// taken, service inputs keep adding metrics on a reload.
+			for n := len(metricC); n > 0; n-- {
+				a.addMetric(<-metricC)
+			}
+			close(aggStop)
+			<-aggDone
+			wg.Wait()
 			return nil
 		case <-ticker.C:
-			a.flush()
+			a.flush(outputs)
 		case m := <-metricC:
-			for _, o := range a.Config.Outputs {
-				o.AddMetric(m)
+			a.addMetric(m)
+		}
+	}
+}
+
+// addMetric applies the processors to the metric, adds it to the aggregators
+// and adds it, or what the processors made of it, to the outputs. If it is
+// tracked, every output holds the metrics it is given until it is done with
+// them.
+func (a *Agent) addMetric(m telegraf.Metric) {
+	// the agent is done with the metric once the outputs hold it
+	defer m.Drop()
+
+	metrics := []telegraf.Metric{m}
+	for _, processor := range a.Config.Processors {
+		metrics = processor.Apply(metrics...)
+	}
+	// the metrics a processor made in place of a tracked metric are
+	// delivered for it
+	metrics = telegraf.Inherit(m, metrics)
+	outMetrics := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		// the original metric is kept unless an aggregator that it was
+		// added to is configured with drop_original
+		dropOriginal := false
+		for _, aggregator := range a.Config.Aggregators {
+			if aggregator.Add(m) {
+				dropOriginal = true
 			}
 		}
+		if !dropOriginal {
+			telegraf.Hold(m, len(a.Config.Outputs))
+			outMetrics = append(outMetrics, m)
+		}
+	}
+	for _, o := range a.Config.Outputs {
+		for _, m := range outMetrics {
+			o.AddMetric(m)
+		}
+	}
+}
+
+// flushOutput flushes an output that has a flush_interval of its own, until
+// shutdown.
+func (a *Agent) flushOutput(
+	shutdown chan struct{},
+	o *internal_models.RunningOutput,
+) {
+	interval := jitterInterval(o.Config.FlushInterval, o.Config.FlushJitter)
+	if a.Config.Agent.Debug {
+		log.Printf("I! Flushing output %s every %s\n", o.Name, interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			a.flush([]*internal_models.RunningOutput{o})
+		}
 	}
 }
 
@@ -304,7 +694,7 @@ func jitterInterval(ininterval, injitter time.Duration) time.Duration {
 	}
 
 	if outinterval.Nanoseconds() < time.Duration(500*time.Millisecond).Nanoseconds() {
-		log.Printf("Flush interval %s too low, setting to 500ms\n", outinterval)
+		log.Printf("W! Flush interval %s too low, setting to 500ms\n", outinterval)
 		outinterval = time.Duration(500 * time.Millisecond)
 	}
 
@@ -313,78 +703,146 @@ func jitterInterval(ininterval, injitter time.Duration) time.Duration {
 
 // Run runs the agent daemon, gathering every Interval
 func (a *Agent) Run(shutdown chan struct{}) error {
-	var wg sync.WaitGroup
-
-	a.Config.Agent.FlushInterval.Duration = jitterInterval(
+	flushInterval := jitterInterval(
 		a.Config.Agent.FlushInterval.Duration,
 		a.Config.Agent.FlushJitter.Duration)
 
-	log.Printf("Agent Config: Interval:%s, Debug:%#v, Quiet:%#v, Hostname:%#v, "+
+	log.Printf("I! Agent Config: Interval:%s, Debug:%#v, Quiet:%#v, Hostname:%#v, "+
 		"Flush Interval:%s \n",
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Debug, a.Config.Agent.Quiet,
-		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
+		a.Config.Agent.Hostname, flushInterval)
 
-	// channel shared between all input threads for accumulating metrics
+	// channel shared between all input threads for accumulating metrics. It
+	// outlives config reloads, so that service inputs keep running.
 	metricC := make(chan telegraf.Metric, 10000)
 
-	for _, input := range a.Config.Inputs {
-		// Start service of any ServicePlugins
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input.Config, metricC)
-			acc.SetDebug(a.Config.Agent.Debug)
-			acc.setDefaultTags(a.Config.Tags)
-			if err := p.Start(acc); err != nil {
-				log.Printf("Service for input %s failed to start, exiting\n%s\n",
-					input.Name, err.Error())
-				return err
+	if address := a.Config.Agent.StatusAddress; address != "" {
+		listener, err := a.startStatusAPI(address)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+	}
+
+	for i, input := range a.Config.Inputs {
+		if err := a.startService(input, metricC); err != nil {
+			for _, started := range a.Config.Inputs[:i] {
+				stopService(started)
 			}
-			defer p.Stop()
+			return err
 		}
 	}
 
-	// Round collection to nearest interval by sleeping
+	// Round collection to nearest interval by sleeping, a shutdown ends the
+	// sleep early, to gather once and shut down
 	if a.Config.Agent.RoundInterval {
 		i := int64(a.Config.Agent.Interval.Duration)
-		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
+		select {
+		case <-shutdown:
+		case <-time.After(time.Duration(i - (time.Now().UnixNano() % i))):
+		}
 	}
 	ticker := time.NewTicker(a.Config.Agent.Interval.Duration)
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := a.flusher(shutdown, metricC); err != nil {
-			log.Printf("Flusher routine failed, exiting: %s\n", err.Error())
-			close(shutdown)
+	for {
+		// everything but the service inputs is stopped and started again
+		// when the config is reloaded
+		stop := make(chan struct{})
+		wg := a.start(stop, metricC, flushInterval)
+
+	gather:
+		for {
+			if err := a.gatherParallel(metricC); err != nil {
+				log.Printf("E! %s", err)
+			}
+
+			select {
+			case <-shutdown:
+				a.shutdown(stop, wg)
+				return nil
+			case c := <-a.reloadC:
+				close(stop)
+				wg.Wait()
+				a.applyConfig(c, metricC)
+				break gather
+			case <-ticker.C:
+				continue
+			}
 		}
+	}
+}
+
+// shutdown stops the agent started with stop. The service inputs stop
+// accepting data first, then the inputs that are gathering finish, every metric
+// gathered is added to the outputs, and the outputs are flushed a final time,
+// for at most the shutdown timeout.
+func (a *Agent) shutdown(stop chan struct{}, wg *sync.WaitGroup) {
+	for _, input := range a.Config.Inputs {
+		stopService(input)
+	}
+	close(stop)
+	wg.Wait()
+
+	log.Println("I! Hang on, flushing any cached metrics before shutdown")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.flush(a.Config.Outputs)
 	}()
 
+	timeout := a.Config.Agent.ShutdownTimeout.Duration
+	if timeout == 0 {
+		<-done
+		return
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf("W! Final flush took longer than shutdown_timeout (%s), "+
+			"abandoning it after the writes under way\n", timeout)
+	}
+}
+
+// start starts the aggregators, the flusher and the inputs that have their
+// own collection interval, until stop is closed. The flusher is stopped after
+// those inputs, so that it gets every metric they gathered.
+func (a *Agent) start(
+	stop chan struct{},
+	metricC chan telegraf.Metric,
+	flushInterval time.Duration,
+) *sync.WaitGroup {
+	var inputs sync.WaitGroup
 	for _, input := range a.Config.Inputs {
 		// Special handling for inputs that have their own collection interval
-		// configured. Default intervals are handled below with gatherParallel
+		// configured. Default intervals are handled by Run with gatherParallel
 		if input.Config.Interval != 0 {
-			wg.Add(1)
+			inputs.Add(1)
 			go func(input *internal_models.RunningInput) {
-				defer wg.Done()
-				if err := a.gatherSeparate(shutdown, input, metricC); err != nil {
-					log.Printf(err.Error())
+				defer inputs.Done()
+				if err := a.gatherSeparate(stop, input, metricC); err != nil {
+					log.Printf("E! %s", err)
 				}
 			}(input)
 		}
 	}
 
-	defer wg.Wait()
-
-	for {
-		if err := a.gatherParallel(metricC); err != nil {
-			log.Printf(err.Error())
+	var wg sync.WaitGroup
+	flusherStop := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		<-stop
+		inputs.Wait()
+		close(flusherStop)
+	}()
+	go func() {
+		defer wg.Done()
+		if err := a.flusher(flusherStop, metricC, flushInterval); err != nil {
+			log.Printf("E! Flusher routine failed: %s\n", err.Error())
 		}
+	}()
 
-		select {
-		case <-shutdown:
-			return nil
-		case <-ticker.C:
-			continue
-		}
-	}
+	return &wg
 }
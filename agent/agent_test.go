@@ -1,10 +1,14 @@
 package agent
 
 import (
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -182,3 +186,398 @@ func TestAgent_JitterMin(t *testing.T) {
 		}
 	}
 }
+
+// hangingInput blocks in Gather until release is closed.
+type hangingInput struct {
+	release chan struct{}
+	gathers int32
+}
+
+func (i *hangingInput) SampleConfig() string { return "" }
+func (i *hangingInput) Description() string  { return "" }
+
+func (i *hangingInput) Gather(acc telegraf.Accumulator) error {
+	atomic.AddInt32(&i.gathers, 1)
+	<-i.release
+	return nil
+}
+
+func TestAgent_GatherTimeout(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.GatherTimeout.Duration = 10 * time.Millisecond
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	hanging := &hangingInput{release: make(chan struct{})}
+	input := &internal_models.RunningInput{
+		Name:   "hanging_test",
+		Input:  hanging,
+		Config: &internal_models.InputConfig{Name: "hanging_test"},
+	}
+	metricC := make(chan telegraf.Metric, 10)
+	acc := NewAccumulator(input.Config, metricC)
+	// the error count is global, and kept between runs of the test
+	errors := inputErrors("hanging_test")
+	start := errors.Get()
+
+	a.gather(input, acc, metricC)
+	assert.Equal(t, int64(1), errors.Get()-start)
+
+	// the stuck Gather is not started again
+	a.gather(input, acc, metricC)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hanging.gathers))
+	assert.Equal(t, int64(2), errors.Get()-start)
+
+	close(hanging.release)
+	for i := 0; i < 100 && !input.StartGather(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	input.EndGather()
+
+	a.gather(input, acc, metricC)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hanging.gathers))
+	assert.Equal(t, int64(2), errors.Get()-start)
+}
+
+// Test that the error count of an input follows its overflow policy, rather
+// than blocking on a full channel.
+func TestAgent_ReportErrorsOverflow(t *testing.T) {
+	c := config.NewConfig()
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	input := &internal_models.RunningInput{
+		Name:  "report_errors_test",
+		Input: &hangingInput{},
+		Config: &internal_models.InputConfig{
+			Name:     "report_errors_test",
+			Overflow: internal_models.OverflowDropNewest,
+		},
+	}
+	metricC := make(chan telegraf.Metric, 1)
+	acc := a.newAccumulator(input, metricC)
+	acc.AddFields("full", map[string]interface{}{"value": 1}, nil)
+	inputErrors("report_errors_test").Incr(1)
+	dropped := acc.metricsDropped.Get()
+
+	reported := make(chan struct{})
+	go func() {
+		a.reportErrors(input, acc)
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reportErrors blocked on a full channel")
+	}
+	assert.Equal(t, int64(1), acc.metricsDropped.Get()-dropped)
+	assert.Equal(t, "full", (<-metricC).Name())
+}
+
+func TestAgent_CollectionTime(t *testing.T) {
+	c := config.NewConfig()
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	now := a.collectionTime(10 * time.Second)
+	assert.Equal(t, int64(0), now.UnixNano()%int64(10*time.Second))
+	assert.True(t, time.Since(now) < 10*time.Second)
+
+	c.Agent.RoundInterval = false
+	now = a.collectionTime(10 * time.Second)
+	assert.True(t, time.Since(now) < time.Second)
+}
+
+func TestAgent_InputPrecision(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Precision = "s"
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	input := &internal_models.RunningInput{
+		Name:   "test",
+		Config: &internal_models.InputConfig{Name: "test"},
+	}
+	metricC := make(chan telegraf.Metric, 10)
+	assert.Equal(t, time.Second, a.newAccumulator(input, metricC).precision)
+
+	input.Config.Precision = time.Millisecond
+	assert.Equal(t, time.Millisecond, a.newAccumulator(input, metricC).precision)
+}
+
+// stoppingInput is a service input that adds its metrics when it is stopped,
+// like statsd reporting the stats it received.
+type stoppingInput struct {
+	acc     telegraf.Accumulator
+	metrics int
+}
+
+func (i *stoppingInput) SampleConfig() string                  { return "" }
+func (i *stoppingInput) Description() string                   { return "" }
+func (i *stoppingInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (i *stoppingInput) Start(acc telegraf.Accumulator) error {
+	i.acc = acc
+	return nil
+}
+
+func (i *stoppingInput) Stop() {
+	for n := 0; n < i.metrics; n++ {
+		i.acc.AddFields("stopping", map[string]interface{}{"value": n}, nil)
+	}
+}
+
+// countingOutput counts the metrics written to it. Its Write blocks until
+// release is closed, if it is set.
+type countingOutput struct {
+	release chan struct{}
+	written int64
+}
+
+func (o *countingOutput) Connect() error       { return nil }
+func (o *countingOutput) Close() error         { return nil }
+func (o *countingOutput) Description() string  { return "" }
+func (o *countingOutput) SampleConfig() string { return "" }
+
+func (o *countingOutput) Write(metrics []telegraf.Metric) error {
+	if o.release != nil {
+		<-o.release
+	}
+	atomic.AddInt64(&o.written, int64(len(metrics)))
+	return nil
+}
+
+func runUntilShutdown(t *testing.T, c *config.Config) {
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, a.Run(shutdown))
+	}()
+	close(shutdown)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent didn't shut down")
+	}
+}
+
+func TestAgent_ShutdownAddsStoppedServiceMetrics(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "stopping",
+		Input:  &stoppingInput{metrics: 5000},
+		Config: &internal_models.InputConfig{Name: "stopping"},
+	})
+	output := &countingOutput{}
+	ro := internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"})
+	ro.MetricBufferLimit = 5000
+	c.Outputs = append(c.Outputs, ro)
+
+	runUntilShutdown(t, c)
+	assert.Equal(t, int64(5000), atomic.LoadInt64(&output.written))
+}
+
+// Test that a shutdown while waiting for the first round interval doesn't
+// wait for it, and still writes the metrics of the service inputs.
+func TestAgent_ShutdownDuringRoundInterval(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = true
+	c.Agent.Interval.Duration = time.Hour
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "stopping",
+		Input:  &stoppingInput{metrics: 10},
+		Config: &internal_models.InputConfig{Name: "stopping"},
+	})
+	output := &countingOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"}))
+
+	runUntilShutdown(t, c)
+	assert.Equal(t, int64(10), atomic.LoadInt64(&output.written))
+}
+
+func TestAgent_ShutdownTimeout(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Agent.ShutdownTimeout.Duration = 10 * time.Millisecond
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "stopping",
+		Input:  &stoppingInput{metrics: 1},
+		Config: &internal_models.InputConfig{Name: "stopping"},
+	})
+	output := &countingOutput{release: make(chan struct{})}
+	defer close(output.release)
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"}))
+
+	runUntilShutdown(t, c)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&output.written))
+}
+
+func TestAgent_AddTrackedMetric(t *testing.T) {
+	c := config.NewConfig()
+	for _, name := range []string{"first", "second"} {
+		c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+			name, &countingOutput{}, &internal_models.OutputConfig{Name: name}))
+	}
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	m, err := telegraf.NewMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Now())
+	assert.NoError(t, err)
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	metrics, _ := telegraf.NewTrackingMetricGroup([]telegraf.Metric{m},
+		func(info telegraf.DeliveryInfo) { delivered <- info })
+
+	a.addMetric(metrics[0])
+	assert.Len(t, delivered, 0)
+
+	a.flush(c.Outputs[:1])
+	assert.Len(t, delivered, 0)
+	a.flush(c.Outputs[1:])
+	assert.Len(t, delivered, 1)
+	assert.True(t, (<-delivered).Delivered())
+}
+
+// Test that a tracked metric that a processor replaces is only delivered once
+// the new metric is written.
+func TestAgent_AddTrackedMetricReplaced(t *testing.T) {
+	c := config.NewConfig()
+	c.Processors = append(c.Processors, &internal_models.RunningProcessor{
+		Name:      "replacing",
+		Processor: &replacingProcessor{},
+		Config:    &internal_models.ProcessorConfig{Name: "replacing"},
+	})
+	output := &countingOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"}))
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	m, err := telegraf.NewMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Now())
+	assert.NoError(t, err)
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	metrics, _ := telegraf.NewTrackingMetricGroup([]telegraf.Metric{m},
+		func(info telegraf.DeliveryInfo) { delivered <- info })
+
+	a.addMetric(metrics[0])
+	assert.Len(t, delivered, 0)
+
+	a.flush(c.Outputs)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&output.written))
+	assert.Len(t, delivered, 1)
+	assert.True(t, (<-delivered).Delivered())
+}
+
+// replacingProcessor replaces every metric with a new one.
+type replacingProcessor struct{}
+
+func (p *replacingProcessor) SampleConfig() string { return "" }
+func (p *replacingProcessor) Description() string  { return "" }
+
+func (p *replacingProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		replacement, err := telegraf.NewMetric(m.Name()+"_replaced", m.Tags(),
+			m.Fields(), m.Time())
+		if err == nil {
+			out = append(out, replacement)
+		}
+	}
+	return out
+}
+
+// gatheringInput adds a metric on every Gather, and returns err.
+type gatheringInput struct {
+	err     error
+	gathers int32
+}
+
+func (i *gatheringInput) SampleConfig() string { return "" }
+func (i *gatheringInput) Description() string  { return "" }
+
+func (i *gatheringInput) Gather(acc telegraf.Accumulator) error {
+	atomic.AddInt32(&i.gathers, 1)
+	acc.AddFields("gathering", map[string]interface{}{"value": 1}, nil)
+	return i.err
+}
+
+func TestAgent_Once(t *testing.T) {
+	c := config.NewConfig()
+	gathering := &gatheringInput{}
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "gathering",
+		Input:  gathering,
+		Config: &internal_models.InputConfig{Name: "gathering"},
+	}, &internal_models.RunningInput{
+		Name:   "stopping",
+		Input:  &stoppingInput{metrics: 3},
+		Config: &internal_models.InputConfig{Name: "stopping"},
+	})
+	output := &countingOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"}))
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	assert.NoError(t, a.Once(0))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gathering.gathers))
+	assert.Equal(t, int64(4), atomic.LoadInt64(&output.written))
+}
+
+func TestAgent_OnceWait(t *testing.T) {
+	c := config.NewConfig()
+	gathering := &gatheringInput{}
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "gathering",
+		Input:  gathering,
+		Config: &internal_models.InputConfig{Name: "gathering"},
+	})
+	output := &countingOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput(
+		"counting", output, &internal_models.OutputConfig{Name: "counting"}))
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	assert.NoError(t, a.Once(10*time.Millisecond))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gathering.gathers))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&output.written))
+}
+
+func TestAgent_OnceErrors(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "failing_once",
+		Input:  &gatheringInput{err: fmt.Errorf("permission denied")},
+		Config: &internal_models.InputConfig{Name: "failing_once"},
+	})
+	output := &countingOutput{}
+	c.Outputs = append(c.Outputs,
+		internal_models.NewRunningOutput(
+			"counting", output, &internal_models.OutputConfig{Name: "counting"}),
+		internal_models.NewRunningOutput(
+			"broken", &brokenOutput{}, &internal_models.OutputConfig{Name: "broken"}))
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	err = a.Once(0)
+	assert.EqualError(t, err,
+		"Collection failed, errors in inputs failing_once and outputs broken")
+	// the metric is still written to the outputs that work
+	assert.Equal(t, int64(1), atomic.LoadInt64(&output.written))
+}
+
+func TestAgent_TestInvalidFormat(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	assert.NoError(t, err)
+	assert.EqualError(t, a.Test("xml", 0), "Invalid data format: xml")
+}
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// healthResponse is the body of /health.
+type healthResponse struct {
+	// Status is "pass", or "fail" if an output has been failing for longer
+	// than the health_failure_threshold
+	Status         string          `json:"status"`
+	FailingOutputs []failingOutput `json:"failing_outputs,omitempty"`
+}
+
+type failingOutput struct {
+	Name         string    `json:"name"`
+	Alias        string    `json:"alias,omitempty"`
+	FailingSince time.Time `json:"failing_since"`
+	LastError    string    `json:"last_error"`
+}
+
+// statusResponse is the body of /status.
+type statusResponse struct {
+	Inputs  []inputStatus  `json:"inputs"`
+	Outputs []outputStatus `json:"outputs"`
+}
+
+type inputStatus struct {
+	Name          string     `json:"name"`
+	Alias         string     `json:"alias,omitempty"`
+	LastGather    *time.Time `json:"last_gather,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type outputStatus struct {
+	Name          string     `json:"name"`
+	Alias         string     `json:"alias,omitempty"`
+	BufferSize    int        `json:"buffer_size"`
+	BufferLimit   int        `json:"buffer_limit"`
+	FailingSince  *time.Time `json:"failing_since,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// startStatusAPI serves the status API on address until the returned
+// listener is closed.
+func (a *Agent) startStatusAPI(address string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting status API: %s", err)
+	}
+	log.Printf("I! Status API listening on %s\n", listener.Addr())
+	// Serve returns once the listener is closed
+	go http.Serve(listener, a.statusHandler())
+	return listener, nil
+}
+
+// statusHandler returns the handler of the status API:
+//
+//	/health  200 if no output has been failing to write for longer than the
+//	         health_failure_threshold, 503 otherwise
+//	/status  the inputs, when they last gathered and failed, and the outputs,
+//	         how full their buffers are and since when they are failing
+//	/config  the config files loaded, with the secrets redacted
+func (a *Agent) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.serveHealth)
+	mux.HandleFunc("/status", a.serveStatus)
+	mux.HandleFunc("/config", a.serveConfig)
+	return mux
+}
+
+// config returns the agent's current config, which is replaced on reload.
+func (a *Agent) config() *config.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config
+}
+
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r) {
+		return
+	}
+	c := a.config()
+	now := time.Now()
+	resp := healthResponse{Status: "pass"}
+	for _, o := range c.Outputs {
+		s := o.Status()
+		if s.FailingSince.IsZero() ||
+			now.Sub(s.FailingSince) < c.Agent.HealthFailureThreshold.Duration {
+			continue
+		}
+		resp.Status = "fail"
+		resp.FailingOutputs = append(resp.FailingOutputs, failingOutput{
+			Name:         s.Name,
+			Alias:        s.Alias,
+			FailingSince: s.FailingSince,
+			LastError:    s.LastError,
+		})
+	}
+
+	code := http.StatusOK
+	if resp.Status != "pass" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+func (a *Agent) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r) {
+		return
+	}
+	c := a.config()
+	resp := statusResponse{
+		Inputs:  make([]inputStatus, 0, len(c.Inputs)),
+		Outputs: make([]outputStatus, 0, len(c.Outputs)),
+	}
+	for _, input := range c.Inputs {
+		resp.Inputs = append(resp.Inputs, newInputStatus(input.Status()))
+	}
+	for _, o := range c.Outputs {
+		resp.Outputs = append(resp.Outputs, newOutputStatus(o.Status()))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *Agent) serveConfig(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, s := range a.config().Sources() {
+		fmt.Fprintf(w, "# %s\n", s.Path)
+		w.Write(s.Contents)
+		fmt.Fprintln(w)
+	}
+}
+
+func newInputStatus(s internal_models.InputStatus) inputStatus {
+	return inputStatus{
+		Name:          s.Name,
+		Alias:         s.Alias,
+		LastGather:    optionalTime(s.LastGather),
+		LastError:     s.LastError,
+		LastErrorTime: optionalTime(s.LastErrorTime),
+	}
+}
+
+func newOutputStatus(s internal_models.OutputStatus) outputStatus {
+	return outputStatus{
+		Name:          s.Name,
+		Alias:         s.Alias,
+		BufferSize:    s.BufferSize,
+		BufferLimit:   s.BufferLimit,
+		FailingSince:  optionalTime(s.FailingSince),
+		LastError:     s.LastError,
+		LastErrorTime: optionalTime(s.LastErrorTime),
+	}
+}
+
+// optionalTime returns nil for the zero time, so that it is left out of the
+// JSON.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// allowMethod reports whether the request is a GET or HEAD, answering 405
+// otherwise.
+func allowMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+	fmt.Fprintln(w)
+}
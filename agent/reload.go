@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Reload switches the running agent to the given config. Inputs and outputs
+// whose configuration didn't change keep running, and outputs keep the metrics
+// they have buffered. Inputs and outputs that were removed are stopped, after
+// a last flush for outputs, and new ones are started. Processors and
+// aggregators are replaced.
+//
+// The [agent] section and the global tags can't be changed while running. If
+// they differ, Reload returns an error and the agent must be restarted instead.
+func (a *Agent) Reload(c *config.Config) error {
+	if err := prepareConfig(c); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(a.Config.Agent, c.Agent) {
+		return fmt.Errorf("the [agent] configuration changed")
+	}
+	if !reflect.DeepEqual(a.Config.Tags, c.Tags) {
+		return fmt.Errorf("the [global_tags] configuration changed")
+	}
+
+	a.reloadC <- c
+	return nil
+}
+
+// applyConfig replaces the agent's config with c. Only the service inputs may
+// be running.
+func (a *Agent) applyConfig(c *config.Config, metricC chan telegraf.Metric) {
+	inputs, newInputs, oldInputs := reuseInputs(a.Config.Inputs, c.Inputs)
+	outputs, newOutputs, oldOutputs := reuseOutputs(a.Config.Outputs, c.Outputs)
+
+	for _, input := range oldInputs {
+		stopService(input)
+	}
+	if len(oldOutputs) > 0 {
+		a.flush(oldOutputs)
+		closeOutputs(oldOutputs)
+	}
+
+	c.Inputs = c.Inputs[:0]
+	for _, input := range inputs {
+		if newInputs[input] {
+			if err := a.startService(input, metricC); err != nil {
+				continue
+			}
+		}
+		c.Inputs = append(c.Inputs, input)
+	}
+	c.Outputs = c.Outputs[:0]
+	for _, output := range outputs {
+		if newOutputs[output] {
+			if err := a.connectOutput(output); err != nil {
+				continue
+			}
+		}
+		c.Outputs = append(c.Outputs, output)
+	}
+
+	log.Printf("I! Reloaded config, inputs: %d kept, %d stopped, %d started, "+
+		"outputs: %d kept, %d stopped, %d started\n",
+		len(inputs)-len(newInputs), len(oldInputs), len(newInputs),
+		len(outputs)-len(newOutputs), len(oldOutputs), len(newOutputs))
+	a.mu.Lock()
+	a.Config = c
+	a.mu.Unlock()
+}
+
+// reuse pairs the running plugins with the plugins of the new config that
+// have the same key. It returns, for every plugin of the new config, the index
+// of the running plugin to reuse or -1, and whether each running plugin is
+// reused.
+func reuse(running, next []string) ([]int, []bool) {
+	reused := make([]bool, len(running))
+	matches := make([]int, len(next))
+	for i, key := range next {
+		matches[i] = -1
+		for j, runningKey := range running {
+			if !reused[j] && key == runningKey {
+				matches[i] = j
+				reused[j] = true
+				break
+			}
+		}
+	}
+	return matches, reused
+}
+
+// reuseInputs returns the inputs of the new config, with the ones that have the
+// same name and configuration as a running input replaced by it. It also
+// returns which of those inputs are new, and the running inputs that are not
+// reused.
+func reuseInputs(
+	running, next []*internal_models.RunningInput,
+) (
+	[]*internal_models.RunningInput,
+	map[*internal_models.RunningInput]bool,
+	[]*internal_models.RunningInput,
+) {
+	keys := func(inputs []*internal_models.RunningInput) []string {
+		k := make([]string, len(inputs))
+		for i, input := range inputs {
+			k[i] = input.Name + "\n" + input.Fingerprint
+		}
+		return k
+	}
+	matches, reused := reuse(keys(running), keys(next))
+
+	inputs := make([]*internal_models.RunningInput, len(next))
+	added := make(map[*internal_models.RunningInput]bool)
+	for i, input := range next {
+		if matches[i] >= 0 {
+			inputs[i] = running[matches[i]]
+		} else {
+			inputs[i] = input
+			added[input] = true
+		}
+	}
+	var removed []*internal_models.RunningInput
+	for j, input := range running {
+		if !reused[j] {
+			removed = append(removed, input)
+		}
+	}
+	return inputs, added, removed
+}
+
+// reuseOutputs returns the outputs of the new config, with the ones that have
+// the same name and configuration as a running output replaced by it. It also
+// returns which of those outputs are new, and the running outputs that are not
+// reused.
+func reuseOutputs(
+	running, next []*internal_models.RunningOutput,
+) (
+	[]*internal_models.RunningOutput,
+	map[*internal_models.RunningOutput]bool,
+	[]*internal_models.RunningOutput,
+) {
+	keys := func(outputs []*internal_models.RunningOutput) []string {
+		k := make([]string, len(outputs))
+		for i, output := range outputs {
+			k[i] = output.Name + "\n" + output.Fingerprint
+		}
+		return k
+	}
+	matches, reused := reuse(keys(running), keys(next))
+
+	outputs := make([]*internal_models.RunningOutput, len(next))
+	added := make(map[*internal_models.RunningOutput]bool)
+	for i, output := range next {
+		if matches[i] >= 0 {
+			outputs[i] = running[matches[i]]
+		} else {
+			outputs[i] = output
+			added[output] = true
+		}
+	}
+	var removed []*internal_models.RunningOutput
+	for j, output := range running {
+		if !reused[j] {
+			removed = append(removed, output)
+		}
+	}
+	return outputs, added, removed
+}
+
+// startService starts the input if it is a service input.
+func (a *Agent) startService(
+	input *internal_models.RunningInput,
+	metricC chan telegraf.Metric,
+) error {
+	switch p := input.Input.(type) {
+	case telegraf.ServiceInput:
+		acc := a.newAccumulator(input, metricC)
+		if err := p.Start(acc); err != nil {
+			log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+				input.Name, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// stopService stops the input if it is a service input.
+func stopService(input *internal_models.RunningInput) {
+	switch p := input.Input.(type) {
+	case telegraf.ServiceInput:
+		p.Stop()
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testInput(name, fingerprint string) *internal_models.RunningInput {
+	return &internal_models.RunningInput{
+		Name:        name,
+		Config:      &internal_models.InputConfig{Name: name},
+		Fingerprint: fingerprint,
+	}
+}
+
+func testOutput(name, fingerprint string) *internal_models.RunningOutput {
+	return &internal_models.RunningOutput{
+		Name:        name,
+		Config:      &internal_models.OutputConfig{Name: name},
+		Fingerprint: fingerprint,
+	}
+}
+
+func TestAgent_ReuseInputs(t *testing.T) {
+	cpu := testInput("cpu", "{}")
+	mem := testInput("mem", "{}")
+	disk := testInput("disk", `{"mount_points"=["/"];}`)
+
+	newCPU := testInput("cpu", "{}")
+	newDisk := testInput("disk", `{"mount_points"=["/home"];}`)
+	net := testInput("net", "{}")
+
+	inputs, added, removed := reuseInputs(
+		[]*internal_models.RunningInput{cpu, mem, disk},
+		[]*internal_models.RunningInput{newCPU, newDisk, net},
+	)
+
+	assert.Equal(t, []*internal_models.RunningInput{cpu, newDisk, net}, inputs)
+	assert.Equal(t,
+		map[*internal_models.RunningInput]bool{newDisk: true, net: true}, added)
+	assert.Equal(t, []*internal_models.RunningInput{mem, disk}, removed)
+}
+
+// Test that identical outputs are paired one to one.
+func TestAgent_ReuseOutputs(t *testing.T) {
+	file1 := testOutput("file", "{}")
+	file2 := testOutput("file", "{}")
+	newFile1 := testOutput("file", "{}")
+
+	outputs, added, removed := reuseOutputs(
+		[]*internal_models.RunningOutput{file1, file2},
+		[]*internal_models.RunningOutput{newFile1},
+	)
+
+	assert.Equal(t, []*internal_models.RunningOutput{file1}, outputs)
+	assert.Empty(t, added)
+	assert.Equal(t, []*internal_models.RunningOutput{file2}, removed)
+}
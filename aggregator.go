@@ -0,0 +1,18 @@
+package telegraf
+
+type Aggregator interface {
+	// SampleConfig returns the default configuration of the Aggregator
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Aggregator
+	Description() string
+
+	// Add the metric to the aggregator
+	Add(in Metric)
+
+	// Push pushes the current aggregates to the accumulator
+	Push(acc Accumulator)
+
+	// Reset resets the aggregators caches and aggregates
+	Reset()
+}
@@ -8,13 +8,17 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/logger"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	_ "github.com/influxdata/telegraf/plugins/processors/all"
 )
 
 var fDebug = flag.Bool("debug", false,
@@ -22,6 +26,14 @@ var fDebug = flag.Bool("debug", false,
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fTestFormat = flag.String("test-format", "influx",
+	"data format of the metrics printed by -test: influx, json or graphite")
+var fTestWait = flag.Duration("test-wait", 0,
+	"with -test or -once, wait this long and gather the inputs a second time")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
+var fValidate = flag.Bool("validate", false,
+	"check the configuration for errors and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
@@ -45,6 +57,11 @@ var fOutputFiltersLegacy = flag.String("outputfilter", "",
 	"filter the outputs to enable, separator is :")
 var fConfigDirectoryLegacy = flag.String("configdirectory", "",
 	"directory containing additional *.conf files")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the config when the config file or directory changes")
+
+// How often the config files are checked for changes with -watch-config
+const configWatchInterval = 5 * time.Second
 
 // Telegraf version
 //	-ldflags "-X main.Version=`git describe --always --tags`"
@@ -60,8 +77,18 @@ The flags are:
 
   -config <file>     configuration file to load
   -test              gather metrics once, print them to stdout, and exit
+  -test-format       data format of the metrics printed by -test: influx (the
+                     default), json or graphite
+  -test-wait         with -test or -once, wait this long, ie, "5s", for the
+                     service inputs to receive data, and gather the inputs a
+                     second time, for those that report rates, like cpu
+  -once              gather metrics once, write them to the outputs, and exit
+                     non-zero if any input or output failed
+  -validate          check the configuration for errors, and exit non-zero if
+                     there are any (same as 'telegraf -config <file> config check')
   -sample-config     print out full sample configuration to stdout
   -config-directory  directory containing additional *.conf files
+  -watch-config      reload the config when the config file or directory changes
   -input-filter      filter the input plugins to enable, separator is :
   -input-list        print all the plugins inputs
   -output-filter     filter the output plugins to enable, separator is :
@@ -79,9 +106,18 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf -sample-config -input-filter cpu -output-filter influxdb
 
+  # check a config file and its config directory for errors
+  telegraf -config telegraf.conf -config-directory /etc/telegraf/telegraf.d -validate
+
   # run a single telegraf collection, outputing metrics to stdout
   telegraf -config telegraf.conf -test
 
+  # the same, as JSON, giving statsd 10s to receive metrics
+  telegraf -config telegraf.conf -test -test-format json -test-wait 10s
+
+  # run a single telegraf collection, writing the metrics to the outputs
+  telegraf -config telegraf.conf -once
+
   # run telegraf with all plugins defined in config file
   telegraf -config telegraf.conf
 
@@ -129,6 +165,10 @@ func main() {
 				fmt.Println(v)
 				return
 			case "config":
+				if len(args) > 1 && args[1] == "check" {
+					*fValidate = true
+					break
+				}
 				config.PrintSampleConfig(inputFilters, outputFilters)
 				return
 			}
@@ -164,101 +204,113 @@ func main() {
 		if *fUsage != "" {
 			if err := config.PrintInputConfig(*fUsage); err != nil {
 				if err2 := config.PrintOutputConfig(*fUsage); err2 != nil {
-					log.Fatalf("%s and %s", err, err2)
+					if err3 := config.PrintProcessorConfig(*fUsage); err3 != nil {
+						if err4 := config.PrintAggregatorConfig(*fUsage); err4 != nil {
+							log.Fatalf("E! %s, %s, %s and %s", err, err2, err3, err4)
+						}
+					}
 				}
 			}
 			return
 		}
 
-		var (
-			c   *config.Config
-			err error
-		)
-
-		if *fConfig != "" {
-			c = config.NewConfig()
-			c.OutputFilters = outputFilters
-			c.InputFilters = inputFilters
-			err = c.LoadConfig(*fConfig)
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
+		if *fConfig == "" {
 			fmt.Println("You must specify a config file. See telegraf --help")
 			os.Exit(1)
 		}
 
-		if *fConfigDirectoryLegacy != "" {
-			err = c.LoadDirectory(*fConfigDirectoryLegacy)
-			if err != nil {
-				log.Fatal(err)
+		if *fValidate {
+			if !validateConfig(inputFilters, outputFilters) {
+				os.Exit(1)
 			}
+			fmt.Println("Configuration is valid")
+			return
 		}
 
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-		if len(c.Outputs) == 0 {
-			log.Fatalf("Error: no outputs found, did you provide a valid config file?")
+		c, err := loadConfig(inputFilters, outputFilters)
+		if err != nil {
+			log.Fatal("E! " + err.Error())
 		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("Error: no inputs found, did you provide a valid config file?")
+		if err := logger.Setup(c.Agent.LoggerConfig()); err != nil {
+			log.Fatal("E! " + err.Error())
 		}
 
 		ag, err := agent.NewAgent(c)
 		if err != nil {
-			log.Fatal(err)
-		}
-
-		if *fDebug {
-			ag.Config.Agent.Debug = true
+			log.Fatal("E! " + err.Error())
 		}
 
-		if *fQuiet {
-			ag.Config.Agent.Quiet = true
+		if *fTest {
+			err = ag.Test(*fTestFormat, *fTestWait)
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		}
 
-		if *fTest {
-			err = ag.Test()
+		if *fOnce {
+			if err = ag.Connect(); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			err = ag.Once(*fTestWait)
+			ag.Close()
 			if err != nil {
-				log.Fatal(err)
+				log.Fatal("E! " + err.Error())
 			}
 			return
 		}
 
 		err = ag.Connect()
 		if err != nil {
-			log.Fatal(err)
+			log.Fatal("E! " + err.Error())
 		}
 
 		shutdown := make(chan struct{})
 		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+		var configChanged chan struct{}
+		if *fWatchConfig {
+			configChanged = config.Watch([]string{*fConfig}, configDirs(),
+				configWatchInterval, shutdown)
+		}
+
 		go func() {
-			sig := <-signals
-			if sig == os.Interrupt {
-				close(shutdown)
-			}
-			if sig == syscall.SIGHUP {
-				log.Printf("Reloading Telegraf config\n")
-				<-reload
-				reload <- true
-				close(shutdown)
+			defer signal.Stop(signals)
+			for {
+				select {
+				case sig := <-signals:
+					// SIGTERM is how service managers, like systemd
+					// and docker, stop telegraf
+					if sig == os.Interrupt || sig == syscall.SIGTERM {
+						close(shutdown)
+						return
+					}
+					log.Printf("I! Reloading Telegraf config\n")
+				case <-configChanged:
+					log.Printf("I! Config changed, reloading Telegraf config\n")
+				}
+
+				if !reloadConfig(ag, inputFilters, outputFilters) {
+					<-reload
+					reload <- true
+					close(shutdown)
+					return
+				}
 			}
 		}()
 
-		log.Printf("Starting Telegraf (version %s)\n", Version)
-		log.Printf("Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-		log.Printf("Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-		log.Printf("Tags enabled: %s", c.ListTags())
+		log.Printf("I! Starting Telegraf (version %s)\n", Version)
+		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+		log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+		log.Printf("I! Loaded processors: %s", strings.Join(c.ProcessorNames(), " "))
+		log.Printf("I! Loaded aggregators: %s", strings.Join(c.AggregatorNames(), " "))
+		log.Printf("I! Tags enabled: %s", c.ListTags())
 
 		if *fPidfile != "" {
 			f, err := os.Create(*fPidfile)
 			if err != nil {
-				log.Fatalf("Unable to create pidfile: %s", err)
+				log.Fatalf("E! Unable to create pidfile: %s", err)
 			}
 
 			fmt.Fprintf(f, "%d\n", os.Getpid())
@@ -267,7 +319,108 @@ func main() {
 		}
 
 		ag.Run(shutdown)
+		// close the outputs, and their buffers, before a reload reopens them
+		ag.Close()
+	}
+}
+
+// loadConfig loads the config file and directories given on the command line.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := c.LoadConfig(*fConfig); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range configDirs() {
+		if err := c.LoadDirectory(dir); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+
+	if *fDebug {
+		c.Agent.Debug = true
+	}
+
+	if *fQuiet {
+		c.Agent.Quiet = true
+	}
+	return c, nil
+}
+
+// validateConfig loads the config file and directories given on the command
+// line in strict mode, and prints every problem found in them. It returns
+// whether there were none.
+func validateConfig(inputFilters, outputFilters []string) bool {
+	c := config.NewConfig()
+	c.Strict = true
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+
+	valid := true
+	report := func(err error) {
+		if err == nil {
+			return
+		}
+		valid = false
+		if errs, ok := err.(config.Errors); ok {
+			for _, err := range errs {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	report(c.LoadConfig(*fConfig))
+	for _, dir := range configDirs() {
+		report(c.LoadDirectory(dir))
+	}
+	// plugins with errors are not loaded, so only check these on their own
+	if valid && len(c.Outputs) == 0 {
+		report(fmt.Errorf("Error: no outputs found, did you provide a valid config file?"))
+	}
+	if valid && len(c.Inputs) == 0 {
+		report(fmt.Errorf("Error: no inputs found, did you provide a valid config file?"))
+	}
+	return valid
+}
+
+// configDirs returns the config directories given on the command line.
+func configDirs() []string {
+	var dirs []string
+	for _, dir := range []string{*fConfigDirectoryLegacy, *fConfigDirectory} {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// reloadConfig loads the config again and applies it to the running agent.
+// It returns false if the agent has to be restarted to apply it.
+func reloadConfig(ag *agent.Agent, inputFilters, outputFilters []string) bool {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Printf("E! Error reloading config, keeping the running one: %s\n", err)
+		return true
+	}
+	if err := logger.Setup(c.Agent.LoggerConfig()); err != nil {
+		log.Printf("E! Error reloading config, keeping the running one: %s\n", err)
+		return true
+	}
+	if err := ag.Reload(c); err != nil {
+		log.Printf("I! Restarting Telegraf, %s\n", err)
+		return false
 	}
+	return true
 }
 
 func usageExit(rc int) {
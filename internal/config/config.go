@@ -6,19 +6,21 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers"
 
 	"github.com/influxdata/config"
@@ -33,9 +35,6 @@ var (
 
 	// Default output plugins
 	outputDefaults = []string{"influxdb"}
-
-	// envVarRe is a regex to find environment variables in the config file
-	envVarRe = regexp.MustCompile(`\$\w+`)
 )
 
 // Config specifies the URL/user/password for the database that telegraf
@@ -46,9 +45,19 @@ type Config struct {
 	InputFilters  []string
 	OutputFilters []string
 
-	Agent   *AgentConfig
-	Inputs  []*internal_models.RunningInput
-	Outputs []*internal_models.RunningOutput
+	// Strict makes unknown options in the config an error. Otherwise they
+	// are logged and ignored.
+	Strict bool
+	// file being loaded, to locate warnings
+	file string
+	// sources are the files loaded, as read
+	sources []Source
+
+	Agent       *AgentConfig
+	Inputs      []*internal_models.RunningInput
+	Outputs     []*internal_models.RunningOutput
+	Processors  internal_models.RunningProcessors
+	Aggregators []*internal_models.RunningAggregator
 }
 
 func NewConfig() *Config {
@@ -59,11 +68,16 @@ func NewConfig() *Config {
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
 			FlushJitter:   internal.Duration{Duration: 5 * time.Second},
+
+			LogfileRotationMaxArchives: 5,
+			HealthFailureThreshold:     internal.Duration{Duration: 5 * time.Minute},
 		},
 
 		Tags:          make(map[string]string),
 		Inputs:        make([]*internal_models.RunningInput, 0),
 		Outputs:       make([]*internal_models.RunningOutput, 0),
+		Processors:    make([]*internal_models.RunningProcessor, 0),
+		Aggregators:   make([]*internal_models.RunningAggregator, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 	}
@@ -84,6 +98,11 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// GatherTimeout is the default time an input may take to gather. A Gather
+	// that takes longer is abandoned, and the input is skipped until it
+	// returns. 0 means no timeout.
+	GatherTimeout internal.Duration
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -93,6 +112,11 @@ type AgentConfig struct {
 	// ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
 	FlushJitter internal.Duration
 
+	// ShutdownTimeout is how long the final flush of the outputs may take on
+	// shutdown. Metrics that aren't written by then are lost. 0 means no
+	// timeout.
+	ShutdownTimeout internal.Duration
+
 	// MetricBufferLimit is the max number of metrics that each output plugin
 	// will cache. The buffer is cleared when a successful write occurs. When
 	// full, the oldest metrics will be overwritten.
@@ -103,21 +127,70 @@ type AgentConfig struct {
 	// does _not_ deactivate FlushInterval.
 	FlushBufferWhenFull bool
 
-	// TODO(cam): Remove UTC and Precision parameters, they are no longer
-	// valid for the agent config. Leaving them here for now for backwards-
-	// compatability
-	UTC       bool `toml:"utc"`
+	// Precision rounds the timestamps of the metrics gathered by the inputs,
+	// ie, "1s" rounds them to the second. It can also be a unit, "s", as in
+	// older config files. Inputs can override it.
 	Precision string
 
-	// Debug is the option for running in debug mode
+	// MetricOverflow is what the inputs do with their metrics when the channel
+	// to the outputs is full: "block" until there is room, which is the
+	// default, or "drop_newest" or "drop_oldest". Inputs can override it.
+	MetricOverflow string
+
+	// TODO(cam): Remove the UTC parameter, it is no longer valid for the
+	// agent config. Leaving it here for now for backwards-compatability
+	UTC bool `toml:"utc"`
+
+	// Debug is the option for running in debug mode, which also logs debug
+	// messages
 	Debug bool
 
-	// Quiet is the option for running in quiet mode
-	Quiet        bool
+	// Quiet is the option for running in quiet mode, which only logs errors
+	// and warnings
+	Quiet bool
+
+	// Logfile is the file the logs are written to, stderr if empty
+	Logfile string
+
+	// LogfileRotationMaxSize is the size the log file is rotated at. 0 means
+	// it is never rotated.
+	LogfileRotationMaxSize internal.Size
+
+	// LogfileRotationMaxArchives is the number of rotated log files kept.
+	LogfileRotationMaxArchives int
+
+	// LogFormat is "text", or "json" for a JSON object per line.
+	LogFormat string
+
+	// StatusAddress is the address the status API listens on, ie,
+	// "localhost:8099". It is disabled if empty.
+	StatusAddress string
+
+	// HealthFailureThreshold is how long an output may fail to write before
+	// the status API reports the agent as unhealthy.
+	HealthFailureThreshold internal.Duration
+
 	Hostname     string
 	OmitHostname bool
 }
 
+// LoggerConfig returns where the logs are written, and how.
+func (a *AgentConfig) LoggerConfig() logger.Config {
+	level := logger.LevelInfo
+	if a.Debug {
+		level = logger.LevelDebug
+	} else if a.Quiet {
+		level = logger.LevelWarn
+	}
+	return logger.Config{
+		Level:               level,
+		Logfile:             a.Logfile,
+		RotationMaxSize:     a.LogfileRotationMaxSize.Size,
+		RotationMaxArchives: a.LogfileRotationMaxArchives,
+		Format:              a.LogFormat,
+	}
+}
+
 // Inputs returns a list of strings of the configured inputs.
 func (c *Config) InputNames() []string {
 	var name []string
@@ -136,6 +209,24 @@ func (c *Config) OutputNames() []string {
 	return name
 }
 
+// ProcessorNames returns a list of strings of the configured processors.
+func (c *Config) ProcessorNames() []string {
+	var name []string
+	for _, processor := range c.Processors {
+		name = append(name, processor.Name)
+	}
+	return name
+}
+
+// AggregatorNames returns a list of strings of the configured aggregators.
+func (c *Config) AggregatorNames() []string {
+	var name []string
+	for _, aggregator := range c.Aggregators {
+		name = append(name, aggregator.Name)
+	}
+	return name
+}
+
 // ListTags returns a string of tags specified in the config,
 // line-protocol style
 func (c *Config) ListTags() string {
@@ -180,7 +271,12 @@ var header = `# Telegraf Configuration
   interval = "10s"
   ## Rounds collection interval to 'interval'
   ## ie, if interval="10s" then always collect on :00, :10, :20, etc.
+  ## Metrics from a collection are all given its time, which is then rounded
+  ## to the interval too.
   round_interval = true
+  ## Rounds the timestamps of collected metrics to the given precision, ie,
+  ## "1s" for whole seconds. Empty means no rounding.
+  precision = ""
 
   ## Telegraf will cache metric_buffer_limit metrics for each output, and will
   ## flush this buffer on a successful write.
@@ -193,6 +289,14 @@ var header = `# Telegraf Configuration
   ## This can be used to avoid many plugins querying things like sysfs at the
   ## same time, which can have a measurable effect on the system.
   collection_jitter = "0s"
+  ## Default time an input may take to gather, after which the gather is
+  ## abandoned and an error is recorded. 0s means no timeout.
+  gather_timeout = "0s"
+  ## What inputs do with their metrics when the channel to the outputs is
+  ## full: "block" until there is room, "drop_newest" to drop the metric being
+  ## added, or "drop_oldest" to drop the oldest metric waiting in the channel.
+  ## Inputs can override it.
+  metric_overflow = "block"
 
   ## Default flushing interval for all outputs. You shouldn't set this below
   ## interval. Maximum flush_interval will be flush_interval + flush_jitter
@@ -201,11 +305,27 @@ var header = `# Telegraf Configuration
   ## large write spikes for users running a large number of telegraf instances.
   ## ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
+  ## Time the final flush of the outputs may take on shutdown, after which
+  ## the metrics that are not written yet are lost. 0s means no timeout.
+  shutdown_timeout = "0s"
 
-  ## Run telegraf in debug mode
+  ## Run telegraf in debug mode, which also logs debug messages
   debug = false
-  ## Run telegraf in quiet mode
+  ## Run telegraf in quiet mode, which only logs errors and warnings
   quiet = false
+  ## File to write the logs to, stderr if empty
+  logfile = ""
+  ## Size the log file is rotated at, ie, "10MB". 0 means never.
+  logfile_rotation_max_size = "0MB"
+  ## Number of rotated log files to keep, as logfile.1, logfile.2, etc.
+  logfile_rotation_max_archives = 5
+  ## Format of the logs: "text", or "json" for a JSON object per line
+  log_format = "text"
+  ## Address of the HTTP status API, serving /health, /status and /config,
+  ## ie, "localhost:8099". Disabled if empty.
+  status_address = ""
+  ## How long an output may fail to write before /health reports a failure
+  health_failure_threshold = "5m"
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
@@ -217,6 +337,20 @@ var header = `# Telegraf Configuration
 ###############################################################################
 `
 
+var processorHeader = `
+
+###############################################################################
+#                            PROCESSOR PLUGINS                                #
+###############################################################################
+`
+
+var aggregatorHeader = `
+
+###############################################################################
+#                            AGGREGATOR PLUGINS                               #
+###############################################################################
+`
+
 var inputHeader = `
 
 ###############################################################################
@@ -250,6 +384,22 @@ func PrintSampleConfig(inputFilters []string, outputFilters []string) {
 		printFilteredOutputs(pnames, true)
 	}
 
+	fmt.Printf(processorHeader)
+	var pnames []string
+	for pname := range processors.Processors {
+		pnames = append(pnames, pname)
+	}
+	sort.Strings(pnames)
+	printFilteredProcessors(pnames, true)
+
+	fmt.Printf(aggregatorHeader)
+	pnames = []string{}
+	for pname := range aggregators.Aggregators {
+		pnames = append(pnames, pname)
+	}
+	sort.Strings(pnames)
+	printFilteredAggregators(pnames, true)
+
 	fmt.Printf(inputHeader)
 	if len(inputFilters) != 0 {
 		printFilteredInputs(inputFilters, false)
@@ -267,6 +417,42 @@ func PrintSampleConfig(inputFilters []string, outputFilters []string) {
 	}
 }
 
+func printFilteredProcessors(processorFilters []string, commented bool) {
+	// Filter processors
+	var pnames []string
+	for pname := range processors.Processors {
+		if sliceContains(pname, processorFilters) {
+			pnames = append(pnames, pname)
+		}
+	}
+	sort.Strings(pnames)
+
+	// Print Processors
+	for _, pname := range pnames {
+		creator := processors.Processors[pname]
+		processor := creator()
+		printConfig(pname, processor, "processors", commented)
+	}
+}
+
+func printFilteredAggregators(aggregatorFilters []string, commented bool) {
+	// Filter aggregators
+	var anames []string
+	for aname := range aggregators.Aggregators {
+		if sliceContains(aname, aggregatorFilters) {
+			anames = append(anames, aname)
+		}
+	}
+	sort.Strings(anames)
+
+	// Print Aggregators
+	for _, aname := range anames {
+		creator := aggregators.Aggregators[aname]
+		aggregator := creator()
+		printConfig(aname, aggregator, "aggregators", commented)
+	}
+}
+
 func printFilteredInputs(inputFilters []string, commented bool) {
 	// Filter inputs
 	var pnames []string
@@ -383,11 +569,34 @@ func PrintOutputConfig(name string) error {
 	return nil
 }
 
+// PrintProcessorConfig prints the config usage of a single processor.
+func PrintProcessorConfig(name string) error {
+	if creator, ok := processors.Processors[name]; ok {
+		printConfig(name, creator(), "processors", false)
+	} else {
+		return errors.New(fmt.Sprintf("Processor %s not found", name))
+	}
+	return nil
+}
+
+// PrintAggregatorConfig prints the config usage of a single aggregator.
+func PrintAggregatorConfig(name string) error {
+	if creator, ok := aggregators.Aggregators[name]; ok {
+		printConfig(name, creator(), "aggregators", false)
+	} else {
+		return errors.New(fmt.Sprintf("Aggregator %s not found", name))
+	}
+	return nil
+}
+
+// LoadDirectory loads all the *.conf files in the given directory. It loads
+// every file before returning the problems found in them.
 func (c *Config) LoadDirectory(path string) error {
 	directoryEntries, err := ioutil.ReadDir(path)
 	if err != nil {
 		return err
 	}
+	var errs Errors
 	for _, entry := range directoryEntries {
 		if entry.IsDir() {
 			continue
@@ -396,103 +605,175 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			continue
 		}
-		err := c.LoadConfig(filepath.Join(path, name))
-		if err != nil {
-			return err
-		}
+		errs = appendErrors(errs, c.LoadConfig(filepath.Join(path, name)))
+	}
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
-// LoadConfig loads the given config file and applies it to c
+// LoadConfig loads the given config file and applies it to c. It checks every
+// plugin in the file before returning the problems found, as Errors whose
+// elements locate each problem in the file.
 func (c *Config) LoadConfig(path string) error {
-	tbl, err := parseFile(path)
-	if err != nil {
+	tbl, contents, err := parseFile(path)
+	if cerr, ok := err.(*ConfigError); ok {
+		cerr.File = path
+		return cerr
+	} else if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
 
-	for name, val := range tbl.Fields {
-		subTable, ok := val.(*ast.Table)
+	c.file = path
+	c.sources = append(c.sources, Source{Path: path, Contents: contents})
+	var errs Errors
+	names := make([]string, 0, len(tbl.Fields))
+	for name := range tbl.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subTable, ok := tbl.Fields[name].(*ast.Table)
 		if !ok {
-			return fmt.Errorf("%s: invalid configuration", path)
+			errs = append(errs, &ConfigError{
+				Line: valueLine(tbl.Fields[name], 0),
+				Err:  fmt.Errorf("invalid configuration %q", name),
+			})
+			continue
 		}
 
 		switch name {
 		case "agent":
-			if err = config.UnmarshalTable(subTable, c.Agent); err != nil {
-				log.Printf("Could not parse [agent] config\n")
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+			if err = c.checkKeys(subTable, c.Agent, "agent"); err != nil {
+				errs = appendErrors(errs, err)
+			} else if err = config.UnmarshalTable(subTable, c.Agent); err != nil {
+				log.Printf("E! Could not parse [agent] config\n")
+				errs = appendErrors(errs, err)
+			} else if _, err = internal.ParsePrecision(c.Agent.Precision); err != nil {
+				errs = appendErrors(errs, lineError(
+					valueLine(subTable.Fields["precision"], subTable.Line), err))
+			} else if _, err = internal_models.ParseOverflowPolicy(c.Agent.MetricOverflow); err != nil {
+				errs = appendErrors(errs, lineError(
+					valueLine(subTable.Fields["metric_overflow"], subTable.Line), err))
+			} else if err = logger.CheckFormat(c.Agent.LogFormat); err != nil {
+				errs = appendErrors(errs, lineError(
+					valueLine(subTable.Fields["log_format"], subTable.Line), err))
 			}
 		case "global_tags", "tags":
 			if err = config.UnmarshalTable(subTable, c.Tags); err != nil {
-				log.Printf("Could not parse [global_tags] config\n")
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+				log.Printf("E! Could not parse [global_tags] config\n")
+				errs = appendErrors(errs, err)
 			}
 		case "outputs":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case *ast.Table:
-					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
-				}
-			}
+			errs = appendErrors(errs, c.addPlugins(subTable, c.addOutput))
+		case "processors":
+			errs = appendErrors(errs, c.addPlugins(subTable, c.addProcessor))
+		case "aggregators":
+			errs = appendErrors(errs, c.addPlugins(subTable, c.addAggregator))
 		case "inputs", "plugins":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case *ast.Table:
-					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
-				}
-			}
+			errs = appendErrors(errs, c.addPlugins(subTable, c.addInput))
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
-			if err = c.addInput(name, subTable); err != nil {
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+			errs = appendErrors(errs, c.addInput(name, subTable))
+		}
+	}
+
+	// Processors are applied in the order given by their 'order' setting,
+	// processors without an order run after them in the order they were
+	// loaded in.
+	sort.Stable(c.Processors)
+
+	if len(errs) > 0 {
+		return Errors(withFile(path, errs))
+	}
+	return nil
+}
+
+// addPlugins adds each of the plugins configured in the given section, such
+// as [[inputs.cpu]] in the "inputs" section, with add.
+func (c *Config) addPlugins(
+	section *ast.Table,
+	add func(name string, table *ast.Table) error,
+) error {
+	var errs Errors
+	names := make([]string, 0, len(section.Fields))
+	for name := range section.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch pluginSubTable := section.Fields[name].(type) {
+		case *ast.Table:
+			errs = appendErrors(errs, add(name, pluginSubTable))
+		case []*ast.Table:
+			for _, t := range pluginSubTable {
+				errs = appendErrors(errs, add(name, t))
 			}
+		default:
+			errs = append(errs, lineError(valueLine(pluginSubTable, section.Line),
+				fmt.Errorf("Unsupported config format: %s", name)))
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkKeys removes the options of a plugin's table that the plugin doesn't
+// have. They are an error in strict mode, otherwise they are only logged.
+func (c *Config) checkKeys(tbl *ast.Table, v interface{}, kind string) error {
+	errs := unknownKeys(tbl, v, kind)
+	if len(errs) == 0 {
+		return nil
+	}
+	if c.Strict {
+		return Errors(errs)
+	}
+	for _, err := range withFile(c.file, errs) {
+		log.Printf("W! Ignoring config option, %s\n", err)
+	}
 	return nil
 }
 
+// appendErrors appends err to errs, flattening Errors.
+func appendErrors(errs Errors, err error) Errors {
+	switch e := err.(type) {
+	case nil:
+		return errs
+	case Errors:
+		return append(errs, e...)
+	default:
+		return append(errs, e)
+	}
+}
+
 // parseFile loads a TOML configuration from a provided path and
 // returns the AST produced from the TOML parser. When loading the file, it
-// will find environment variables and replace them.
-func parseFile(fpath string) (*ast.Table, error) {
-	contents, err := ioutil.ReadFile(fpath)
+// will find environment variables and replace them, and then read the
+// secrets referenced by "@file:" values. It also returns the contents of the
+// file as read, before the environment variables are substituted.
+func parseFile(fpath string) (*ast.Table, []byte, error) {
+	raw, err := ioutil.ReadFile(fpath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	env_vars := envVarRe.FindAll(contents, -1)
-	for _, env_var := range env_vars {
-		env_val := os.Getenv(strings.TrimPrefix(string(env_var), "$"))
-		if env_val != "" {
-			contents = bytes.Replace(contents, env_var, []byte(env_val), 1)
-		}
+	contents, err := substituteEnvVars(raw)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return toml.Parse(contents)
+	tbl, err := toml.Parse(contents)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := resolveSecrets(tbl); err != nil {
+		return nil, nil, err
+	}
+	return tbl, raw, nil
 }
 
 func (c *Config) addOutput(name string, table *ast.Table) error {
@@ -501,9 +782,11 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	}
 	creator, ok := outputs.Outputs[name]
 	if !ok {
-		return fmt.Errorf("Undefined but requested output: %s", name)
+		return lineError(table.Line,
+			fmt.Errorf("Undefined but requested output: %s", name))
 	}
 	output := creator()
+	fingerprint := tableFingerprint(table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -511,21 +794,40 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	case serializers.SerializerOutput:
 		serializer, err := buildSerializer(name, table)
 		if err != nil {
-			return err
+			return lineError(table.Line, err)
 		}
 		t.SetSerializer(serializer)
 	}
 
 	outputConfig, err := buildOutput(name, table)
 	if err != nil {
-		return err
+		return lineError(table.Line, err)
+	}
+	// outputs sharing a buffer would corrupt each other's segments and cursor
+	if dir := outputConfig.BufferDir; dir != "" {
+		for _, o := range c.Outputs {
+			if o.Config.BufferDir != "" &&
+				filepath.Clean(o.Config.BufferDir) == filepath.Clean(dir) {
+				return lineError(table.Line, fmt.Errorf(
+					"buffer_dir %s is already used by output %s", dir, o.Name))
+			}
+		}
 	}
 
+	if err := c.checkKeys(table, output, "outputs."+name); err != nil {
+		return err
+	}
 	if err := config.UnmarshalTable(table, output); err != nil {
 		return err
 	}
 
+	switch t := output.(type) {
+	case telegraf.LoggingPlugin:
+		t.SetLogger(logger.New("outputs", name, outputConfig.Alias))
+	}
+
 	ro := internal_models.NewRunningOutput(name, output, outputConfig)
+	ro.Fingerprint = fingerprint
 	if c.Agent.MetricBufferLimit > 0 {
 		ro.MetricBufferLimit = c.Agent.MetricBufferLimit
 	}
@@ -534,6 +836,76 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	return nil
 }
 
+func (c *Config) addAggregator(name string, table *ast.Table) error {
+	creator, ok := aggregators.Aggregators[name]
+	if !ok {
+		return lineError(table.Line,
+			fmt.Errorf("Undefined but requested aggregator: %s", name))
+	}
+	aggregator := creator()
+
+	aggregatorConfig, err := buildAggregator(name, table)
+	if err != nil {
+		return lineError(table.Line, err)
+	}
+
+	if err := c.checkKeys(table, aggregator, "aggregators."+name); err != nil {
+		return err
+	}
+	if err := config.UnmarshalTable(table, aggregator); err != nil {
+		return err
+	}
+
+	switch t := aggregator.(type) {
+	case telegraf.LoggingPlugin:
+		t.SetLogger(logger.New("aggregators", name, aggregatorConfig.Alias))
+	}
+
+	ra := &internal_models.RunningAggregator{
+		Name:       name,
+		Aggregator: aggregator,
+		Config:     aggregatorConfig,
+	}
+
+	c.Aggregators = append(c.Aggregators, ra)
+	return nil
+}
+
+func (c *Config) addProcessor(name string, table *ast.Table) error {
+	creator, ok := processors.Processors[name]
+	if !ok {
+		return lineError(table.Line,
+			fmt.Errorf("Undefined but requested processor: %s", name))
+	}
+	processor := creator()
+
+	processorConfig, err := buildProcessor(name, table)
+	if err != nil {
+		return lineError(table.Line, err)
+	}
+
+	if err := c.checkKeys(table, processor, "processors."+name); err != nil {
+		return err
+	}
+	if err := config.UnmarshalTable(table, processor); err != nil {
+		return err
+	}
+
+	switch t := processor.(type) {
+	case telegraf.LoggingPlugin:
+		t.SetLogger(logger.New("processors", name, processorConfig.Alias))
+	}
+
+	rf := &internal_models.RunningProcessor{
+		Name:      name,
+		Processor: processor,
+		Config:    processorConfig,
+	}
+
+	c.Processors = append(c.Processors, rf)
+	return nil
+}
+
 func (c *Config) addInput(name string, table *ast.Table) error {
 	if len(c.InputFilters) > 0 && !sliceContains(name, c.InputFilters) {
 		return nil
@@ -545,9 +917,11 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 
 	creator, ok := inputs.Inputs[name]
 	if !ok {
-		return fmt.Errorf("Undefined but requested input: %s", name)
+		return lineError(table.Line,
+			fmt.Errorf("Undefined but requested input: %s", name))
 	}
 	input := creator()
+	fingerprint := tableFingerprint(table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -555,29 +929,85 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	case parsers.ParserInput:
 		parser, err := buildParser(name, table)
 		if err != nil {
-			return err
+			return lineError(table.Line, err)
 		}
 		t.SetParser(parser)
 	}
 
 	pluginConfig, err := buildInput(name, table)
 	if err != nil {
-		return err
+		return lineError(table.Line, err)
 	}
 
+	if err := c.checkKeys(table, input, "inputs."+name); err != nil {
+		return err
+	}
 	if err := config.UnmarshalTable(table, input); err != nil {
 		return err
 	}
 
+	switch t := input.(type) {
+	case telegraf.LoggingPlugin:
+		t.SetLogger(logger.New("inputs", name, pluginConfig.Alias))
+	}
+
 	rp := &internal_models.RunningInput{
-		Name:   name,
-		Input:  input,
-		Config: pluginConfig,
+		Name:        name,
+		Input:       input,
+		Config:      pluginConfig,
+		Fingerprint: fingerprint,
 	}
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// tableFingerprint returns a canonical form of a plugin's table, which is the
+// same for two tables only if they configure the plugin the same way. It must
+// be called before any of the build functions delete fields from the table.
+func tableFingerprint(tbl *ast.Table) string {
+	var buf bytes.Buffer
+	writeFingerprint(&buf, tbl)
+	return buf.String()
+}
+
+func writeFingerprint(buf *bytes.Buffer, val interface{}) {
+	switch v := val.(type) {
+	case *ast.Table:
+		keys := make([]string, 0, len(v.Fields))
+		for k := range v.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{")
+		for _, k := range keys {
+			buf.WriteString(strconv.Quote(k) + "=")
+			writeFingerprint(buf, v.Fields[k])
+			buf.WriteString(";")
+		}
+		buf.WriteString("}")
+	case []*ast.Table:
+		buf.WriteString("[")
+		for _, t := range v {
+			writeFingerprint(buf, t)
+			buf.WriteString(",")
+		}
+		buf.WriteString("]")
+	case *ast.KeyValue:
+		writeFingerprint(buf, v.Value)
+	case *ast.Array:
+		buf.WriteString("[")
+		for _, elem := range v.Value {
+			writeFingerprint(buf, elem)
+			buf.WriteString(",")
+		}
+		buf.WriteString("]")
+	case *ast.String:
+		buf.WriteString(strconv.Quote(v.Value))
+	case ast.Value:
+		buf.WriteString(v.Source())
+	}
+}
+
 // buildFilter builds a Filter
 // (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
 // be inserted into the internal_models.OutputConfig/internal_models.InputConfig
@@ -704,6 +1134,34 @@ func buildFilter(tbl *ast.Table) (internal_models.Filter, error) {
 			}
 		}
 	}
+	if node, ok := tbl.Fields["tagmode"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				f.TagMode = str.Value
+			}
+		}
+	}
+
+	fields = []string{"valuepass", "valuedrop"}
+	for _, field := range fields {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if ary, ok := kv.Value.(*ast.Array); ok {
+					for _, elem := range ary.Value {
+						if str, ok := elem.(*ast.String); ok {
+							if field == "valuepass" {
+								f.ValuePass = append(f.ValuePass, str.Value)
+							} else {
+								f.ValueDrop = append(f.ValueDrop, str.Value)
+							}
+							f.IsActive = true
+						}
+					}
+				}
+			}
+		}
+	}
+
 	if err := f.CompileFilter(); err != nil {
 		return f, err
 	}
@@ -718,24 +1176,209 @@ func buildFilter(tbl *ast.Table) (internal_models.Filter, error) {
 	delete(tbl.Fields, "tagpass")
 	delete(tbl.Fields, "tagexclude")
 	delete(tbl.Fields, "taginclude")
+	delete(tbl.Fields, "tagmode")
+	delete(tbl.Fields, "valuepass")
+	delete(tbl.Fields, "valuedrop")
 	return f, nil
 }
 
+// buildAggregator parses aggregator specific items from the ast.Table,
+// builds the filter and returns a
+// internal_models.AggregatorConfig to be inserted into
+// internal_models.RunningAggregator
+func buildAggregator(name string, tbl *ast.Table) (*internal_models.AggregatorConfig, error) {
+	conf := &internal_models.AggregatorConfig{
+		Name:   name,
+		Period: time.Second * 30,
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["period"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			dur, err := durationValue(kv)
+			if err != nil {
+				return nil, err
+			}
+
+			conf.Period = dur
+		}
+	}
+
+	if node, ok := tbl.Fields["drop_original"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				conf.DropOriginal, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_prefix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.MeasurementPrefix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_suffix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.MeasurementSuffix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_override"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.NameOverride = str.Value
+			}
+		}
+	}
+
+	conf.Tags = make(map[string]string)
+	if node, ok := tbl.Fields["tags"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			if err := config.UnmarshalTable(subtbl, conf.Tags); err != nil {
+				log.Printf("E! Could not parse tags for aggregator %s\n", name)
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "period")
+	delete(tbl.Fields, "drop_original")
+	delete(tbl.Fields, "name_prefix")
+	delete(tbl.Fields, "name_suffix")
+	delete(tbl.Fields, "name_override")
+	delete(tbl.Fields, "tags")
+	var err error
+	conf.Filter, err = buildFilter(tbl)
+	if err != nil {
+		return conf, err
+	}
+	return conf, nil
+}
+
+// buildProcessor parses processor specific items from the ast.Table,
+// builds the filter and returns a
+// internal_models.ProcessorConfig to be inserted into
+// internal_models.RunningProcessor
+func buildProcessor(name string, tbl *ast.Table) (*internal_models.ProcessorConfig, error) {
+	conf := &internal_models.ProcessorConfig{Name: name}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			b, ok := kv.Value.(*ast.Integer)
+			if !ok {
+				return nil, fmt.Errorf("order must be an integer, got %s",
+					kv.Value.Source())
+			}
+			var err error
+			conf.Order, err = strconv.ParseInt(b.Value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if conf.Order < 1 {
+				return nil, fmt.Errorf("order must be a positive integer, got %d",
+					conf.Order)
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "order")
+	var err error
+	conf.Filter, err = buildFilter(tbl)
+	if err != nil {
+		return conf, err
+	}
+	return conf, nil
+}
+
 // buildInput parses input specific items from the ast.Table,
 // builds the filter and returns a
 // internal_models.InputConfig to be inserted into internal_models.RunningInput
 func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, error) {
 	cp := &internal_models.InputConfig{Name: name}
-	if node, ok := tbl.Fields["interval"]; ok {
+	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
-				dur, err := time.ParseDuration(str.Value)
-				if err != nil {
-					return nil, err
-				}
+				cp.Alias = str.Value
+			}
+		}
+	}
 
-				cp.Interval = dur
+	if node, ok := tbl.Fields["interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			dur, err := durationValue(kv)
+			if err != nil {
+				return nil, err
 			}
+
+			cp.Interval = dur
+		}
+	}
+
+	if node, ok := tbl.Fields["gather_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			dur, err := durationValue(kv)
+			if err != nil {
+				return nil, err
+			}
+
+			cp.GatherTimeout = dur
+		}
+	}
+
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			str, ok := kv.Value.(*ast.String)
+			if !ok {
+				return nil, lineError(kv.Line,
+					fmt.Errorf("invalid precision: expected a duration string like \"1s\""))
+			}
+			dur, err := internal.ParsePrecision(str.Value)
+			if err != nil {
+				return nil, lineError(kv.Line, err)
+			}
+
+			cp.Precision = dur
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_overflow"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			str, ok := kv.Value.(*ast.String)
+			if !ok {
+				return nil, lineError(kv.Line,
+					fmt.Errorf("invalid metric_overflow: expected a string"))
+			}
+			policy, err := internal_models.ParseOverflowPolicy(str.Value)
+			if err != nil {
+				return nil, lineError(kv.Line, err)
+			}
+
+			cp.Overflow = policy
 		}
 	}
 
@@ -767,15 +1410,19 @@ func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, erro
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
 			if err := config.UnmarshalTable(subtbl, cp.Tags); err != nil {
-				log.Printf("Could not parse tags for input %s\n", name)
+				log.Printf("E! Could not parse tags for input %s\n", name)
 			}
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "gather_timeout")
+	delete(tbl.Fields, "precision")
+	delete(tbl.Fields, "metric_overflow")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -785,6 +1432,20 @@ func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, erro
 	return cp, nil
 }
 
+// durationValue parses the duration a key is set to, such as "10s".
+func durationValue(kv *ast.KeyValue) (time.Duration, error) {
+	str, ok := kv.Value.(*ast.String)
+	if !ok {
+		return 0, lineError(kv.Line,
+			fmt.Errorf("invalid %s: expected a duration string like \"10s\"", kv.Key))
+	}
+	dur, err := time.ParseDuration(str.Value)
+	if err != nil {
+		return 0, lineError(kv.Line, fmt.Errorf("invalid %s: %s", kv.Key, err))
+	}
+	return dur, nil
+}
+
 // buildParser grabs the necessary entries from the ast.Table for creating
 // a parsers.Parser object, and creates it, which can then be added onto
 // an Input object.
@@ -900,7 +1561,6 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 // buildOutput parses output specific items from the ast.Table,
 // builds the filter and returns an
 // internal_models.OutputConfig to be inserted into internal_models.RunningInput
-// Note: error exists in the return for future calls that might require error
 func buildOutput(name string, tbl *ast.Table) (*internal_models.OutputConfig, error) {
 	filter, err := buildFilter(tbl)
 	if err != nil {
@@ -910,12 +1570,81 @@ func buildOutput(name string, tbl *ast.Table) (*internal_models.OutputConfig, er
 		Name:   name,
 		Filter: filter,
 	}
-	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
-	if len(oc.Filter.FieldDrop) > 0 {
-		oc.Filter.NameDrop = oc.Filter.FieldDrop
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Alias = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_dir"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDir = str.Value
+			}
+		}
 	}
-	if len(oc.Filter.FieldPass) > 0 {
-		oc.Filter.NamePass = oc.Filter.FieldPass
+
+	if node, ok := tbl.Fields["buffer_max_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.BufferMaxBytes, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
+
+	oc.Retry = internal_models.RetryConfig{
+		InitialBackoff: internal_models.DEFAULT_RETRY_INITIAL_BACKOFF,
+		MaxBackoff:     internal_models.DEFAULT_RETRY_MAX_BACKOFF,
+	}
+	durations := map[string]*time.Duration{
+		"retry_initial_backoff":   &oc.Retry.InitialBackoff,
+		"retry_max_backoff":       &oc.Retry.MaxBackoff,
+		"retry_jitter":            &oc.Retry.Jitter,
+		"circuit_breaker_timeout": &oc.Retry.CircuitBreakerTimeout,
+		"flush_interval":          &oc.FlushInterval,
+		"flush_jitter":            &oc.FlushJitter,
+	}
+	for field, dur := range durations {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				*dur, err = durationValue(kv)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		delete(tbl.Fields, field)
+	}
+
+	ints := map[string]*int{
+		"retry_max_retries":         &oc.Retry.MaxRetries,
+		"circuit_breaker_threshold": &oc.Retry.CircuitBreakerThreshold,
+		"metric_batch_size":         &oc.MetricBatchSize,
+	}
+	for field, n := range ints {
+		if node, ok := tbl.Fields[field]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if integer, ok := kv.Value.(*ast.Integer); ok {
+					*n, err = strconv.Atoi(integer.Value)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		delete(tbl.Fields, field)
+	}
+	if oc.Retry.CircuitBreakerThreshold > 0 && oc.Retry.CircuitBreakerTimeout == 0 {
+		oc.Retry.CircuitBreakerTimeout = oc.Retry.MaxBackoff
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "buffer_dir")
+	delete(tbl.Fields, "buffer_max_bytes")
 	return oc, nil
 }
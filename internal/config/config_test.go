@@ -1,16 +1,22 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/file"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/processors/printer"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -177,3 +183,292 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_LoadProcessorOrder(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/processor_order.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 4, len(c.Processors))
+	assert.Equal(t, int64(1), c.Processors[0].Config.Order)
+	assert.Equal(t, []string{"first"}, c.Processors[0].Config.Filter.NamePass)
+	assert.Equal(t, int64(2), c.Processors[1].Config.Order)
+	assert.Equal(t, []string{"second"}, c.Processors[1].Config.Filter.NamePass)
+	// processors without an order run last, in the order they were loaded
+	assert.Equal(t, int64(0), c.Processors[2].Config.Order)
+	assert.Equal(t, []string{"unordered"}, c.Processors[2].Config.Filter.NamePass)
+	assert.Equal(t, int64(0), c.Processors[3].Config.Order)
+	assert.Equal(t, []string{"last"}, c.Processors[3].Config.Filter.NamePass)
+}
+
+func TestConfig_LoadInvalidProcessorOrder(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/invalid_processor_order.toml")
+	assert.EqualError(t, err, `./testdata/invalid_processor_order.toml:1: `+
+		`order must be an integer, got "first"`)
+}
+
+func TestConfig_LoadOutputBuffer(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_buffer.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(c.Outputs))
+	assert.Equal(t, "/var/lib/telegraf/buffer/file", c.Outputs[0].Config.BufferDir)
+	assert.Equal(t, int64(1048576), c.Outputs[0].Config.BufferMaxBytes)
+}
+
+func TestConfig_LoadDuplicateBufferDir(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/duplicate_buffer_dir.toml")
+	assert.EqualError(t, err, `./testdata/duplicate_buffer_dir.toml:5: `+
+		`buffer_dir /var/lib/telegraf/buffer/file/ is already used by output file`)
+}
+
+func TestConfig_LoadOutputRetry(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_retry.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(c.Outputs))
+	assert.Equal(t, internal_models.RetryConfig{
+		InitialBackoff:          time.Second,
+		MaxBackoff:              5 * time.Minute,
+		Jitter:                  3 * time.Second,
+		MaxRetries:              10,
+		CircuitBreakerThreshold: 5,
+		CircuitBreakerTimeout:   5 * time.Minute,
+	}, c.Outputs[0].Config.Retry)
+}
+
+func TestConfig_LoadOutputFlush(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_flush.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(c.Outputs))
+	assert.Equal(t, time.Second, c.Outputs[0].Config.FlushInterval)
+	assert.Equal(t, 100*time.Millisecond, c.Outputs[0].Config.FlushJitter)
+	assert.Equal(t, 20, c.Outputs[0].Config.MetricBatchSize)
+}
+
+func TestConfig_Fingerprint(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/fingerprint.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, len(c.Inputs))
+	assert.NotEqual(t, "", c.Inputs[0].Fingerprint)
+	// same settings, in a different order and layout
+	assert.Equal(t, c.Inputs[0].Fingerprint, c.Inputs[1].Fingerprint)
+	assert.NotEqual(t, c.Inputs[0].Fingerprint, c.Inputs[2].Fingerprint)
+}
+
+func TestConfig_UnknownOption(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/unknown_option.toml")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, []string{"localhost"},
+		c.Inputs[0].Input.(*memcached.Memcached).Servers)
+
+	c = NewConfig()
+	c.Strict = true
+	err = c.LoadConfig("./testdata/unknown_option.toml")
+	assert.EqualError(t, err, "./testdata/unknown_option.toml:3: "+
+		`unknown option "unix_socket" in inputs.memcached`)
+}
+
+// Test that all the problems of a config file are reported, with their line.
+func TestConfig_Invalid(t *testing.T) {
+	c := NewConfig()
+	c.Strict = true
+	err := c.LoadConfig("./testdata/invalid.toml")
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+
+	// the messages of the time and glob packages vary between versions
+	expected := []string{
+		`./testdata/invalid.toml:3: unknown option "flush_intervall" in agent`,
+		`./testdata/invalid.toml:18: invalid interval: expected a duration string like "10s"`,
+		"./testdata/invalid.toml:13: Undefined but requested input: memcache",
+		"./testdata/invalid.toml:7: invalid interval: ",
+		"./testdata/invalid.toml:9: Error compiling 'namepass', ",
+		`./testdata/invalid.toml:23: unknown option "data_fromat" in outputs.file`,
+	}
+	assert.Equal(t, len(expected), len(errs))
+	for i, err := range errs {
+		assert.True(t, strings.HasPrefix(err.Error(), expected[i]),
+			"expected %q to start with %q", err.Error(), expected[i])
+	}
+}
+
+func TestConfig_SubstituteEnvVars(t *testing.T) {
+	os.Setenv("TEST_VAR", `a "quoted" \value`)
+	os.Setenv("TEST_MULTILINE", "line1\nline2")
+	os.Setenv("TEST_INT", "10")
+	os.Unsetenv("TEST_UNSET")
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{`a = "${TEST_VAR}"`, `a = "a \"quoted\" \\value"`},
+		{`a = """${TEST_MULTILINE}"""`, `a = """line1\nline2"""`},
+		{`a = ${TEST_INT}`, `a = 10`},
+		{`a = "${TEST_UNSET}"`, `a = ""`},
+		{`a = "${TEST_UNSET:-default}"`, `a = "default"`},
+		{`a = "${TEST_INT:-default}"`, `a = "10"`},
+		{`a = "$TEST_INT"`, `a = "10"`},
+		{`a = "$TEST_UNSET"`, `a = "$TEST_UNSET"`},
+		{`a = "\"${TEST_INT}"`, `a = "\"10"`},
+		{`a = '${TEST_INT}'`, `a = '10'`},
+		{"a = 1 # ${TEST_UNSET:?ignored in comments}", "a = 1 # ${TEST_UNSET:?ignored in comments}"},
+	}
+	for _, test := range tests {
+		out, err := substituteEnvVars([]byte(test.in))
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.out, string(out), test.in)
+	}
+
+	_, err := substituteEnvVars([]byte("a = 1\nb = \"${TEST_UNSET:?must be set}\""))
+	assert.EqualError(t, err, ":2: TEST_UNSET: must be set")
+
+	_, err = substituteEnvVars([]byte("a = '${TEST_MULTILINE}'"))
+	assert.Error(t, err)
+
+	_, err = substituteEnvVars([]byte("a = \"${TEST INT}\""))
+	assert.Error(t, err)
+}
+
+func TestConfig_LoadSecretFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "telegraf-secret")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("secret\n")
+	assert.NoError(t, err)
+	f.Close()
+
+	os.Setenv("TEST_SECRET_FILE", f.Name())
+	c := NewConfig()
+	err = c.LoadConfig("./testdata/secret_file.toml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret"},
+		c.Inputs[0].Input.(*memcached.Memcached).Servers)
+
+	os.Setenv("TEST_SECRET_FILE", f.Name()+".missing")
+	c = NewConfig()
+	err = c.LoadConfig("./testdata/secret_file.toml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "./testdata/secret_file.toml:2: Error reading secret")
+}
+
+func TestConfig_LoadGatherTimeout(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/gather_timeout.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 30*time.Second, c.Agent.GatherTimeout.Duration)
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, 5*time.Second, c.Inputs[0].Config.GatherTimeout)
+}
+
+func TestConfig_LoadPrecision(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/precision.toml")
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(errs))
+	assert.EqualError(t, errs[0],
+		`./testdata/precision.toml:10: invalid precision "1 second"`)
+
+	assert.Equal(t, "s", c.Agent.Precision)
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, 100*time.Millisecond, c.Inputs[0].Config.Precision)
+}
+
+func TestConfig_LoadMetricOverflow(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/metric_overflow.toml")
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(errs))
+	assert.EqualError(t, errs[0],
+		`./testdata/metric_overflow.toml:10: invalid metric_overflow "drop", `+
+			`expected "block", "drop_newest" or "drop_oldest"`)
+
+	assert.Equal(t, "drop_newest", c.Agent.MetricOverflow)
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, internal_models.OverflowBlock, c.Inputs[0].Config.Overflow)
+}
+
+func TestConfig_LoadOutputFilter(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_filter.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, []string{`state == "idle"`}, c.Inputs[0].Config.Filter.ValueDrop)
+	assert.True(t, c.Inputs[0].Config.Filter.IsActive)
+	assert.Equal(t, "and", c.Inputs[0].Config.Filter.TagMode)
+	assert.True(t, c.Inputs[0].Config.Filter.ShouldTagsPass(
+		map[string]string{"server": "localhost:11211", "role": "primary"}))
+	assert.False(t, c.Inputs[0].Config.Filter.ShouldTagsPass(
+		map[string]string{"server": "localhost:11211", "role": "replica"}))
+
+	assert.Equal(t, 1, len(c.Outputs))
+	f := c.Outputs[0].Config.Filter
+	assert.Equal(t, []string{"usage_*"}, f.FieldPass)
+	assert.Nil(t, f.NamePass)
+	assert.Equal(t, []string{"usage_idle < 90"}, f.ValuePass)
+}
+
+// loggingInput is an input which keeps the logger it is given.
+type loggingInput struct {
+	logger telegraf.Logger
+}
+
+func (i *loggingInput) SampleConfig() string {
+	return ""
+}
+
+func (i *loggingInput) Description() string {
+	return ""
+}
+
+func (i *loggingInput) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (i *loggingInput) SetLogger(logger telegraf.Logger) {
+	i.logger = logger
+}
+
+func TestConfig_LoadLogging(t *testing.T) {
+	inputs.Add("logging", func() telegraf.Input { return &loggingInput{} })
+
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/logging.toml"))
+
+	assert.Equal(t, logger.Config{
+		Level:               logger.LevelWarn,
+		Logfile:             "/var/log/telegraf/telegraf.log",
+		RotationMaxSize:     10 * 1024 * 1024,
+		RotationMaxArchives: 5,
+		Format:              "json",
+	}, c.Agent.LoggerConfig())
+
+	assert.Equal(t, 1, len(c.Inputs))
+	assert.Equal(t, "first", c.Inputs[0].Config.Alias)
+	assert.Equal(t, logger.New("inputs", "logging", "first"),
+		c.Inputs[0].Input.(*loggingInput).logger)
+	assert.Equal(t, 1, len(c.Outputs))
+	assert.Equal(t, "stdout", c.Outputs[0].Config.Alias)
+}
+
+func TestConfig_LoadInvalidLogFormat(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/invalid_log_format.toml")
+	assert.EqualError(t, err, `./testdata/invalid_log_format.toml:2: `+
+		`invalid log_format "xml", expected "text" or "json"`)
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Source is a config file that was loaded.
+type Source struct {
+	Path     string
+	Contents []byte
+}
+
+// redacted replaces secret values.
+const redacted = "<redacted>"
+
+var (
+	// keyValue matches an option, or a commented out one, on a line.
+	keyValue = regexp.MustCompile(`^(\s*#?\s*"?([A-Za-z0-9_\-]+)"?\s*=\s*)(.*)$`)
+	// secretKey matches the names of the options whose values are secret.
+	secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|api_?key|access_key|private_key)`)
+	// userinfo matches the password of the user:password@ in URLs and DSNs.
+	userinfo = regexp.MustCompile(`([A-Za-z0-9._%+\-]+:)[^@\s"'/:]+@`)
+	// secretPair matches the secrets of key=value pairs in strings, ie, the
+	// password of "host=localhost password=secret" or of "?token=secret&".
+	secretPair = regexp.MustCompile(`(?i)(\b[A-Za-z0-9_]*(?:password|passwd|pwd|secret|token|api_?key|access_key)\s*=\s*)('[^']*'|[^\s"';&]+)`)
+	// reference matches a string that is only an environment variable without
+	// a default, or an "@file:" secret.
+	reference = regexp.MustCompile(`^(?:"(?:\$\{[A-Za-z0-9_]+\}|` + secretFilePrefix +
+		`[^"\\]*)?"|'(?:\$\{[A-Za-z0-9_]+\}|` + secretFilePrefix + `[^']*)?')$`)
+)
+
+// Sources returns the config files that were loaded, in order, with the
+// values of the options that look like secrets, ie, password or token, and
+// the passwords in URLs and key=value strings redacted. Environment variables
+// are not substituted, so that their values are never shown, and neither are
+// the values of "@file:" secrets. Those references are left as is.
+func (c *Config) Sources() []Source {
+	sources := make([]Source, 0, len(c.sources))
+	for _, s := range c.sources {
+		sources = append(sources, Source{
+			Path:     s.Path,
+			Contents: redact(s.Contents),
+		})
+	}
+	return sources
+}
+
+// redact returns the contents of a config file with the secrets redacted. The
+// whole value of a secret option is replaced, including the following lines of
+// a multi-line string or array.
+func redact(contents []byte) []byte {
+	lines := strings.Split(string(contents), "\n")
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		m := keyValue.FindStringSubmatch(lines[i])
+		n := 0
+		if m != nil && !strings.HasPrefix(strings.TrimSpace(m[1]), "#") {
+			n = valueLines(m[3], lines[i+1:])
+		}
+
+		switch {
+		case m != nil && secretKey.MatchString(m[2]) && isReference(m[3]):
+			out = append(out, lines[i])
+		case m != nil && secretKey.MatchString(m[2]):
+			out = append(out, m[1]+`"`+redacted+`"`)
+		default:
+			for _, line := range lines[i : i+n+1] {
+				line = userinfo.ReplaceAllString(line, "${1}"+redacted+"@")
+				line = secretPair.ReplaceAllString(line, "${1}"+redacted)
+				out = append(out, line)
+			}
+		}
+		i += n
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// isReference reports whether the value of an option is empty, an environment
+// variable without a default, ie, "${PASSWORD}", or an "@file:" secret, which
+// reveal nothing by themselves. "$PASSWORD" is not one: it is left as is if the
+// variable is not set, so it may as well be a password starting with "$".
+func isReference(value string) bool {
+	v := strings.TrimSpace(value)
+	return v == "" || reference.MatchString(v)
+}
+
+// valueLines returns how many of the next lines the value of an option goes
+// on for, when it is a multi-line string, or an array not closed on its first
+// line.
+func valueLines(value string, next []string) int {
+	ctx := inBare
+	depth := 0
+	for n := 0; ; n++ {
+		for j := 0; j < len(value); j++ {
+			rest := value[j:]
+			switch ctx {
+			case inBare:
+				switch {
+				case rest[0] == '#':
+					j = len(value)
+				case strings.HasPrefix(rest, `"""`):
+					ctx, j = inMultilineBasicString, j+2
+				case strings.HasPrefix(rest, `'''`):
+					ctx, j = inMultilineLiteralString, j+2
+				case rest[0] == '"':
+					ctx = inBasicString
+				case rest[0] == '\'':
+					ctx = inLiteralString
+				case rest[0] == '[':
+					depth++
+				case rest[0] == ']':
+					depth--
+				}
+			case inBasicString:
+				if rest[0] == '\\' {
+					j++
+				} else if rest[0] == '"' {
+					ctx = inBare
+				}
+			case inLiteralString:
+				if rest[0] == '\'' {
+					ctx = inBare
+				}
+			case inMultilineBasicString:
+				if rest[0] == '\\' {
+					j++
+				} else if strings.HasPrefix(rest, `"""`) {
+					ctx, j = inBare, j+2
+				}
+			case inMultilineLiteralString:
+				if strings.HasPrefix(rest, `'''`) {
+					ctx, j = inBare, j+2
+				}
+			}
+		}
+		if ctx == inBasicString || ctx == inLiteralString {
+			// single-line strings end with their line
+			ctx = inBare
+		}
+		if ctx == inBare && depth <= 0 || n == len(next) {
+			return n
+		}
+		value = next[n]
+	}
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// secretFilePrefix starts a string value that is replaced by the contents of
+// a file, ie, password = "@file:/run/secrets/influxdb".
+const secretFilePrefix = "@file:"
+
+// Where a variable is in a config file, which decides how its value is
+// escaped.
+const (
+	inBare = iota
+	inComment
+	inBasicString
+	inLiteralString
+	inMultilineBasicString
+	inMultilineLiteralString
+)
+
+// substituteEnvVars replaces the environment variables in the contents of a
+// config file:
+//
+//	${VAR}          the value of VAR, empty if it isn't set
+//	${VAR:-default} the value of VAR, or default if VAR is empty or not set
+//	${VAR:?message} the value of VAR, it is an error if VAR is empty or not set
+//	$VAR            the value of VAR, left as is if VAR is empty or not set
+//
+// Values are escaped for the TOML string they are in, so that quotes,
+// backslashes and newlines in them don't change the document. Variables in
+// comments are left as is.
+func substituteEnvVars(contents []byte) ([]byte, error) {
+	var out bytes.Buffer
+	line := 1
+	ctx := inBare
+	for i := 0; i < len(contents); {
+		rest := contents[i:]
+		c := contents[i]
+
+		if c == '$' && ctx != inComment {
+			name, value, n, err := envVar(rest)
+			if err != nil {
+				return nil, lineError(line, err)
+			}
+			if n > 0 {
+				escaped, err := escapeValue(value, ctx)
+				if err != nil {
+					return nil, lineError(line, fmt.Errorf("%s: %s", name, err))
+				}
+				out.WriteString(escaped)
+				i += n
+				continue
+			}
+		}
+
+		n := 1
+		switch ctx {
+		case inBare:
+			switch {
+			case c == '#':
+				ctx = inComment
+			case bytes.HasPrefix(rest, []byte(`"""`)):
+				ctx, n = inMultilineBasicString, 3
+			case bytes.HasPrefix(rest, []byte(`'''`)):
+				ctx, n = inMultilineLiteralString, 3
+			case c == '"':
+				ctx = inBasicString
+			case c == '\'':
+				ctx = inLiteralString
+			}
+		case inComment:
+			if c == '\n' {
+				ctx = inBare
+			}
+		case inBasicString:
+			switch c {
+			case '\\':
+				n = 2
+			case '"', '\n':
+				ctx = inBare
+			}
+		case inLiteralString:
+			if c == '\'' || c == '\n' {
+				ctx = inBare
+			}
+		case inMultilineBasicString:
+			if c == '\\' {
+				n = 2
+			} else if bytes.HasPrefix(rest, []byte(`"""`)) {
+				ctx, n = inBare, 3
+			}
+		case inMultilineLiteralString:
+			if bytes.HasPrefix(rest, []byte(`'''`)) {
+				ctx, n = inBare, 3
+			}
+		}
+		if n > len(rest) {
+			n = len(rest)
+		}
+		line += bytes.Count(rest[:n], []byte("\n"))
+		out.Write(rest[:n])
+		i += n
+	}
+	return out.Bytes(), nil
+}
+
+// envVar parses the variable reference at the start of b. It returns the
+// variable's name, the value to replace it with and the length of the
+// reference, 0 if it shouldn't be replaced.
+func envVar(b []byte) (string, string, int, error) {
+	if len(b) > 1 && b[1] == '{' {
+		end := bytes.IndexByte(b, '}')
+		if end < 0 || bytes.IndexByte(b[:end], '\n') >= 0 {
+			return "", "", 0, nil
+		}
+		ref := string(b[2:end])
+		name := ref[:varNameLen(ref)]
+		if name == "" {
+			return "", "", 0, fmt.Errorf("invalid variable reference ${%s}", ref)
+		}
+		value := os.Getenv(name)
+
+		op := ref[len(name):]
+		switch {
+		case op == "":
+		case strings.HasPrefix(op, ":-"):
+			if value == "" {
+				value = op[2:]
+			}
+		case strings.HasPrefix(op, ":?"):
+			if value == "" {
+				msg := op[2:]
+				if msg == "" {
+					msg = "not set"
+				}
+				return "", "", 0, fmt.Errorf("%s: %s", name, msg)
+			}
+		default:
+			return "", "", 0, fmt.Errorf("invalid variable reference ${%s}", ref)
+		}
+		return name, value, end + 1, nil
+	}
+
+	name := string(b[1 : 1+varNameLen(string(b[1:]))])
+	value := os.Getenv(name)
+	if name == "" || value == "" {
+		return "", "", 0, nil
+	}
+	return name, value, 1 + len(name), nil
+}
+
+// varNameLen returns the length of the variable name at the start of s.
+func varNameLen(s string) int {
+	for i, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			c >= '0' && c <= '9') {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// escapeValue escapes the value of a variable for where it is in the file.
+// Values outside strings are not escaped, so that variables can set numbers,
+// booleans and arrays.
+func escapeValue(value string, ctx int) (string, error) {
+	switch ctx {
+	case inBasicString, inMultilineBasicString:
+		var buf bytes.Buffer
+		for _, c := range value {
+			switch c {
+			case '"':
+				buf.WriteString(`\"`)
+			case '\\':
+				buf.WriteString(`\\`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				if c < 0x20 || c == 0x7f || c == utf8.RuneError {
+					fmt.Fprintf(&buf, `\u%04x`, c)
+				} else {
+					buf.WriteRune(c)
+				}
+			}
+		}
+		return buf.String(), nil
+	case inLiteralString:
+		if strings.ContainsAny(value, "'\r\n") {
+			return "", fmt.Errorf("value can't be used in a 'literal string', " +
+				"use a \"basic string\" instead")
+		}
+	case inMultilineLiteralString:
+		if strings.Contains(value, "'''") {
+			return "", fmt.Errorf("value can't be used in a '''literal string''', " +
+				"use a \"\"\"basic string\"\"\" instead")
+		}
+	}
+	return value, nil
+}
+
+// resolveSecrets replaces the string values of tbl that start with "@file:"
+// with the contents of the file that follows, without the trailing newline.
+func resolveSecrets(tbl *ast.Table) error {
+	for _, val := range tbl.Fields {
+		switch v := val.(type) {
+		case *ast.Table:
+			if err := resolveSecrets(v); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		case *ast.KeyValue:
+			if err := resolveSecret(v.Value, v.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resolveSecret(val ast.Value, line int) error {
+	switch v := val.(type) {
+	case *ast.String:
+		if !strings.HasPrefix(v.Value, secretFilePrefix) {
+			return nil
+		}
+		path := strings.TrimPrefix(v.Value, secretFilePrefix)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return lineError(line, fmt.Errorf("Error reading secret, %s", err))
+		}
+		v.Value = strings.TrimRight(string(data), "\r\n")
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := resolveSecret(elem, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
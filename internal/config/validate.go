@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// ConfigError is a problem found in a config file, with its location.
+type ConfigError struct {
+	File string
+	// Line is the line of the config file the problem is on, 0 if unknown.
+	Line int
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Err)
+}
+
+// Errors is the list of problems found while loading a config.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// lineError returns err with the line it was found on. If err already has a
+// line, it is returned as is.
+func lineError(line int, err error) error {
+	if _, ok := err.(*ConfigError); ok {
+		return err
+	}
+	return &ConfigError{Line: line, Err: err}
+}
+
+// withFile sets the file of the ConfigErrors in errs, wrapping the errors that
+// aren't ConfigErrors.
+func withFile(path string, errs []error) []error {
+	for i, err := range errs {
+		cerr, ok := err.(*ConfigError)
+		if !ok {
+			cerr = &ConfigError{Err: err}
+			errs[i] = cerr
+		}
+		cerr.File = path
+	}
+	return errs
+}
+
+// unknownKeys removes the keys of tbl that no field of v, a pointer to a
+// struct, would be set from, and returns an error for each of them. Keys of
+// sub-tables are checked against the fields of nested structs.
+func unknownKeys(tbl *ast.Table, v interface{}, kind string) []error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return unknownStructKeys(tbl, rv.Type(), kind)
+}
+
+func unknownStructKeys(tbl *ast.Table, typ reflect.Type, kind string) []error {
+	fields := make(map[string]reflect.Type)
+	addStructFields(fields, typ)
+
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		val := tbl.Fields[key]
+		ft, ok := fields[key]
+		if !ok {
+			ft, ok = fields[normFieldName(key)]
+		}
+		if !ok {
+			errs = append(errs, lineError(valueLine(val, tbl.Line),
+				fmt.Errorf("unknown option %q in %s", key, kind)))
+			delete(tbl.Fields, key)
+			continue
+		}
+
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		switch sub := val.(type) {
+		case *ast.Table:
+			errs = append(errs, unknownStructKeys(sub, ft, kind+"."+key)...)
+		case []*ast.Table:
+			for _, t := range sub {
+				errs = append(errs, unknownStructKeys(t, ft, kind+"."+key)...)
+			}
+		}
+	}
+	return errs
+}
+
+// addStructFields adds the fields of typ that can be set from the config,
+// keyed by their toml tag or their normalized name, including the fields of
+// embedded structs.
+func addStructFields(fields map[string]reflect.Type, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(fields, ft)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("toml"), ",")[0]
+		switch tag {
+		case "-":
+		case "":
+			fields[normFieldName(f.Name)] = f.Type
+		default:
+			fields[tag] = f.Type
+		}
+	}
+}
+
+// normFieldName is how the toml decoder matches keys to struct fields, so
+// that 'api_key' sets the field 'APIKey'.
+func normFieldName(s string) string {
+	return strings.Replace(strings.ToLower(s), "_", "", -1)
+}
+
+// valueLine returns the line of a table field, or def if it has none.
+func valueLine(val interface{}, def int) int {
+	switch v := val.(type) {
+	case *ast.KeyValue:
+		return v.Line
+	case *ast.Table:
+		return v.Line
+	case []*ast.Table:
+		if len(v) > 0 {
+			return v[0].Line
+		}
+	}
+	return def
+}
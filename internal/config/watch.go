@@ -0,0 +1,92 @@
+package config
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watch polls the given config files, and the *.conf files in the given
+// directories, every interval. It sends on the returned channel whenever one
+// of them is created, removed or modified, until stop is closed.
+func Watch(
+	files []string,
+	dirs []string,
+	interval time.Duration,
+	stop chan struct{},
+) chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := snapshot(files, dirs)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			current := snapshot(files, dirs)
+			if !sameSnapshot(last, current) {
+				last = current
+				select {
+				case changed <- struct{}{}:
+				default:
+					// a change is already pending
+				}
+			}
+		}
+	}()
+	return changed
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot returns the state of the config files and of the *.conf files in
+// the config directories.
+func snapshot(files []string, dirs []string) map[string]fileState {
+	states := make(map[string]fileState)
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		states[path] = fileState{info.ModTime(), info.Size()}
+	}
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			log.Printf("E! Error watching config directory %s: %s", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".conf") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			states[path] = fileState{entry.ModTime(), entry.Size()}
+		}
+	}
+	return states
+}
+
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		other, ok := b[path]
+		if !ok || !state.modTime.Equal(other.modTime) || state.size != other.size {
+			return false
+		}
+	}
+	return true
+}
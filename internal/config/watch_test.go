@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func waitChanged(changed chan struct{}) bool {
+	select {
+	case <-changed:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "telegraf.toml")
+	confDir := filepath.Join(dir, "telegraf.d")
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n"), 0644))
+	require.NoError(t, os.Mkdir(confDir, 0755))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := Watch([]string{file}, []string{confDir}, 10*time.Millisecond, stop)
+
+	select {
+	case <-changed:
+		t.Fatal("unexpected change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n  debug = true\n"), 0644))
+	require.True(t, waitChanged(changed), "modified config file not detected")
+
+	// files without the .conf extension are ignored
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "notes.txt"), []byte("x"), 0644))
+	require.False(t, waitChanged(changed), "unexpected change")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "cpu.conf"), []byte("[[inputs.cpu]]\n"), 0644))
+	require.True(t, waitChanged(changed), "new config file not detected")
+
+	require.NoError(t, os.Remove(filepath.Join(confDir, "cpu.conf")))
+	require.True(t, waitChanged(changed), "removed config file not detected")
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -33,8 +34,77 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Size is a number of bytes, set in the TOML config file as an integer, or as
+// a string with a unit: "512KB", "10MB" or "1GB", which are powers of 1024.
+type Size struct {
+	Size int64
+}
+
+// UnmarshalTOML parses the size from the TOML config file
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := string(b)
+	if len(str) >= 2 && str[0] == '"' && str[len(str)-1] == '"' {
+		str = str[1 : len(str)-1]
+	}
+	size, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+
+	s.Size = size
+
+	return nil
+}
+
+// ParseSize parses a number of bytes with an optional unit, "B", "KB", "MB"
+// or "GB", ie, "10MB".
+func ParseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	number := strings.TrimSpace(s)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KB", 1 << 10},
+		{"MB", 1 << 20},
+		{"GB", 1 << 30},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(number, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
 var NotImplementedError = errors.New("not implemented yet")
 
+// ParsePrecision parses the precision timestamps are rounded to. It is either a
+// duration, ie, "1s" or "100ms", or, as in older config files, a unit: "ns",
+// "us" (or "µs"), "ms", "s", "m" or "h". An empty string is no rounding.
+func ParsePrecision(s string) (time.Duration, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "ns", "us", "µs", "ms", "s", "m", "h":
+		s = "1" + s
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid precision %q", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid precision %q, must not be negative", s)
+	}
+	return d, nil
+}
+
 // ReadLines reads contents from a file and splits them by new lines.
 // A convenience wrapper to ReadLinesOffsetN(filename, 0, -1).
 func ReadLines(filename string) ([]string, error) {
@@ -139,3 +209,29 @@ func SnakeCase(in string) string {
 
 	return string(out)
 }
+
+// CounterDeltas turns the cumulative values of counters into their increase
+// since the previous value, for outputs to services that expect a count per
+// interval rather than a running total.
+type CounterDeltas struct {
+	last map[string]float64
+}
+
+// Delta returns the increase of the counter identified by key since the last
+// value it was given. It returns false for the first value of a counter, which
+// has nothing to be compared to. A counter that went down was reset, and its
+// increase is its new value.
+func (c *CounterDeltas) Delta(key string, value float64) (float64, bool) {
+	if c.last == nil {
+		c.last = make(map[string]float64)
+	}
+	last, ok := c.last[key]
+	c.last[key] = value
+	if !ok {
+		return 0, false
+	}
+	if value < last {
+		return value, true
+	}
+	return value - last, true
+}
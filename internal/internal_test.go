@@ -1,6 +1,9 @@
 package internal
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 type SnakeTest struct {
 	input  string
@@ -30,3 +33,64 @@ func TestSnakeCase(t *testing.T) {
 		}
 	}
 }
+
+func TestCounterDeltas(t *testing.T) {
+	var c CounterDeltas
+
+	if _, ok := c.Delta("a", 10); ok {
+		t.Errorf("Expected no delta for the first value of a counter")
+	}
+	if d, ok := c.Delta("a", 15); !ok || d != 5 {
+		t.Errorf("Expected a delta of 5, got %v, %v", d, ok)
+	}
+	if _, ok := c.Delta("b", 15); ok {
+		t.Errorf("Expected no delta for the first value of another counter")
+	}
+	// the counter was reset
+	if d, ok := c.Delta("a", 3); !ok || d != 3 {
+		t.Errorf("Expected a delta of 3 after a reset, got %v, %v", d, ok)
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	valid := map[string]time.Duration{
+		"":      0,
+		"s":     time.Second,
+		"ms":    time.Millisecond,
+		"us":    time.Microsecond,
+		"µs":    time.Microsecond,
+		"10s":   10 * time.Second,
+		"100ms": 100 * time.Millisecond,
+	}
+	for s, want := range valid {
+		if d, err := ParsePrecision(s); err != nil || d != want {
+			t.Errorf("ParsePrecision(%q): wanted %s, got %s, %v", s, want, d, err)
+		}
+	}
+	for _, s := range []string{"seconds", "10", "-1s"} {
+		if _, err := ParsePrecision(s); err == nil {
+			t.Errorf("ParsePrecision(%q): expected an error", s)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	valid := map[string]int64{
+		"0":     0,
+		"100":   100,
+		"100B":  100,
+		"512KB": 512 * 1024,
+		"10MB":  10 * 1024 * 1024,
+		"1 GB":  1024 * 1024 * 1024,
+	}
+	for s, want := range valid {
+		if n, err := ParseSize(s); err != nil || n != want {
+			t.Errorf("ParseSize(%q): wanted %d, got %d, %v", s, want, n, err)
+		}
+	}
+	for _, s := range []string{"", "MB", "10TB", "-1", "1.5MB"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package internal_models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+const (
+	// Default maximum size of an output's on-disk buffer.
+	DEFAULT_BUFFER_MAX_BYTES = 100 * 1024 * 1024
+
+	// Maximum size of a single buffer segment file.
+	bufferSegmentBytes = 1024 * 1024
+
+	bufferSegmentExt = ".seg"
+	bufferCursorFile = "cursor"
+)
+
+// DiskBuffer is an on-disk write-ahead queue of metrics. Metrics are appended
+// as line protocol to segment files in the buffer directory, and a cursor file
+// records the position of the oldest metric that has not been written yet.
+// Line protocol has no metric types, so every line is prefixed with the type
+// of its metric, ie, "#counter cpu usage=1", and "%", carriage returns and
+// newlines, which line protocol doesn't escape in string fields, are
+// percent-encoded, as is a leading "#". Line protocol parsers see such a line
+// as a comment.
+//
+// Reading is done in two steps: Batch returns the oldest metrics without
+// removing them, and Ack removes them once they have been written. A batch
+// that is never acknowledged, because the write failed or the agent stopped,
+// is returned again by the next call to Batch, even after a restart. Metrics
+// can be added between Batch and Ack, if that drops the oldest metrics the
+// batch is cancelled, and what is left of it is returned again.
+//
+// DiskBuffer is not safe for concurrent use.
+type DiskBuffer struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	// ids of the segment files, oldest first. The last one is being
+	// appended to.
+	segments []uint64
+	w        *os.File
+	wBytes   int64
+
+	// position of the oldest unwritten metric
+	readSeg uint64
+	readOff int64
+
+	// number and size of the unwritten metrics
+	count int
+	bytes int64
+
+	// position and size of the end of the batch returned by Batch
+	batchSeg   uint64
+	batchOff   int64
+	batchCount int
+	batchBytes int64
+
+	parser influx.InfluxParser
+}
+
+// NewDiskBuffer opens the buffer stored in dir, creating it if needed. Once
+// the unwritten metrics take more than maxBytes, the oldest ones are dropped.
+func NewDiskBuffer(dir string, maxBytes int64) (*DiskBuffer, error) {
+	if maxBytes <= 0 {
+		maxBytes = DEFAULT_BUFFER_MAX_BYTES
+	}
+	segmentBytes := int64(bufferSegmentBytes)
+	if maxBytes/4 < segmentBytes {
+		segmentBytes = maxBytes / 4
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Error creating buffer directory %s: %s", dir, err)
+	}
+
+	b := &DiskBuffer{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: segmentBytes,
+	}
+	if err := b.load(); err != nil {
+		return nil, fmt.Errorf("Error loading buffer %s: %s", dir, err)
+	}
+	return b, nil
+}
+
+// Len returns the number of metrics that have not been written yet.
+func (b *DiskBuffer) Len() int {
+	return b.count
+}
+
+// Add appends a metric to the buffer. It returns the number of metrics that
+// were dropped to keep the buffer under its maximum size.
+func (b *DiskBuffer) Add(m telegraf.Metric) (int, error) {
+	line := []byte(bufferLine(m))
+
+	if b.wBytes > 0 && b.wBytes+int64(len(line)) > b.segmentBytes {
+		if err := b.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := b.w.Write(line); err != nil {
+		// don't leave a partial line behind
+		b.w.Truncate(b.wBytes)
+		b.w.Seek(b.wBytes, os.SEEK_SET)
+		return 0, err
+	}
+	b.wBytes += int64(len(line))
+	b.count++
+	b.bytes += int64(len(line))
+
+	dropped := 0
+	for b.bytes > b.maxBytes && len(b.segments) > 1 {
+		n, err := b.dropOldest()
+		dropped += n
+		if err != nil {
+			return dropped, err
+		}
+	}
+	return dropped, nil
+}
+
+// Sync commits the metrics added to the buffer to disk. Metrics that are
+// added but not synced may be lost if the system crashes.
+func (b *DiskBuffer) Sync() error {
+	return b.w.Sync()
+}
+
+// Batch returns up to n of the oldest unwritten metrics. They stay in the
+// buffer until Ack is called.
+func (b *DiskBuffer) Batch(n int) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0, n)
+	b.batchSeg, b.batchOff = b.readSeg, b.readOff
+	b.batchCount, b.batchBytes = 0, 0
+
+	for i, id := range b.segments {
+		if id < b.readSeg {
+			continue
+		}
+		if b.batchCount == n {
+			break
+		}
+		if id != b.batchSeg {
+			b.batchSeg, b.batchOff = id, 0
+		}
+
+		f, err := os.Open(b.segmentPath(id))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(b.batchOff, os.SEEK_SET); err != nil {
+			f.Close()
+			return nil, err
+		}
+		r := bufio.NewReader(f)
+		for b.batchCount < n {
+			line, err := r.ReadBytes('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			b.batchOff += int64(len(line))
+			b.batchCount++
+			b.batchBytes += int64(len(line))
+
+			metric, err := b.parseLine(bytes.TrimSuffix(line, []byte("\n")))
+			if err != nil {
+				log.Printf("W! Skipping unreadable metric in buffer %s: %s", b.dir, err)
+				continue
+			}
+			metrics = append(metrics, metric)
+		}
+		f.Close()
+
+		if i == len(b.segments)-1 && b.batchCount < n {
+			// reached the end of the buffer, so everything left is in this batch
+			b.count = b.batchCount
+			b.bytes = b.batchBytes
+		}
+	}
+	return metrics, nil
+}
+
+// bufferLine returns the metric as a line of the buffer, prefixed with its
+// type.
+func bufferLine(m telegraf.Metric) string {
+	t := m.Type()
+	if _, ok := bufferTypes[t.String()]; !ok {
+		t = telegraf.Untyped
+	}
+	line := bufferEscaper.Replace(m.String())
+	if strings.HasPrefix(line, "#") {
+		// the parser would take it for a comment
+		line = "%23" + line[1:]
+	}
+	return "#" + t.String() + " " + line + "\n"
+}
+
+// parseLine parses a line of the buffer, see bufferLine. Lines without a type
+// prefix are read as untyped line protocol.
+func (b *DiskBuffer) parseLine(line []byte) (telegraf.Metric, error) {
+	if len(line) == 0 || line[0] != '#' {
+		return b.parser.ParseLine(string(line))
+	}
+
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		return nil, fmt.Errorf("missing metric after type %q", line)
+	}
+	mType, ok := bufferTypes[string(line[1:i])]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric type %q", line[1:i])
+	}
+	line = line[i+1:]
+
+	m, err := b.parser.ParseLine(string(line))
+	if err != nil {
+		return nil, err
+	}
+	escaped := bytes.IndexByte(line, '%') >= 0
+	if mType == telegraf.Untyped && !escaped {
+		return m, nil
+	}
+
+	name, tags, fields := m.Name(), m.Tags(), m.Fields()
+	if escaped {
+		name = bufferUnescaper.Replace(name)
+		tags = make(map[string]string, len(m.Tags()))
+		for k, v := range m.Tags() {
+			tags[bufferUnescaper.Replace(k)] = bufferUnescaper.Replace(v)
+		}
+		fields = make(map[string]interface{}, len(m.Fields()))
+		for k, v := range m.Fields() {
+			if str, ok := v.(string); ok {
+				v = bufferUnescaper.Replace(str)
+			}
+			fields[bufferUnescaper.Replace(k)] = v
+		}
+	}
+	return telegraf.NewTypedMetric(name, tags, fields, mType, m.Time())
+}
+
+var (
+	bufferEscaper   = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	bufferUnescaper = strings.NewReplacer(
+		"%25", "%", "%0D", "\r", "%0A", "\n", "%23", "#")
+)
+
+// bufferTypes are the metric types kept in the buffer.
+var bufferTypes = map[string]telegraf.ValueType{
+	telegraf.Untyped.String():   telegraf.Untyped,
+	telegraf.Counter.String():   telegraf.Counter,
+	telegraf.Gauge.String():     telegraf.Gauge,
+	telegraf.Summary.String():   telegraf.Summary,
+	telegraf.Histogram.String(): telegraf.Histogram,
+}
+
+// Ack removes the metrics returned by the last call to Batch from the buffer.
+func (b *DiskBuffer) Ack() error {
+	if err := b.w.Sync(); err != nil {
+		return err
+	}
+	b.readSeg, b.readOff = b.batchSeg, b.batchOff
+	b.count -= b.batchCount
+	b.bytes -= b.batchBytes
+	b.batchCount, b.batchBytes = 0, 0
+	if err := b.saveCursor(); err != nil {
+		return err
+	}
+	return b.removeReadSegments()
+}
+
+// Close syncs the buffer to disk and closes it.
+func (b *DiskBuffer) Close() error {
+	if err := b.w.Sync(); err != nil {
+		b.w.Close()
+		return err
+	}
+	if err := b.w.Close(); err != nil {
+		return err
+	}
+	return b.saveCursor()
+}
+
+// load reads the segments and the cursor found in the buffer directory.
+func (b *DiskBuffer) load() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, bufferSegmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, bufferSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, id)
+	}
+	sort.Sort(segmentIDs(b.segments))
+
+	if err := b.loadCursor(); err != nil {
+		return err
+	}
+	if len(b.segments) == 0 {
+		b.segments = []uint64{b.readSeg}
+		b.readOff = 0
+	}
+	if b.readSeg < b.segments[0] || b.readSeg > b.segments[len(b.segments)-1] {
+		b.readSeg, b.readOff = b.segments[0], 0
+	}
+	if err := b.removeReadSegments(); err != nil {
+		return err
+	}
+
+	last := b.segments[len(b.segments)-1]
+	b.w, err = os.OpenFile(b.segmentPath(last), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	// a crash may have left a partial metric at the end of the last segment
+	if b.wBytes, err = lastLineEnd(b.w); err != nil {
+		b.w.Close()
+		return err
+	}
+	if err := b.w.Truncate(b.wBytes); err != nil {
+		b.w.Close()
+		return err
+	}
+	if _, err := b.w.Seek(b.wBytes, os.SEEK_SET); err != nil {
+		b.w.Close()
+		return err
+	}
+	if b.readSeg == last && b.readOff > b.wBytes {
+		b.readOff = b.wBytes
+	}
+
+	// count the unwritten metrics
+	for _, id := range b.segments {
+		var off int64
+		if id == b.readSeg {
+			off = b.readOff
+		}
+		n, size, err := countLines(b.segmentPath(id), off)
+		if err != nil {
+			b.w.Close()
+			return err
+		}
+		b.count += n
+		b.bytes += size
+	}
+	return nil
+}
+
+func (b *DiskBuffer) loadCursor() error {
+	b.readSeg, b.readOff = 1, 0
+	data, err := ioutil.ReadFile(filepath.Join(b.dir, bufferCursorFile))
+	if os.IsNotExist(err) {
+		if len(b.segments) > 0 {
+			b.readSeg = b.segments[0]
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Sscanf(string(data), "%d %d", &b.readSeg, &b.readOff); err != nil {
+		return fmt.Errorf("invalid cursor file: %s", err)
+	}
+	return nil
+}
+
+// saveCursor atomically replaces the cursor file with the current read
+// position.
+func (b *DiskBuffer) saveCursor() error {
+	path := filepath.Join(b.dir, bufferCursorFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%d %d\n", b.readSeg, b.readOff); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// rotate starts a new segment.
+func (b *DiskBuffer) rotate() error {
+	if err := b.w.Sync(); err != nil {
+		return err
+	}
+	if err := b.w.Close(); err != nil {
+		return err
+	}
+	id := b.segments[len(b.segments)-1] + 1
+	w, err := os.OpenFile(b.segmentPath(id), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	b.w = w
+	b.wBytes = 0
+	b.segments = append(b.segments, id)
+	return nil
+}
+
+// dropOldest removes the oldest segment, returning the number of unwritten
+// metrics that were in it.
+func (b *DiskBuffer) dropOldest() (int, error) {
+	n, size, err := countLines(b.segmentPath(b.segments[0]), b.readOff)
+	if err != nil {
+		return 0, err
+	}
+	b.count -= n
+	b.bytes -= size
+	b.readSeg, b.readOff = b.segments[1], 0
+	// the batch being written may be in the dropped segment, so Ack must not
+	// move the read position back to its end
+	b.batchSeg, b.batchOff = b.readSeg, b.readOff
+	b.batchCount, b.batchBytes = 0, 0
+	if err := b.saveCursor(); err != nil {
+		return n, err
+	}
+	return n, b.removeReadSegments()
+}
+
+// removeReadSegments deletes the segments that are before the read position.
+func (b *DiskBuffer) removeReadSegments() error {
+	for len(b.segments) > 1 && b.segments[0] < b.readSeg {
+		if err := os.Remove(b.segmentPath(b.segments[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		b.segments = b.segments[1:]
+	}
+	return nil
+}
+
+func (b *DiskBuffer) segmentPath(id uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, bufferSegmentExt))
+}
+
+// countLines returns the number and total size of the complete lines in the
+// file, starting at offset.
+func countLines(path string, offset int64) (int, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, os.SEEK_SET); err != nil {
+		return 0, 0, err
+	}
+
+	var n int
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return n, size, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		n++
+		size += int64(len(line))
+	}
+}
+
+// lastLineEnd returns the offset just after the last newline in the file.
+func lastLineEnd(f *os.File) (int64, error) {
+	var end, off int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		off += int64(len(line))
+		if err == io.EOF {
+			return end, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		end = off
+	}
+}
+
+type segmentIDs []uint64
+
+func (s segmentIDs) Len() int           { return len(s) }
+func (s segmentIDs) Less(i, j int) bool { return s[i] < s[j] }
+func (s segmentIDs) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
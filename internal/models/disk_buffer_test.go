@@ -0,0 +1,273 @@
+package internal_models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, maxBytes int64) (*DiskBuffer, string) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	b, err := NewDiskBuffer(dir, maxBytes)
+	require.NoError(t, err)
+	return b, dir
+}
+
+func metricStrings(metrics []telegraf.Metric) []string {
+	var s []string
+	for _, m := range metrics {
+		s = append(s, m.String())
+	}
+	return s
+}
+
+func TestDiskBuffer_BatchAndAck(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	for _, m := range first5 {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 5, b.Len())
+
+	batch, err := b.Batch(3)
+	require.NoError(t, err)
+	assert.Equal(t, metricStrings(first5[:3]), metricStrings(batch))
+
+	// without an Ack, the same batch is returned again
+	batch, err = b.Batch(3)
+	require.NoError(t, err)
+	assert.Equal(t, metricStrings(first5[:3]), metricStrings(batch))
+	assert.Equal(t, 5, b.Len())
+
+	require.NoError(t, b.Ack())
+	assert.Equal(t, 2, b.Len())
+
+	batch, err = b.Batch(3)
+	require.NoError(t, err)
+	assert.Equal(t, metricStrings(first5[3:]), metricStrings(batch))
+	require.NoError(t, b.Ack())
+	assert.Equal(t, 0, b.Len())
+}
+
+// Test that unacknowledged metrics survive reopening the buffer, and that
+// acknowledged ones are not returned again.
+func TestDiskBuffer_Reopen(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+
+	for _, m := range first5 {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	_, err := b.Batch(2)
+	require.NoError(t, err)
+	require.NoError(t, b.Ack())
+
+	// this batch is interrupted before it is acknowledged
+	_, err = b.Batch(2)
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+
+	for _, m := range next5 {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	batch, err := b.Batch(100)
+	require.NoError(t, err)
+	expected := append(metricStrings(first5[2:]), metricStrings(next5)...)
+	assert.Equal(t, expected, metricStrings(batch))
+}
+
+// Test that metric types are kept in the buffer.
+func TestDiskBuffer_Types(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1257894000, 0)
+	counter, err := telegraf.NewCounterMetric("requests",
+		map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, now)
+	require.NoError(t, err)
+	gauge, err := telegraf.NewGaugeMetric("memory",
+		nil, map[string]interface{}{"used": 2.5}, now)
+	require.NoError(t, err)
+	for _, m := range []telegraf.Metric{counter, gauge, first5[0]} {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	batch, err := b.Batch(3)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, metricStrings([]telegraf.Metric{counter, gauge, first5[0]}),
+		metricStrings(batch))
+	assert.Equal(t, telegraf.Counter, batch[0].Type())
+	assert.Equal(t, telegraf.Gauge, batch[1].Type())
+	assert.Equal(t, telegraf.Untyped, batch[2].Type())
+}
+
+// Test that string fields with newlines, and measurements starting with "#",
+// are kept in the buffer.
+func TestDiskBuffer_Escaping(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1257894000, 0)
+	multiline, err := telegraf.NewMetric("log",
+		map[string]string{"path": "/var/log/100%"},
+		map[string]interface{}{"message": "first line\nsecond line\r\n%0A"}, now)
+	require.NoError(t, err)
+	hash, err := telegraf.NewMetric("#hash", nil,
+		map[string]interface{}{"value": 1}, now)
+	require.NoError(t, err)
+	for _, m := range []telegraf.Metric{multiline, hash, first5[0]} {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 3, b.Len())
+	batch, err := b.Batch(3)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "log", batch[0].Name())
+	assert.Equal(t, map[string]string{"path": "/var/log/100%"}, batch[0].Tags())
+	assert.Equal(t, "first line\nsecond line\r\n%0A", batch[0].Fields()["message"])
+	assert.Equal(t, "#hash", batch[1].Name())
+	assert.Equal(t, int64(1), batch[1].Fields()["value"])
+	assert.Equal(t, first5[0].String(), batch[2].String())
+}
+
+// Test that lines of line protocol without a type prefix are read as untyped
+// metrics.
+func TestDiskBuffer_PlainLines(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+
+	_, err := b.w.Write([]byte(first5[0].String() + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	batch, err := b.Batch(1)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, first5[0].String(), batch[0].String())
+	assert.Equal(t, telegraf.Untyped, batch[0].Type())
+}
+
+// Test that a partial metric left at the end of the buffer by a crash is
+// discarded.
+func TestDiskBuffer_PartialWrite(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0)
+	defer os.RemoveAll(dir)
+
+	for _, m := range first5 {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	_, err := b.w.Write([]byte("metric6 value=1"))
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 5, b.Len())
+
+	_, err = b.Add(next5[0])
+	require.NoError(t, err)
+	batch, err := b.Batch(100)
+	require.NoError(t, err)
+	expected := append(metricStrings(first5), next5[0].String())
+	assert.Equal(t, expected, metricStrings(batch))
+}
+
+// Test that the oldest metrics are dropped once the buffer is full, and that
+// fully written segments are removed.
+func TestDiskBuffer_MaxBytes(t *testing.T) {
+	m := testutil.TestMetric(101, "metric1")
+	size := int64(len(bufferLine(m)))
+
+	// 4 metrics per segment, 16 metrics in the buffer
+	b, dir := newTestDiskBuffer(t, 16*size)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	for i := 0; i < 16; i++ {
+		dropped, err := b.Add(m)
+		require.NoError(t, err)
+		assert.Equal(t, 0, dropped)
+	}
+	assert.Equal(t, 16, b.Len())
+
+	dropped, err := b.Add(m)
+	require.NoError(t, err)
+	assert.Equal(t, 4, dropped)
+	assert.Equal(t, 13, b.Len())
+
+	batch, err := b.Batch(100)
+	require.NoError(t, err)
+	assert.Len(t, batch, 13)
+	require.NoError(t, b.Ack())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+// Test that a batch that loses metrics to the buffer being full before it is
+// acknowledged is cancelled.
+func TestDiskBuffer_MaxBytesDuringBatch(t *testing.T) {
+	m := testutil.TestMetric(101, "metric1")
+	size := int64(len(bufferLine(m)))
+
+	// 4 metrics per segment, 16 metrics in the buffer
+	b, dir := newTestDiskBuffer(t, 16*size)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	for i := 0; i < 16; i++ {
+		_, err := b.Add(m)
+		require.NoError(t, err)
+	}
+	batch, err := b.Batch(6)
+	require.NoError(t, err)
+	assert.Len(t, batch, 6)
+
+	// the first segment, with 4 metrics of the batch, is dropped
+	dropped, err := b.Add(m)
+	require.NoError(t, err)
+	assert.Equal(t, 4, dropped)
+	require.NoError(t, b.Ack())
+	assert.Equal(t, 13, b.Len())
+
+	batch, err = b.Batch(100)
+	require.NoError(t, err)
+	assert.Len(t, batch, 13)
+}
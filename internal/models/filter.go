@@ -2,6 +2,8 @@ package internal_models
 
 import (
 	"fmt"
+	"log"
+	"regexp"
 	"strings"
 
 	"github.com/gobwas/glob"
@@ -13,34 +15,58 @@ import (
 type TagFilter struct {
 	Name   string
 	Filter []string
-	filter glob.Glob
+	filter *matcher
 }
 
+// Modes of combining the tag filters of tagpass and tagdrop.
+const (
+	// TagModeOr matches if any of the tag filters matches, the default
+	TagModeOr = "or"
+	// TagModeAnd matches if all of the tag filters match
+	TagModeAnd = "and"
+)
+
 // Filter containing drop/pass and tagdrop/tagpass rules
 type Filter struct {
 	NameDrop []string
-	nameDrop glob.Glob
+	nameDrop *matcher
 	NamePass []string
-	namePass glob.Glob
+	namePass *matcher
 
 	FieldDrop []string
-	fieldDrop glob.Glob
+	fieldDrop *matcher
 	FieldPass []string
-	fieldPass glob.Glob
+	fieldPass *matcher
 
 	TagDrop []TagFilter
 	TagPass []TagFilter
+	// TagMode is how the tag filters of TagPass and TagDrop are combined,
+	// TagModeOr or TagModeAnd
+	TagMode string
 
 	TagExclude []string
-	tagExclude glob.Glob
+	tagExclude *matcher
 	TagInclude []string
-	tagInclude glob.Glob
+	tagInclude *matcher
+
+	ValueDrop []string
+	valueDrop []*ValueFilter
+	ValuePass []string
+	valuePass []*ValueFilter
 
 	IsActive bool
 }
 
-// Compile all Filter lists into glob.Glob objects.
+// Compile all Filter lists into matchers of their glob and regular expression
+// patterns.
 func (f *Filter) CompileFilter() error {
+	switch f.TagMode {
+	case "", TagModeOr, TagModeAnd:
+	default:
+		return fmt.Errorf("Error compiling 'tagmode', %q is not %q or %q",
+			f.TagMode, TagModeOr, TagModeAnd)
+	}
+
 	var err error
 	f.nameDrop, err = compileFilter(f.NameDrop)
 	if err != nil {
@@ -81,10 +107,100 @@ func (f *Filter) CompileFilter() error {
 			return fmt.Errorf("Error compiling 'tagpass', %s", err)
 		}
 	}
+
+	f.valueDrop, err = compileValueFilters(f.ValueDrop)
+	if err != nil {
+		return fmt.Errorf("Error compiling 'valuedrop', %s", err)
+	}
+	f.valuePass, err = compileValueFilters(f.ValuePass)
+	if err != nil {
+		return fmt.Errorf("Error compiling 'valuepass', %s", err)
+	}
 	return nil
 }
 
-func compileFilter(filter []string) (glob.Glob, error) {
+func compileValueFilters(conditions []string) ([]*ValueFilter, error) {
+	var filters []*ValueFilter
+	for _, c := range conditions {
+		vf, err := CompileValueFilter(c)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, vf)
+	}
+	return filters, nil
+}
+
+// regexpPrefix starts the patterns that are regular expressions rather than
+// globs, and negatePrefix the patterns that must not match.
+const (
+	regexpPrefix = "re:"
+	negatePrefix = "!"
+)
+
+// matcher matches strings against a list of patterns. A string matches if it
+// matches any of the patterns, or if there are only negated patterns, and
+// none of the negated patterns.
+type matcher struct {
+	glob    glob.Glob
+	regexps []*regexp.Regexp
+
+	notGlob    glob.Glob
+	notRegexps []*regexp.Regexp
+}
+
+// compileFilter compiles the patterns of a filter. Patterns are globs, or
+// regular expressions if they start with "re:", ie, "re:^sd[a-z]$". Patterns
+// starting with "!" are negated. Globs that match a leading "!" or "re:"
+// literally must escape it, ie, `\!cpu` or `re\:cpu`. It returns nil if there
+// are no patterns.
+func compileFilter(filter []string) (*matcher, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	var globs, notGlobs []string
+	m := &matcher{}
+	for _, pattern := range filter {
+		negated := strings.HasPrefix(pattern, negatePrefix)
+		if negated {
+			// before v0.13, this was a glob matching a leading "!"
+			log.Printf("W! Filter pattern '%s' is negated, use '%s' to match "+
+				"a leading \"!\"", pattern, `\`+pattern)
+			pattern = strings.TrimPrefix(pattern, negatePrefix)
+		}
+
+		if strings.HasPrefix(pattern, regexpPrefix) {
+			// before v0.13, this was a glob matching a leading "re:"
+			log.Printf("W! Filter pattern '%s' is a regular expression, use '%s' "+
+				"to match a leading \"re:\"", pattern,
+				`re\`+strings.TrimPrefix(pattern, "re"))
+			re, err := regexp.Compile(strings.TrimPrefix(pattern, regexpPrefix))
+			if err != nil {
+				return nil, err
+			}
+			if negated {
+				m.notRegexps = append(m.notRegexps, re)
+			} else {
+				m.regexps = append(m.regexps, re)
+			}
+		} else if negated {
+			notGlobs = append(notGlobs, pattern)
+		} else {
+			globs = append(globs, pattern)
+		}
+	}
+
+	var err error
+	if m.glob, err = compileGlobs(globs); err != nil {
+		return nil, err
+	}
+	if m.notGlob, err = compileGlobs(notGlobs); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func compileGlobs(filter []string) (glob.Glob, error) {
 	if len(filter) == 0 {
 		return nil, nil
 	}
@@ -98,8 +214,48 @@ func compileFilter(filter []string) (glob.Glob, error) {
 	return g, err
 }
 
+// Match returns true if s matches the patterns.
+func (m *matcher) Match(s string) bool {
+	return m.matchPositive(s) && !m.matchNegated(s)
+}
+
+func (m *matcher) matchPositive(s string) bool {
+	if m.glob == nil && len(m.regexps) == 0 {
+		// there are only negated patterns
+		return true
+	}
+	if m.glob != nil && m.glob.Match(s) {
+		return true
+	}
+	for _, re := range m.regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *matcher) matchNegated(s string) bool {
+	if m.notGlob != nil && m.notGlob.Match(s) {
+		return true
+	}
+	for _, re := range m.notRegexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMissing returns true if the patterns match a tag that is not set, which
+// they do if they are only negated patterns.
+func (m *matcher) matchMissing() bool {
+	return m.glob == nil && len(m.regexps) == 0
+}
+
 func (f *Filter) ShouldMetricPass(metric telegraf.Metric) bool {
-	if f.ShouldNamePass(metric.Name()) && f.ShouldTagsPass(metric.Tags()) {
+	if f.ShouldNamePass(metric.Name()) && f.ShouldTagsPass(metric.Tags()) &&
+		f.ShouldValuesPass(metric.Tags(), metric.Fields()) {
 		return true
 	}
 	return false
@@ -145,52 +301,86 @@ func (f *Filter) ShouldFieldsPass(key string) bool {
 // based on the tagdrop/tagpass filter parameters
 func (f *Filter) ShouldTagsPass(tags map[string]string) bool {
 	if f.TagPass != nil {
-		for _, pat := range f.TagPass {
-			if pat.filter == nil {
-				continue
-			}
-			if tagval, ok := tags[pat.Name]; ok {
-				if pat.filter.Match(tagval) {
-					return true
-				}
-			}
-		}
-		return false
+		return f.matchTags(f.TagPass, tags)
 	}
 
 	if f.TagDrop != nil {
-		for _, pat := range f.TagDrop {
-			if pat.filter == nil {
-				continue
-			}
-			if tagval, ok := tags[pat.Name]; ok {
-				if pat.filter.Match(tagval) {
-					return false
-				}
+		return !f.matchTags(f.TagDrop, tags)
+	}
+
+	return true
+}
+
+// matchTags returns true if the tags match any of the tag filters, or all of
+// them with TagModeAnd. A tag filter doesn't match a metric without the tag,
+// unless all its patterns are negated.
+func (f *Filter) matchTags(filters []TagFilter, tags map[string]string) bool {
+	and := f.TagMode == TagModeAnd
+	matched := false
+	for _, pat := range filters {
+		if pat.filter == nil {
+			continue
+		}
+		var match bool
+		if tagval, ok := tags[pat.Name]; ok {
+			match = pat.filter.Match(tagval)
+		} else {
+			match = pat.filter.matchMissing()
+		}
+		if match && !and {
+			return true
+		}
+		if !match && and {
+			return false
+		}
+		matched = match
+	}
+	return matched
+}
+
+// ShouldValuesPass returns true if the metric with the given tags and fields
+// should pass, false if should drop based on the valuepass/valuedrop filter
+// parameters. It passes if it meets any of the valuepass conditions, and drops
+// if it meets any of the valuedrop conditions.
+func (f *Filter) ShouldValuesPass(
+	tags map[string]string,
+	fields map[string]interface{},
+) bool {
+	if f.valuePass != nil {
+		for _, vf := range f.valuePass {
+			if vf.Match(tags, fields) {
+				return true
 			}
 		}
-		return true
+		return false
 	}
 
+	for _, vf := range f.valueDrop {
+		if vf.Match(tags, fields) {
+			return false
+		}
+	}
 	return true
 }
 
 // Apply TagInclude and TagExclude filters.
 // modifies the tags map in-place.
 func (f *Filter) FilterTags(tags map[string]string) {
-	if f.tagInclude != nil {
-		for k, _ := range tags {
-			if !f.tagInclude.Match(k) {
-				delete(tags, k)
-			}
+	for k := range tags {
+		if !f.ShouldTagKeyPass(k) {
+			delete(tags, k)
 		}
 	}
+}
 
-	if f.tagExclude != nil {
-		for k, _ := range tags {
-			if f.tagExclude.Match(k) {
-				delete(tags, k)
-			}
-		}
+// ShouldTagKeyPass returns true if the tag should be kept, false if it should
+// be removed based on the taginclude/tagexclude filter parameters
+func (f *Filter) ShouldTagKeyPass(key string) bool {
+	if f.tagInclude != nil && !f.tagInclude.Match(key) {
+		return false
 	}
+	if f.tagExclude != nil && f.tagExclude.Match(key) {
+		return false
+	}
+	return true
 }
@@ -1,6 +1,9 @@
 package internal_models
 
 import (
+	"bytes"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
@@ -253,6 +256,21 @@ func TestFilter_TagDrop(t *testing.T) {
 	}
 }
 
+// Patterns which were globs before v0.13 are logged when compiled.
+func TestFilter_CompileFilterWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	_, err := compileFilter([]string{"cpu", "!mem*", "re:^disk$"})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(),
+		`W! Filter pattern '!mem*' is negated, use '\!mem*' to match`)
+	assert.Contains(t, buf.String(),
+		`W! Filter pattern 're:^disk$' is a regular expression, use 're\:^disk$'`)
+	assert.NotContains(t, buf.String(), "'cpu'")
+}
+
 func TestFilter_CompileFilterError(t *testing.T) {
 	f := Filter{
 		NameDrop: []string{"", ""},
@@ -364,3 +382,138 @@ func TestFilter_FilterTagsMatches(t *testing.T) {
 		"mytag": "foobar",
 	}, pretags)
 }
+
+func TestFilter_ValuePass(t *testing.T) {
+	f := Filter{
+		ValuePass: []string{"value < 90", `tag1 == "other"`},
+	}
+	require.NoError(t, f.CompileFilter())
+
+	assert.True(t, f.ShouldMetricPass(testutil.TestMetric(50)))
+	assert.False(t, f.ShouldMetricPass(testutil.TestMetric(95)))
+	assert.False(t, f.ShouldMetricPass(testutil.TestMetric("string")))
+}
+
+func TestFilter_ValueDrop(t *testing.T) {
+	f := Filter{
+		ValueDrop: []string{`tag1 == "value1"`},
+	}
+	require.NoError(t, f.CompileFilter())
+
+	assert.False(t, f.ShouldMetricPass(testutil.TestMetric(50)))
+	assert.True(t, f.ShouldValuesPass(map[string]string{"tag1": "value2"},
+		map[string]interface{}{"value": 50}))
+
+	f = Filter{
+		ValueDrop: []string{"value <"},
+	}
+	assert.Error(t, f.CompileFilter())
+}
+
+func TestFilter_NameRegexpAndNegation(t *testing.T) {
+	f := Filter{
+		NamePass:  []string{"re:^sd[a-z]$", "cpu*", "!cpu_guest*"},
+		FieldDrop: []string{"!re:^usage_"},
+	}
+	require.NoError(t, f.CompileFilter())
+
+	for _, name := range []string{"sda", "sdb", "cpu", "cpu_usage"} {
+		assert.True(t, f.ShouldNamePass(name), name)
+	}
+	for _, name := range []string{"sda1", "xsda", "cpu_guest", "cpu_guest_nice", "mem"} {
+		assert.False(t, f.ShouldNamePass(name), name)
+	}
+
+	assert.True(t, f.ShouldFieldsPass("usage_idle"))
+	assert.False(t, f.ShouldFieldsPass("time_idle"))
+
+	f = Filter{
+		NamePass: []string{"re:sd[a-z"},
+	}
+	assert.Error(t, f.CompileFilter())
+}
+
+// Test that globs keep matching as before, unless they start with "!" or
+// "re:", which can be escaped to match them literally.
+func TestFilter_GlobPrefixes(t *testing.T) {
+	f := Filter{
+		NamePass: []string{"re*", "*!", "cpu!*", `\!net*`, `re\:disk*`},
+	}
+	require.NoError(t, f.CompileFilter())
+
+	for _, name := range []string{"re", "redis", "re:", "mem!", "cpu!guest",
+		"!net", "!net_eth0", "re:disk", "re:disk_sda"} {
+		assert.True(t, f.ShouldNamePass(name), name)
+	}
+	for _, name := range []string{"net", "disk", "cpu", "r:disk"} {
+		assert.False(t, f.ShouldNamePass(name), name)
+	}
+
+	// without escaping, "!" negates and "re:" starts a regular expression
+	f = Filter{
+		NamePass: []string{"!net*"},
+	}
+	require.NoError(t, f.CompileFilter())
+	assert.True(t, f.ShouldNamePass("!net"))
+	assert.False(t, f.ShouldNamePass("net_eth0"))
+
+	f = Filter{
+		NamePass: []string{"re:^disk$"},
+	}
+	require.NoError(t, f.CompileFilter())
+	assert.True(t, f.ShouldNamePass("disk"))
+	assert.False(t, f.ShouldNamePass("re:disk"))
+}
+
+func TestFilter_TagMode(t *testing.T) {
+	filters := []TagFilter{
+		TagFilter{
+			Name:   "container_name",
+			Filter: []string{"re:^web-[0-9]+$"},
+		},
+		TagFilter{
+			Name:   "device",
+			Filter: []string{"!lo"},
+		}}
+	f := Filter{
+		TagPass: filters,
+		TagMode: TagModeAnd,
+	}
+	require.NoError(t, f.CompileFilter())
+
+	passes := []map[string]string{
+		{"container_name": "web-1", "device": "eth0"},
+		// a tag that isn't set doesn't match a negated pattern
+		{"container_name": "web-12"},
+	}
+	drops := []map[string]string{
+		{"container_name": "web-1", "device": "lo"},
+		{"container_name": "web-x", "device": "eth0"},
+		{"device": "eth0"},
+	}
+	for _, tags := range passes {
+		assert.True(t, f.ShouldTagsPass(tags), "%v", tags)
+	}
+	for _, tags := range drops {
+		assert.False(t, f.ShouldTagsPass(tags), "%v", tags)
+	}
+
+	// the same filters dropping metrics
+	f = Filter{
+		TagDrop: filters,
+		TagMode: TagModeAnd,
+	}
+	require.NoError(t, f.CompileFilter())
+	for _, tags := range passes {
+		assert.False(t, f.ShouldTagsPass(tags), "%v", tags)
+	}
+	for _, tags := range drops {
+		assert.True(t, f.ShouldTagsPass(tags), "%v", tags)
+	}
+
+	f = Filter{
+		TagPass: filters,
+		TagMode: "xor",
+	}
+	assert.Error(t, f.CompileFilter())
+}
@@ -0,0 +1,85 @@
+package internal_models
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// Default delay before the first retry of a failed write or connect.
+	DEFAULT_RETRY_INITIAL_BACKOFF = time.Second
+
+	// Default limit of the delay between retries.
+	DEFAULT_RETRY_MAX_BACKOFF = time.Minute
+
+	// Shortest delay between two background connection attempts.
+	minReconnectInterval = time.Second
+)
+
+// RetryConfig is the retry policy of an output.
+type RetryConfig struct {
+	// InitialBackoff is the delay before the first retry. It doubles with
+	// every consecutive failure, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the maximum random delay added to every backoff.
+	Jitter time.Duration
+
+	// MaxRetries is the number of times a batch is retried before it is
+	// dropped. 0 retries forever.
+	MaxRetries int
+
+	// CircuitBreakerThreshold is the number of consecutive failed writes
+	// that opens the circuit breaker. 0 disables the circuit breaker.
+	CircuitBreakerThreshold int
+	// CircuitBreakerTimeout is how long the circuit breaker stays open
+	// before a write is tried again.
+	CircuitBreakerTimeout time.Duration
+}
+
+// backoff returns the delay before retrying after the given number of
+// consecutive failures.
+func (c RetryConfig) backoff(failures int) time.Duration {
+	d := c.InitialBackoff
+	for i := 1; i < failures && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if c.MaxBackoff > 0 && d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	if c.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(c.Jitter)))
+	}
+	return d
+}
+
+// circuitBreaker stops calls to an output after too many consecutive
+// failures. Once open, it lets a single call through after its timeout; if
+// that call fails too, it opens again.
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+
+	failures  int
+	openUntil time.Time
+}
+
+// allow returns whether a call may be made at the given time.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	return !cb.isOpen() || !now.Before(cb.openUntil)
+}
+
+func (cb *circuitBreaker) isOpen() bool {
+	return cb.threshold > 0 && cb.failures >= cb.threshold
+}
+
+func (cb *circuitBreaker) success() {
+	cb.failures = 0
+}
+
+func (cb *circuitBreaker) failure(now time.Time) {
+	cb.failures++
+	if cb.isOpen() {
+		cb.openUntil = now.Add(cb.timeout)
+	}
+}
@@ -0,0 +1,67 @@
+package internal_models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	c := RetryConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}
+	assert.Equal(t, time.Second, c.backoff(1))
+	assert.Equal(t, 2*time.Second, c.backoff(2))
+	assert.Equal(t, 8*time.Second, c.backoff(4))
+	assert.Equal(t, 10*time.Second, c.backoff(5))
+	assert.Equal(t, 10*time.Second, c.backoff(100))
+}
+
+func TestRetryConfig_BackoffJitter(t *testing.T) {
+	c := RetryConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         time.Second,
+	}
+	for i := 0; i < 100; i++ {
+		d := c.backoff(2)
+		assert.True(t, d >= 2*time.Second && d < 3*time.Second, d.String())
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := &circuitBreaker{threshold: 2, timeout: time.Minute}
+	assert.True(t, cb.allow(now))
+
+	cb.failure(now)
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.False(t, cb.allow(now))
+	assert.False(t, cb.allow(now.Add(59*time.Second)))
+
+	// half-open after the timeout, and open again on failure
+	now = now.Add(time.Minute)
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.False(t, cb.allow(now))
+
+	// closed again on success
+	now = now.Add(time.Minute)
+	assert.True(t, cb.allow(now))
+	cb.success()
+	assert.True(t, cb.allow(now))
+	cb.failure(now)
+	assert.True(t, cb.allow(now))
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	now := time.Now()
+	cb := &circuitBreaker{}
+	for i := 0; i < 100; i++ {
+		cb.failure(now)
+	}
+	assert.True(t, cb.allow(now))
+}
@@ -0,0 +1,90 @@
+package internal_models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+type RunningAggregator struct {
+	Name string
+
+	sync.Mutex
+	Aggregator telegraf.Aggregator
+	Config     *AggregatorConfig
+}
+
+// AggregatorConfig containing configuration parameters for the running
+// aggregator plugin.
+type AggregatorConfig struct {
+	Name  string
+	Alias string
+
+	DropOriginal      bool
+	NameOverride      string
+	MeasurementPrefix string
+	MeasurementSuffix string
+	Tags              map[string]string
+	Filter            Filter
+
+	Period time.Duration
+}
+
+// InputConfig returns an InputConfig carrying the naming and tagging options
+// of the aggregator, so that the aggregates it pushes can be decorated by a
+// regular input accumulator.
+func (r *RunningAggregator) InputConfig() *InputConfig {
+	return &InputConfig{
+		Name:              r.Name,
+		NameOverride:      r.Config.NameOverride,
+		MeasurementPrefix: r.Config.MeasurementPrefix,
+		MeasurementSuffix: r.Config.MeasurementSuffix,
+		Tags:              r.Config.Tags,
+	}
+}
+
+// Add applies the given metric to the aggregator, if it passes the
+// aggregator's filter. Add returns true if the original metric should be
+// dropped.
+func (r *RunningAggregator) Add(in telegraf.Metric) bool {
+	if r.Config.Filter.IsActive {
+		if !r.Config.Filter.ShouldMetricPass(in) {
+			return false
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.Aggregator.Add(in)
+	return r.Config.DropOriginal
+}
+
+// Push pushes the current aggregates to the accumulator and resets the
+// aggregator.
+func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
+	r.Lock()
+	defer r.Unlock()
+	r.Aggregator.Push(acc)
+	r.Aggregator.Reset()
+}
+
+// Run pushes the aggregates on every period until shutdown is closed. A final
+// push is made on shutdown so that the last partial period is not lost.
+func (r *RunningAggregator) Run(
+	acc telegraf.Accumulator,
+	shutdown chan struct{},
+) {
+	ticker := time.NewTicker(r.Config.Period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			r.Push(acc)
+			return
+		case <-ticker.C:
+			r.Push(acc)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package internal_models
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningAggregator_Add(t *testing.T) {
+	a := &TestAggregator{}
+	ra := &RunningAggregator{
+		Name:       "test",
+		Aggregator: a,
+		Config: &AggregatorConfig{
+			Period: time.Minute,
+		},
+	}
+
+	assert.False(t, ra.Add(testutil.TestMetric(101, "metric1")))
+	assert.False(t, ra.Add(testutil.TestMetric(101, "metric2")))
+
+	acc := testutil.Accumulator{}
+	ra.Push(&acc)
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(202)})
+
+	// Push resets the aggregator
+	acc = testutil.Accumulator{}
+	ra.Push(&acc)
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(0)})
+}
+
+func TestRunningAggregator_DropOriginal(t *testing.T) {
+	ra := &RunningAggregator{
+		Name:       "test",
+		Aggregator: &TestAggregator{},
+		Config: &AggregatorConfig{
+			DropOriginal: true,
+			Filter: Filter{
+				IsActive: true,
+				NamePass: []string{"RI*"},
+			},
+		},
+	}
+	require.NoError(t, ra.Config.Filter.CompileFilter())
+
+	// metric passes the filter, so the original is dropped
+	assert.True(t, ra.Add(testutil.TestMetric(101, "RITest")))
+	// metric doesn't pass the filter, so it is kept and not aggregated
+	assert.False(t, ra.Add(testutil.TestMetric(101, "foobar")))
+
+	acc := testutil.Accumulator{}
+	ra.Push(&acc)
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(101)})
+}
+
+func TestRunningAggregator_RunPushesOnShutdown(t *testing.T) {
+	ra := &RunningAggregator{
+		Name:       "test",
+		Aggregator: &TestAggregator{},
+		Config: &AggregatorConfig{
+			Period: time.Hour,
+		},
+	}
+	ra.Add(testutil.TestMetric(5, "metric1"))
+
+	acc := testutil.Accumulator{}
+	shutdown := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ra.Run(&acc, shutdown)
+	}()
+	close(shutdown)
+	wg.Wait()
+
+	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(5)})
+}
+
+type TestAggregator struct {
+	sum int64
+}
+
+func (t *TestAggregator) Description() string  { return "" }
+func (t *TestAggregator) SampleConfig() string { return "" }
+func (t *TestAggregator) Reset() {
+	t.sum = 0
+}
+
+func (t *TestAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("TestMetric",
+		map[string]interface{}{"sum": t.sum},
+		map[string]string{},
+	)
+}
+
+func (t *TestAggregator) Add(in telegraf.Metric) {
+	for _, v := range in.Fields() {
+		if vi, ok := v.(int64); ok {
+			t.sum += vi
+		}
+	}
+}
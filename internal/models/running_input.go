@@ -1,6 +1,9 @@
 package internal_models
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -10,15 +13,116 @@ type RunningInput struct {
 	Name   string
 	Input  telegraf.Input
 	Config *InputConfig
+
+	// Fingerprint is a canonical form of the input's configuration, used to
+	// find out whether it changed when the config is reloaded.
+	Fingerprint string
+
+	// gathering is 1 between StartGather and EndGather.
+	gathering int32
+
+	// when the input last gathered and reported an error, for the status
+	// API
+	mu            sync.Mutex
+	lastGather    time.Time
+	lastError     string
+	lastErrorTime time.Time
 }
 
 // InputConfig containing a name, interval, and filter
 type InputConfig struct {
 	Name              string
+	Alias             string
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+	// GatherTimeout overrides the agent's gather_timeout for this input.
+	GatherTimeout time.Duration
+	// Precision overrides the agent's precision for this input.
+	Precision time.Duration
+	// Overflow overrides the agent's metric_overflow for this input.
+	Overflow OverflowPolicy
+}
+
+// OverflowPolicy is what an input does with a metric when the channel between
+// the inputs and the outputs is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDefault is the agent's policy.
+	OverflowDefault OverflowPolicy = iota
+	// OverflowBlock waits until there is room in the channel.
+	OverflowBlock
+	// OverflowDropNewest drops the metric being added.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest metric in the channel to make room.
+	OverflowDropOldest
+)
+
+// ParseOverflowPolicy parses a metric_overflow setting: "block",
+// "drop_newest" or "drop_oldest". An empty string is the default.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "":
+		return OverflowDefault, nil
+	case "block":
+		return OverflowBlock, nil
+	case "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	}
+	return OverflowDefault, fmt.Errorf("invalid metric_overflow %q, "+
+		"expected \"block\", \"drop_newest\" or \"drop_oldest\"", s)
+}
+
+// StartGather marks the input as gathering. It returns false if the previous
+// Gather hasn't returned yet, in which case no other Gather must be started.
+func (r *RunningInput) StartGather() bool {
+	return atomic.CompareAndSwapInt32(&r.gathering, 0, 1)
+}
+
+// EndGather marks the Gather started after StartGather as returned.
+func (r *RunningInput) EndGather() {
+	atomic.StoreInt32(&r.gathering, 0)
+}
+
+// RecordGather records that a Gather of the input finished at t.
+func (r *RunningInput) RecordGather(t time.Time) {
+	r.mu.Lock()
+	r.lastGather = t
+	r.mu.Unlock()
+}
+
+// RecordError records an error the input reported at t.
+func (r *RunningInput) RecordError(err error, t time.Time) {
+	r.mu.Lock()
+	r.lastError = err.Error()
+	r.lastErrorTime = t
+	r.mu.Unlock()
+}
+
+// InputStatus is the state of an input, as reported by the status API.
+type InputStatus struct {
+	Name          string
+	Alias         string
+	LastGather    time.Time
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// Status returns the current state of the input.
+func (r *RunningInput) Status() InputStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return InputStatus{
+		Name:          r.Name,
+		Alias:         r.Config.Alias,
+		LastGather:    r.lastGather,
+		LastError:     r.lastError,
+		LastErrorTime: r.lastErrorTime,
+	}
 }
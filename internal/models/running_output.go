@@ -1,11 +1,13 @@
 package internal_models
 
 import (
-	"log"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 const (
@@ -16,6 +18,13 @@ const (
 	FULL_METRIC_BUFFERS_LIMIT = 100
 )
 
+var (
+	// GlobalMetricsWritten counts the metrics written by all outputs.
+	GlobalMetricsWritten = selfstat.Register("agent", "metrics_written", map[string]string{})
+	// GlobalMetricsDropped counts the metrics dropped by all outputs.
+	GlobalMetricsDropped = selfstat.Register("agent", "metrics_dropped", map[string]string{})
+)
+
 type RunningOutput struct {
 	Name                string
 	Output              telegraf.Output
@@ -24,11 +33,57 @@ type RunningOutput struct {
 	MetricBufferLimit   int
 	FlushBufferWhenFull bool
 
+	// Fingerprint is a canonical form of the output's configuration, used to
+	// find out whether it changed when the config is reloaded.
+	Fingerprint string
+
 	metrics    []telegraf.Metric
 	tmpmetrics map[int][]telegraf.Metric
 	overwriteI int
 	mapI       int
 
+	// buffer replaces metrics and tmpmetrics when the output is configured
+	// with a buffer_dir. unsynced are the metrics added to it that are not
+	// synced to disk yet, which are accepted once they are.
+	buffer   *DiskBuffer
+	unsynced []telegraf.Metric
+
+	// consecutive failed writes, and when the next write may be tried
+	failures int
+	retryAt  time.Time
+	breaker  *circuitBreaker
+
+	// when the output started failing to connect or write, zero while it
+	// works, and the last error it returned
+	failingSince  time.Time
+	lastError     string
+	lastErrorTime time.Time
+
+	// log prefixes the output's messages with its name
+	log telegraf.Logger
+
+	// writing is true while the output is being written to, outside of the
+	// lock, writeDone is signalled when that is done.
+	writing   bool
+	writeDone *sync.Cond
+
+	// closed is set by Close, after which no write is started, so that a
+	// flush abandoned at shutdown stops after the write under way.
+	closed bool
+
+	// connecting is true while the output is being connected in the
+	// background, done stops that.
+	connecting bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+
+	MetricsWritten selfstat.Stat
+	MetricsDropped selfstat.Stat
+	BufferSize     selfstat.Stat
+	BufferLimit    selfstat.Stat
+	FailedBuffers  selfstat.Stat
+	WriteTime      selfstat.Stat
+
 	sync.Mutex
 }
 
@@ -37,6 +92,7 @@ func NewRunningOutput(
 	output telegraf.Output,
 	conf *OutputConfig,
 ) *RunningOutput {
+	tags := map[string]string{"output": name}
 	ro := &RunningOutput{
 		Name:              name,
 		metrics:           make([]telegraf.Metric, 0),
@@ -44,113 +100,668 @@ func NewRunningOutput(
 		Output:            output,
 		Config:            conf,
 		MetricBufferLimit: DEFAULT_METRIC_BUFFER_LIMIT,
+		log:               logger.New("outputs", name, conf.Alias),
+		breaker: &circuitBreaker{
+			threshold: conf.Retry.CircuitBreakerThreshold,
+			timeout:   conf.Retry.CircuitBreakerTimeout,
+		},
+		MetricsWritten: selfstat.Register(
+			"write",
+			"metrics_written",
+			tags,
+		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			tags,
+		),
+		BufferSize: selfstat.Register(
+			"write",
+			"buffer_size",
+			tags,
+		),
+		BufferLimit: selfstat.Register(
+			"write",
+			"buffer_limit",
+			tags,
+		),
+		FailedBuffers: selfstat.Register(
+			"write",
+			"failed_buffers",
+			tags,
+		),
+		WriteTime: selfstat.RegisterTiming(
+			"write",
+			"write_time_ns",
+			tags,
+		),
 	}
 	return ro
 }
 
 // AddMetric adds a metric to the output. This function can also write cached
-// points if FlushBufferWhenFull is true.
+// points if FlushBufferWhenFull is true. The output holds the metric until it
+// is written, or dropped, and then calls its Accept, Reject or Drop.
 func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 	if ro.Config.Filter.IsActive {
 		if !ro.Config.Filter.ShouldMetricPass(metric) {
+			metric.Drop()
 			return
 		}
 	}
 	ro.Lock()
-	defer ro.Unlock()
+	flush := ro.add(metric)
+	ro.updateBufferStats()
+	ro.Unlock()
 
-	// Filter any tagexclude/taginclude parameters before adding metric
+	if flush {
+		if err := ro.flush(false); err != nil {
+			ro.log.Errorf("Writing full metric buffer: %s", err)
+		}
+	}
+}
+
+// add adds the metric to the output's buffer. It returns true if the buffer
+// is full and must be written out. ro must be locked.
+func (ro *RunningOutput) add(metric telegraf.Metric) bool {
+	// Filter any fieldpass/fielddrop and tagexclude/taginclude parameters
+	// before adding metric. The metric is shared with the other outputs, so
+	// the fields and tags are removed from a copy, which is only made if
+	// there are any to remove.
+	copied := false
+	if len(ro.Config.Filter.FieldDrop) != 0 || len(ro.Config.Filter.FieldPass) != 0 {
+		var drop []string
+		for k := range metric.Fields() {
+			if !ro.Config.Filter.ShouldFieldsPass(k) {
+				drop = append(drop, k)
+			}
+		}
+		if len(drop) == len(metric.Fields()) {
+			metric.Drop()
+			return false
+		}
+		if len(drop) > 0 {
+			metric = metric.Copy()
+			copied = true
+			for _, k := range drop {
+				metric.RemoveField(k)
+			}
+		}
+	}
 	if len(ro.Config.Filter.TagExclude) != 0 || len(ro.Config.Filter.TagInclude) != 0 {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
-		tags := metric.Tags()
-		fields := metric.Fields()
-		t := metric.Time()
-		name := metric.Name()
-		ro.Config.Filter.FilterTags(tags)
-		// error is not possible if creating from another metric, so ignore.
-		metric, _ = telegraf.NewMetric(name, tags, fields, t)
+		var drop []string
+		for k := range metric.Tags() {
+			if !ro.Config.Filter.ShouldTagKeyPass(k) {
+				drop = append(drop, k)
+			}
+		}
+		if len(drop) > 0 {
+			if !copied {
+				metric = metric.Copy()
+			}
+			for _, k := range drop {
+				metric.RemoveTag(k)
+			}
+		}
+	}
+
+	if ro.buffer != nil {
+		return ro.addToBuffer(metric)
 	}
 
 	if len(ro.metrics) < ro.MetricBufferLimit {
 		ro.metrics = append(ro.metrics, metric)
 	} else {
 		if ro.FlushBufferWhenFull {
+			// the full buffer is kept with those that failed to be written,
+			// which are all written once ro is unlocked
 			ro.metrics = append(ro.metrics, metric)
-			tmpmetrics := make([]telegraf.Metric, len(ro.metrics))
-			copy(tmpmetrics, ro.metrics)
+			ro.keepBuffer(ro.metrics)
 			ro.metrics = make([]telegraf.Metric, 0)
-			err := ro.write(tmpmetrics)
-			if err != nil {
-				log.Printf("ERROR writing full metric buffer to output %s, %s",
-					ro.Name, err)
-				if len(ro.tmpmetrics) == FULL_METRIC_BUFFERS_LIMIT {
-					ro.mapI = 0
-					// overwrite one
-					ro.tmpmetrics[ro.mapI] = tmpmetrics
-					ro.mapI++
-				} else {
-					ro.tmpmetrics[ro.mapI] = tmpmetrics
-					ro.mapI++
-				}
-			}
+			return true
 		} else {
 			if ro.overwriteI == 0 {
-				log.Printf("WARNING: overwriting cached metrics, you may want to " +
+				ro.log.Warnf("Overwriting cached metrics, you may want to " +
 					"increase the metric_buffer_limit setting in your [agent] " +
-					"config if you do not wish to overwrite metrics.\n")
+					"config if you do not wish to overwrite metrics.")
 			}
 			if ro.overwriteI == len(ro.metrics) {
 				ro.overwriteI = 0
 			}
+			ro.reject(ro.metrics[ro.overwriteI : ro.overwriteI+1])
 			ro.metrics[ro.overwriteI] = metric
 			ro.overwriteI++
 		}
 	}
+	return false
+}
+
+// keepBuffer keeps a full metric buffer, or what is left of one after a
+// failed write, to be written on the next flush. ro must be locked.
+func (ro *RunningOutput) keepBuffer(tmpmetrics []telegraf.Metric) {
+	if len(tmpmetrics) == 0 {
+		return
+	}
+	if len(ro.tmpmetrics) == FULL_METRIC_BUFFERS_LIMIT {
+		ro.mapI = 0
+		// overwrite one
+		ro.reject(ro.tmpmetrics[ro.mapI])
+		ro.tmpmetrics[ro.mapI] = tmpmetrics
+		ro.mapI++
+	} else {
+		ro.tmpmetrics[ro.mapI] = tmpmetrics
+		ro.mapI++
+	}
 }
 
-// Write writes all cached points to this output.
+// Write writes all cached points to this output. If the output is already
+// being written to, it waits for that to finish first.
 func (ro *RunningOutput) Write() error {
+	return ro.flush(true)
+}
+
+// flush writes all cached points to this output. The output is not locked
+// while it is written to, so that metrics can be added meanwhile, but only one
+// flush writes to it at a time. If another flush is in progress, flush waits
+// for it if wait is true, or else leaves the points for the next flush.
+func (ro *RunningOutput) flush(wait bool) error {
 	ro.Lock()
-	defer ro.Unlock()
-	err := ro.write(ro.metrics)
+	if !ro.startWrite(wait) {
+		ro.Unlock()
+		return nil
+	}
+	buffered := ro.buffer != nil
+	metrics := ro.metrics
+	ro.metrics = make([]telegraf.Metric, 0)
+	ro.overwriteI = 0
+	ro.Unlock()
+
+	defer func() {
+		ro.Lock()
+		ro.endWrite()
+		ro.updateBufferStats()
+		ro.Unlock()
+	}()
+	if buffered {
+		return ro.writeBuffer()
+	}
+
+	n, err := ro.writeBatches(metrics)
+	if n < len(metrics) {
+		ro.Lock()
+		ro.requeue(metrics[n:])
+		ro.Unlock()
+	}
 	if err != nil {
 		return err
-	} else {
-		ro.metrics = make([]telegraf.Metric, 0)
-		ro.overwriteI = 0
 	}
 
 	// Write any cached metric buffers that failed previously
-	for i, tmpmetrics := range ro.tmpmetrics {
-		if err := ro.write(tmpmetrics); err != nil {
-			return err
+	ro.Lock()
+	failed := ro.tmpmetrics
+	ro.tmpmetrics = make(map[int][]telegraf.Metric)
+	ro.mapI = 0
+	ro.Unlock()
+	for i, tmpmetrics := range failed {
+		n, err = ro.writeBatches(tmpmetrics)
+		if n == len(tmpmetrics) {
+			delete(failed, i)
 		} else {
-			delete(ro.tmpmetrics, i)
+			failed[i] = tmpmetrics[n:]
+		}
+		if err != nil {
+			break
 		}
 	}
+	ro.Lock()
+	for _, tmpmetrics := range failed {
+		ro.keepBuffer(tmpmetrics)
+	}
+	ro.Unlock()
 
-	return nil
+	return err
 }
 
+// startWrite marks the output as being written to. If it already is, it waits
+// for that to finish, unless wait is false, in which case it returns false.
+// ro must be locked.
+func (ro *RunningOutput) startWrite(wait bool) bool {
+	if ro.writeDone == nil {
+		ro.writeDone = sync.NewCond(&ro.Mutex)
+	}
+	for ro.writing {
+		if !wait {
+			return false
+		}
+		ro.writeDone.Wait()
+	}
+	ro.writing = true
+	return true
+}
+
+// endWrite marks the output as no longer being written to. ro must be locked.
+func (ro *RunningOutput) endWrite() {
+	ro.writing = false
+	ro.writeDone.Broadcast()
+}
+
+// requeue puts metrics that were not written back at the front of the buffer,
+// before those added while they were being written. If that fills the buffer
+// past its limit, the oldest metrics are dropped. ro must be locked.
+func (ro *RunningOutput) requeue(metrics []telegraf.Metric) {
+	all := make([]telegraf.Metric, 0, len(metrics)+len(ro.metrics))
+	all = append(append(all, metrics...), ro.metrics...)
+	if excess := len(all) - ro.MetricBufferLimit; excess > 0 {
+		ro.reject(all[:excess])
+		all = all[excess:]
+	}
+	ro.metrics = all
+}
+
+// writeBatches writes the metrics in batches of at most the output's
+// metric_batch_size, stopping at the first batch that fails. It returns the
+// number of metrics that are done with, which includes a failed batch that
+// used up its retries and was dropped. ro must not be locked.
+func (ro *RunningOutput) writeBatches(metrics []telegraf.Metric) (int, error) {
+	n := 0
+	for n < len(metrics) {
+		end := len(metrics)
+		if size := ro.Config.MetricBatchSize; size > 0 && n+size < end {
+			end = n + size
+		}
+		if err := ro.write(metrics[n:end]); err != nil {
+			ro.Lock()
+			giveUp := ro.giveUp(end - n)
+			ro.Unlock()
+			if giveUp {
+				ro.reject(metrics[n:end])
+				n = end
+			}
+			return n, err
+		}
+		for _, m := range metrics[n:end] {
+			m.Accept()
+		}
+		n = end
+	}
+	return n, nil
+}
+
+// write writes the metrics to the output, unless the output is still
+// connecting, backing off after a failed write, or its circuit breaker is
+// open. ro must not be locked, it is only locked before and after the write.
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
 	start := time.Now()
+	if err := ro.checkWrite(start); err != nil {
+		return err
+	}
+
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
-	if err == nil {
+	ro.WriteTime.Incr(elapsed.Nanoseconds())
+
+	ro.Lock()
+	defer ro.Unlock()
+	if err != nil {
+		ro.failed(err, start)
+		ro.failures++
+		ro.retryAt = start.Add(ro.Config.Retry.backoff(ro.failures))
+		wasOpen := ro.breaker.isOpen()
+		ro.breaker.failure(start)
+		if !wasOpen && ro.breaker.isOpen() {
+			ro.log.Warnf("Circuit breaker opened after %d failed writes",
+				ro.breaker.failures)
+		}
+	} else {
+		ro.failures = 0
+		ro.retryAt = time.Time{}
+		ro.failingSince = time.Time{}
+		ro.breaker.success()
+		ro.MetricsWritten.Incr(int64(len(metrics)))
+		GlobalMetricsWritten.Incr(int64(len(metrics)))
 		if !ro.Quiet {
-			log.Printf("Wrote %d metrics to output %s in %s\n",
-				len(metrics), ro.Name, elapsed)
+			ro.log.Infof("Wrote %d metrics in %s", len(metrics), elapsed)
 		}
 	}
 	return err
 }
 
-// OutputConfig containing name and filter
+// checkWrite returns an error if the output can't be written to at t.
+func (ro *RunningOutput) checkWrite(t time.Time) error {
+	ro.Lock()
+	defer ro.Unlock()
+	if ro.closed {
+		return fmt.Errorf("output is closed")
+	}
+	if ro.connecting {
+		return fmt.Errorf("output is not connected yet")
+	}
+	if t.Before(ro.retryAt) {
+		return fmt.Errorf("backing off for %s after %d failed writes",
+			ro.retryAt.Sub(t), ro.failures)
+	}
+	if !ro.breaker.allow(t) {
+		return fmt.Errorf("circuit breaker is open until %s",
+			ro.breaker.openUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// giveUp reports whether the batch of n metrics that failed to be written has
+// used up its retries, in which case the caller must drop it. ro must be
+// locked.
+func (ro *RunningOutput) giveUp(n int) bool {
+	maxRetries := ro.Config.Retry.MaxRetries
+	if maxRetries <= 0 || ro.failures <= maxRetries {
+		return false
+	}
+	ro.log.Errorf("Dropping %d metrics after %d retries", n, maxRetries)
+	ro.failures = 0
+	return true
+}
+
+// Connect connects the output. If that fails, the output keeps trying to
+// connect in the background, backing off between attempts, and its metrics
+// are buffered until it succeeds.
+func (ro *RunningOutput) Connect() {
+	err := ro.Output.Connect()
+	if err == nil {
+		return
+	}
+	ro.log.Errorf("Failed to connect, retrying in the background, "+
+		"error was '%s'", err)
+
+	ro.Lock()
+	ro.connecting = true
+	ro.failed(err, time.Now())
+	ro.Unlock()
+	ro.done = make(chan struct{})
+	ro.wg.Add(1)
+	go ro.reconnect()
+}
+
+func (ro *RunningOutput) reconnect() {
+	defer ro.wg.Done()
+	for attempt := 1; ; attempt++ {
+		d := ro.Config.Retry.backoff(attempt)
+		if d < minReconnectInterval {
+			d = minReconnectInterval
+		}
+		select {
+		case <-ro.done:
+			return
+		case <-time.After(d):
+		}
+
+		err := ro.Output.Connect()
+		if err == nil {
+			ro.Lock()
+			ro.connecting = false
+			ro.failingSince = time.Time{}
+			ro.Unlock()
+			ro.log.Infof("Successfully connected")
+			return
+		}
+		ro.log.Errorf("Failed to connect (attempt %d), error was '%s'",
+			attempt, err)
+		ro.Lock()
+		ro.failed(err, time.Now())
+		ro.Unlock()
+	}
+}
+
+// OpenBuffer opens the output's on-disk buffer if it is configured with a
+// buffer_dir. Metrics left in the buffer by a previous run are kept and
+// written before any new ones.
+func (ro *RunningOutput) OpenBuffer() error {
+	ro.Lock()
+	defer ro.Unlock()
+	if ro.Config.BufferDir == "" || ro.buffer != nil {
+		return nil
+	}
+
+	buffer, err := NewDiskBuffer(ro.Config.BufferDir, ro.Config.BufferMaxBytes)
+	if err != nil {
+		return err
+	}
+	ro.buffer = buffer
+	if n := buffer.Len(); n > 0 {
+		ro.log.Infof("Found %d unwritten metrics in buffer", n)
+	}
+	ro.updateBufferStats()
+	return nil
+}
+
+// Close stops connecting the output in the background and closes its on-disk
+// buffer, if any. Unwritten metrics stay on disk, those in memory are lost. It
+// does not close the output itself. If the output is being written to, ie, by
+// a final flush that took longer than the shutdown timeout, the flush stops
+// after the write under way, and Close waits for that write to return, so
+// that neither the output nor its buffer are closed underneath it.
+func (ro *RunningOutput) Close() error {
+	ro.Lock()
+	ro.closed = true
+	ro.Unlock()
+
+	if ro.done != nil {
+		close(ro.done)
+		ro.wg.Wait()
+		ro.done = nil
+	}
+
+	ro.Lock()
+	defer ro.Unlock()
+	for ro.writing {
+		ro.writeDone.Wait()
+	}
+	for _, m := range ro.metrics {
+		m.Reject()
+	}
+	ro.metrics = ro.metrics[:0]
+	for i, tmpmetrics := range ro.tmpmetrics {
+		for _, m := range tmpmetrics {
+			m.Reject()
+		}
+		delete(ro.tmpmetrics, i)
+	}
+	if ro.buffer == nil {
+		return nil
+	}
+	err := ro.syncBuffer()
+	if closeErr := ro.buffer.Close(); err == nil {
+		err = closeErr
+	}
+	ro.buffer = nil
+	return err
+}
+
+// addToBuffer appends the metric to the on-disk buffer. It returns true if
+// the buffer is full and FlushBufferWhenFull is set, so it must be written
+// out. The buffer outlives the agent, so the metric is accepted once it is
+// synced to disk, on the next flush. ro must be locked.
+func (ro *RunningOutput) addToBuffer(metric telegraf.Metric) bool {
+	dropped, err := ro.buffer.Add(metric)
+	if err != nil {
+		ro.log.Errorf("Adding metric to buffer: %s", err)
+		if dropped == 0 {
+			metric.Reject()
+			ro.dropped(1)
+			return false
+		}
+	}
+	ro.unsynced = append(ro.unsynced, metric)
+	if dropped > 0 {
+		ro.log.Warnf("Dropped %d metrics from buffer, you may want to "+
+			"increase the buffer_max_bytes setting of the output if you do "+
+			"not wish to drop metrics.", dropped)
+		ro.dropped(dropped)
+	}
+
+	return ro.FlushBufferWhenFull && ro.buffer.Len() >= ro.MetricBufferLimit
+}
+
+// writeBuffer writes the on-disk buffer out oldest first, in batches of the
+// output's metric_batch_size, or of MetricBufferLimit if it is not set. A
+// batch is removed from the buffer only after the output accepted it, so a
+// failed or interrupted write is retried on the next flush.
+// ro must not be locked.
+func (ro *RunningOutput) writeBuffer() error {
+	ro.Lock()
+	err := ro.syncBuffer()
+	ro.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		ro.Lock()
+		if ro.buffer == nil || ro.buffer.Len() == 0 {
+			ro.Unlock()
+			return nil
+		}
+		size := ro.Config.MetricBatchSize
+		if size <= 0 {
+			size = ro.MetricBufferLimit
+		}
+		batch, err := ro.buffer.Batch(size)
+		ro.Unlock()
+		if err != nil {
+			return err
+		}
+
+		err = ro.write(batch)
+		ro.Lock()
+		if err != nil && ro.giveUp(len(batch)) {
+			ro.reject(batch)
+			if ackErr := ro.ackBuffer(); ackErr != nil {
+				err = ackErr
+			}
+		} else if err == nil {
+			err = ro.ackBuffer()
+		}
+		ro.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// syncBuffer syncs the on-disk buffer, and accepts the metrics added to it
+// since the last sync, or rejects them if that fails. ro must be locked.
+func (ro *RunningOutput) syncBuffer() error {
+	if ro.buffer == nil || len(ro.unsynced) == 0 {
+		return nil
+	}
+	err := ro.buffer.Sync()
+	for _, m := range ro.unsynced {
+		if err != nil {
+			m.Reject()
+		} else {
+			m.Accept()
+		}
+	}
+	ro.unsynced = nil
+	return err
+}
+
+// ackBuffer removes the last batch from the on-disk buffer, if it is still
+// open. ro must be locked.
+func (ro *RunningOutput) ackBuffer() error {
+	if ro.buffer == nil {
+		return nil
+	}
+	return ro.buffer.Ack()
+}
+
+// failed records that the output failed to connect or write at t. ro must
+// be locked.
+func (ro *RunningOutput) failed(err error, t time.Time) {
+	if ro.failingSince.IsZero() {
+		ro.failingSince = t
+	}
+	ro.lastError = err.Error()
+	ro.lastErrorTime = t
+}
+
+// OutputStatus is the state of an output, as reported by the status API.
+type OutputStatus struct {
+	Name        string
+	Alias       string
+	BufferSize  int
+	BufferLimit int
+	// FailingSince is when the output started failing to connect or write,
+	// zero while it works.
+	FailingSince  time.Time
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// Status returns the current state of the output.
+func (ro *RunningOutput) Status() OutputStatus {
+	ro.Lock()
+	defer ro.Unlock()
+	s := OutputStatus{
+		Name:          ro.Name,
+		Alias:         ro.Config.Alias,
+		BufferLimit:   ro.MetricBufferLimit,
+		FailingSince:  ro.failingSince,
+		LastError:     ro.lastError,
+		LastErrorTime: ro.lastErrorTime,
+	}
+	if ro.buffer != nil {
+		s.BufferSize = ro.buffer.Len()
+	} else {
+		s.BufferSize = len(ro.metrics)
+	}
+	return s
+}
+
+// reject rejects metrics that were lost before they could be written.
+func (ro *RunningOutput) reject(metrics []telegraf.Metric) {
+	for _, m := range metrics {
+		m.Reject()
+	}
+	ro.dropped(len(metrics))
+}
+
+// dropped counts n metrics that were lost before they could be written.
+func (ro *RunningOutput) dropped(n int) {
+	ro.MetricsDropped.Incr(int64(n))
+	GlobalMetricsDropped.Incr(int64(n))
+}
+
+// updateBufferStats records the current state of the metric buffers.
+// ro must be locked.
+func (ro *RunningOutput) updateBufferStats() {
+	if ro.buffer != nil {
+		ro.BufferSize.Set(int64(ro.buffer.Len()))
+	} else {
+		ro.BufferSize.Set(int64(len(ro.metrics)))
+	}
+	ro.BufferLimit.Set(int64(ro.MetricBufferLimit))
+	ro.FailedBuffers.Set(int64(len(ro.tmpmetrics)))
+}
+
+// OutputConfig containing name, filter, flush, retry policy and on-disk
+// buffer settings
 type OutputConfig struct {
 	Name   string
+	Alias  string
 	Filter Filter
+	Retry  RetryConfig
+
+	// FlushInterval and FlushJitter, if set, replace the agent's for this
+	// output.
+	FlushInterval time.Duration
+	FlushJitter   time.Duration
+	// MetricBatchSize, if set, is the most metrics sent in one write.
+	MetricBatchSize int
+
+	// BufferDir, if set, is the directory of the output's on-disk buffer.
+	BufferDir string
+	// BufferMaxBytes is the maximum size of the on-disk buffer.
+	BufferMaxBytes int64
 }
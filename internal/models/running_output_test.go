@@ -2,9 +2,12 @@ package internal_models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
@@ -126,6 +129,142 @@ func TestRunningOutput_TagExcludeMatch(t *testing.T) {
 }
 
 // Test that tags are properly Excluded
+// Test that the type of a metric is kept when its tags are filtered
+func TestRunningOutput_TagExcludeKeepsType(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive:   true,
+			TagExclude: []string{"tag*"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	counter, err := telegraf.NewCounterMetric("metric1",
+		map[string]string{"tag1": "value1"},
+		map[string]interface{}{"value": 101},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	ro.AddMetric(counter)
+
+	err = ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	assert.Len(t, m.Metrics()[0].Tags(), 0)
+	assert.Equal(t, telegraf.Counter, m.Metrics()[0].Type())
+}
+
+// Test that the tags are removed from a copy of the metric, as metrics are
+// shared between outputs
+func TestRunningOutput_TagExcludeCopiesMetric(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive:   true,
+			TagExclude: []string{"tag*"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	metric, err := telegraf.NewMetric("metric1",
+		map[string]string{"tag1": "value1", "host": "localhost"},
+		map[string]interface{}{"value": 101},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	ro.AddMetric(metric)
+
+	err = ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Metrics()[0].Tags())
+	assert.Equal(t, map[string]string{"tag1": "value1", "host": "localhost"},
+		metric.Tags())
+}
+
+func TestRunningOutput_FieldPass(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			FieldPass: []string{"usage_*"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	metric, err := telegraf.NewMetric("cpu",
+		map[string]string{"tag1": "value1"},
+		map[string]interface{}{"usage_idle": 90.0, "time_idle": 1000.0},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	ro.AddMetric(metric)
+	// metrics without any of the fields are dropped
+	ro.AddMetric(testutil.TestMetric(101, "metric1"))
+
+	err = ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	assert.Equal(t, map[string]interface{}{"usage_idle": 90.0},
+		m.Metrics()[0].Fields())
+	// the metric is shared with the other outputs
+	assert.Len(t, metric.Fields(), 2)
+}
+
+func TestRunningOutput_FieldDropAndTagExclude(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			FieldDrop:  []string{"time_*"},
+			TagExclude: []string{"tag1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	metric, err := telegraf.NewMetric("cpu",
+		map[string]string{"tag1": "value1", "host": "localhost"},
+		map[string]interface{}{"usage_idle": 90.0, "time_idle": 1000.0},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	ro.AddMetric(metric)
+
+	err = ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	assert.Equal(t, map[string]interface{}{"usage_idle": 90.0},
+		m.Metrics()[0].Fields())
+	assert.Equal(t, map[string]string{"host": "localhost"},
+		m.Metrics()[0].Tags())
+	assert.Len(t, metric.Fields(), 2)
+	assert.Len(t, metric.Tags(), 2)
+}
+
+func TestRunningOutput_ValuePass(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive:  true,
+			ValuePass: []string{"value > 100"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	ro.AddMetric(testutil.TestMetric(101, "metric1"))
+	ro.AddMetric(testutil.TestMetric(99, "metric2"))
+
+	err := ro.Write()
+	assert.NoError(t, err)
+	assert.Len(t, m.Metrics(), 1)
+	assert.Equal(t, "metric1", m.Metrics()[0].Name())
+}
+
 func TestRunningOutput_TagExcludeNoMatch(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{
@@ -356,6 +495,494 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+// Test that the status of the output tracks since when it is failing.
+func TestRunningOutputStatus(t *testing.T) {
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{Alias: "primary"})
+	ro.MetricBufferLimit = 10
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	s := ro.Status()
+	assert.Equal(t, "test", s.Name)
+	assert.Equal(t, "primary", s.Alias)
+	assert.Equal(t, 5, s.BufferSize)
+	assert.Equal(t, 10, s.BufferLimit)
+	assert.True(t, s.FailingSince.IsZero())
+
+	require.Error(t, ro.Write())
+	s = ro.Status()
+	assert.False(t, s.FailingSince.IsZero())
+	assert.Equal(t, "Failed Write!", s.LastError)
+	assert.Equal(t, 5, s.BufferSize)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	s = ro.Status()
+	assert.True(t, s.FailingSince.IsZero())
+	assert.Equal(t, "Failed Write!", s.LastError)
+	assert.Equal(t, 0, s.BufferSize)
+}
+
+// Test that the output's self-monitoring stats track written, dropped and
+// buffered metrics.
+func TestRunningOutputStats(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test_stats", m, conf)
+	ro.MetricBufferLimit = 3
+	// the counters are global, and kept between runs of the test
+	dropped := ro.MetricsDropped.Get()
+	written := ro.MetricsWritten.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	assert.Equal(t, int64(2), ro.MetricsDropped.Get()-dropped)
+	assert.Equal(t, int64(3), ro.BufferSize.Get())
+	assert.Equal(t, int64(3), ro.BufferLimit.Get())
+
+	require.NoError(t, ro.Write())
+	assert.Equal(t, int64(3), ro.MetricsWritten.Get()-written)
+	assert.Equal(t, int64(0), ro.BufferSize.Get())
+	assert.Equal(t, int64(0), ro.FailedBuffers.Get())
+}
+
+// Test that metrics kept in the on-disk buffer survive a failed write and
+// reopening the output, and are written once.
+func TestRunningOutputDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		BufferDir: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf)
+	ro.MetricBufferLimit = 4
+	require.NoError(t, ro.OpenBuffer())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.Close())
+
+	// a new output, as after a restart, finds the buffered metrics
+	m.failWrite = false
+	ro = NewRunningOutput("test", m, conf)
+	ro.MetricBufferLimit = 4
+	require.NoError(t, ro.OpenBuffer())
+	defer ro.Close()
+
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 10)
+	for i, exp := range append(first5, next5...) {
+		assert.Equal(t, exp.String(), m.Metrics()[i].String())
+	}
+
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 10)
+}
+
+// Test that metric_batch_size splits the buffer into bounded writes, and that
+// the metrics of a failed batch are kept in order.
+func TestRunningOutputMetricBatchSize(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		MetricBatchSize: 2,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 3, m.Writes())
+	assert.Len(t, m.Metrics(), 5)
+
+	m.failWrite = true
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, 4, m.Writes())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 7, m.Writes())
+	require.Len(t, m.Metrics(), 10)
+	for i, exp := range next5 {
+		assert.Equal(t, exp.String(), m.Metrics()[5+i].String())
+	}
+}
+
+// Test that a failed write isn't retried until the backoff has passed.
+func TestRunningOutputBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		Retry: RetryConfig{
+			InitialBackoff: time.Hour,
+			MaxBackoff:     time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, 1, m.Writes())
+
+	m.failWrite = false
+	require.Error(t, ro.Write())
+	assert.Equal(t, 1, m.Writes())
+
+	ro.retryAt = time.Now()
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 2, m.Writes())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// Test that the circuit breaker stops writes once open, and lets a single
+// write through after its timeout.
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		Retry: RetryConfig{
+			CircuitBreakerThreshold: 2,
+			CircuitBreakerTimeout:   time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+	assert.Equal(t, 2, m.Writes())
+
+	// open
+	require.Error(t, ro.Write())
+	assert.Equal(t, 2, m.Writes())
+
+	// half-open, and the write fails again
+	ro.breaker.openUntil = time.Now()
+	require.Error(t, ro.Write())
+	assert.Equal(t, 3, m.Writes())
+	require.Error(t, ro.Write())
+	assert.Equal(t, 3, m.Writes())
+
+	// half-open, and the write succeeds
+	ro.breaker.openUntil = time.Now()
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 4, m.Writes())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// Test that a batch is dropped once it has used up its retries.
+func TestRunningOutputMaxRetries(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		Retry: RetryConfig{
+			MaxRetries: 1,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test_max_retries", m, conf)
+	// the counters are global, and kept between runs of the test
+	dropped := ro.MetricsDropped.Get()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(0), ro.MetricsDropped.Get()-dropped)
+
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(5), ro.MetricsDropped.Get()-dropped)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+}
+
+// Test that an output that can't connect keeps connecting in the background,
+// and doesn't write until it is connected.
+func TestRunningOutputConnectInBackground(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failConnect = 1
+	ro := NewRunningOutput("test", m, conf)
+	ro.Connect()
+	defer ro.Close()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, 0, m.Writes())
+
+	for i := 0; i < 50 && ro.Write() != nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, 1, m.Writes())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// trackedMetrics returns the metrics of group tracked as one group, and a
+// function returning its delivery once it is done with.
+func trackedMetrics(group []telegraf.Metric) ([]telegraf.Metric, func() telegraf.DeliveryInfo) {
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	metrics, _ := telegraf.NewTrackingMetricGroup(group,
+		func(info telegraf.DeliveryInfo) { delivered <- info })
+	return metrics, func() telegraf.DeliveryInfo {
+		select {
+		case info := <-delivered:
+			return info
+		default:
+			return nil
+		}
+	}
+}
+
+// Test that tracked metrics are delivered once written, and filtered metrics
+// count as delivered.
+func TestRunningOutputTrackingAccept(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: true,
+			NameDrop: []string{"metric1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.CompileFilter())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+
+	metrics, delivery := trackedMetrics(first5[:3])
+	for _, metric := range metrics {
+		ro.AddMetric(metric)
+	}
+	assert.Nil(t, delivery())
+
+	require.NoError(t, ro.Write())
+	info := delivery()
+	require.NotNil(t, info)
+	assert.True(t, info.Delivered())
+}
+
+// Test that tracked metrics added to an on-disk buffer are delivered once the
+// buffer is synced to disk, even if they are not written yet.
+func TestRunningOutputTrackingDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{BufferDir: dir})
+	require.NoError(t, ro.OpenBuffer())
+	defer ro.Close()
+
+	metrics, delivery := trackedMetrics(first5[:3])
+	for _, metric := range metrics {
+		ro.AddMetric(metric)
+	}
+	assert.Nil(t, delivery())
+
+	require.Error(t, ro.Write())
+	info := delivery()
+	require.NotNil(t, info)
+	assert.True(t, info.Delivered())
+	assert.Empty(t, m.Metrics())
+}
+
+// Test that tracked metrics overwritten in a full buffer are not delivered.
+func TestRunningOutputTrackingOverwrite(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf)
+	ro.MetricBufferLimit = 4
+
+	metrics, delivery := trackedMetrics(first5[:1])
+	ro.AddMetric(metrics[0])
+	for _, metric := range next5[:4] {
+		ro.AddMetric(metric)
+	}
+
+	info := delivery()
+	require.NotNil(t, info)
+	assert.False(t, info.Delivered())
+}
+
+// Test that tracked metrics dropped after the max retries are not delivered.
+func TestRunningOutputTrackingMaxRetries(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			IsActive: false,
+		},
+		Retry: RetryConfig{
+			MaxRetries: 1,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test_tracking_max_retries", m, conf)
+
+	metrics, delivery := trackedMetrics(first5)
+	for _, metric := range metrics {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Nil(t, delivery())
+
+	require.Error(t, ro.Write())
+	info := delivery()
+	require.NotNil(t, info)
+	assert.False(t, info.Delivered())
+}
+
+// Test that metrics can be added to an output while it is being written to.
+func TestRunningOutputSlowWrite(t *testing.T) {
+	m := &blockingOutput{started: make(chan bool), release: make(chan bool)}
+	ro := NewRunningOutput("test", m, &OutputConfig{})
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	errC := make(chan error)
+	go func() {
+		errC <- ro.Write()
+	}()
+	<-m.started
+
+	// the write is blocked, adding metrics must not be
+	added := make(chan bool)
+	go func() {
+		for _, metric := range next5 {
+			ro.AddMetric(metric)
+		}
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AddMetric blocked by a write in progress")
+	}
+	assert.Equal(t, 5, ro.Status().BufferSize)
+
+	close(m.release)
+	require.NoError(t, <-errC)
+	require.NoError(t, ro.Write())
+	assert.Equal(t, append(metricStrings(first5), metricStrings(next5)...),
+		metricStrings(m.metrics))
+}
+
+// Test that closing an output that is being written to, as after a shutdown
+// timeout, waits for the write under way, and that no other write is started.
+func TestRunningOutputCloseDuringWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m := &blockingOutput{started: make(chan bool), release: make(chan bool)}
+	conf := &OutputConfig{BufferDir: dir, MetricBatchSize: 2}
+	ro := NewRunningOutput("test", m, conf)
+	require.NoError(t, ro.OpenBuffer())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	errC := make(chan error)
+	go func() {
+		errC <- ro.Write()
+	}()
+	<-m.started
+
+	closed := make(chan error)
+	go func() {
+		closed <- ro.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("output closed during a write")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(m.release)
+	require.NoError(t, <-closed)
+	require.Error(t, <-errC)
+	assert.Equal(t, metricStrings(first5[:2]), metricStrings(m.Metrics()))
+
+	// the metrics that were not written are left in the buffer
+	buffer, err := NewDiskBuffer(dir, 0)
+	require.NoError(t, err)
+	defer buffer.Close()
+	assert.Equal(t, 3, buffer.Len())
+}
+
+// blockingOutput is an output whose writes block until it is released.
+type blockingOutput struct {
+	mockOutput
+	started chan bool
+	release chan bool
+	once    sync.Once
+}
+
+func (m *blockingOutput) Write(metrics []telegraf.Metric) error {
+	m.once.Do(func() {
+		close(m.started)
+		<-m.release
+	})
+	return m.mockOutput.Write(metrics)
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -363,9 +990,20 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+	// number of calls to Write
+	writes int
+
+	// number of connection attempts to fail
+	failConnect int
 }
 
 func (m *mockOutput) Connect() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.failConnect > 0 {
+		m.failConnect--
+		return fmt.Errorf("Failed Connect!")
+	}
 	return nil
 }
 
@@ -384,6 +1022,7 @@ func (m *mockOutput) SampleConfig() string {
 func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	m.Lock()
 	defer m.Unlock()
+	m.writes++
 	if m.failWrite {
 		return fmt.Errorf("Failed Write!")
 	}
@@ -403,3 +1042,9 @@ func (m *mockOutput) Metrics() []telegraf.Metric {
 	defer m.Unlock()
 	return m.metrics
 }
+
+func (m *mockOutput) Writes() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.writes
+}
@@ -0,0 +1,62 @@
+package internal_models
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+type RunningProcessor struct {
+	Name string
+
+	sync.Mutex
+	Processor telegraf.Processor
+	Config    *ProcessorConfig
+}
+
+type RunningProcessors []*RunningProcessor
+
+func (rp RunningProcessors) Len() int      { return len(rp) }
+func (rp RunningProcessors) Swap(i, j int) { rp[i], rp[j] = rp[j], rp[i] }
+func (rp RunningProcessors) Less(i, j int) bool {
+	oi, oj := rp[i].Config.Order, rp[j].Config.Order
+	// processors without an order run after the ordered ones
+	if oi == 0 || oj == 0 {
+		return oj == 0 && oi != 0
+	}
+	return oi < oj
+}
+
+// ProcessorConfig containing a name, order, and filter. An Order of 0 means
+// the processor has no order set.
+type ProcessorConfig struct {
+	Name   string
+	Alias  string
+	Order  int64
+	Filter Filter
+}
+
+// Apply runs the processor on every metric that passes the processor's
+// filter. Metrics that do not pass the filter are returned untouched.
+func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	rp.Lock()
+	defer rp.Unlock()
+
+	ret := []telegraf.Metric{}
+
+	for _, metric := range in {
+		if rp.Config.Filter.IsActive {
+			// check if the filter should be applied to this metric
+			if !rp.Config.Filter.ShouldMetricPass(metric) {
+				// this means filter should not be applied
+				ret = append(ret, metric)
+				continue
+			}
+		}
+		// This metric should pass through the filter, so call the processor's
+		// Apply function and append the results to the output slice.
+		ret = append(ret, rp.Processor.Apply(metric)...)
+	}
+
+	return ret
+}
@@ -0,0 +1,149 @@
+package internal_models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValueFilter is a condition on the value of a tag or field of a metric, ie,
+// `usage_idle < 90` or `state == "idle"`. The name is looked up in the tags of
+// the metric, then in its fields.
+type ValueFilter struct {
+	Name string
+	Op   string
+
+	// the value compared to, a float64, bool or string
+	value interface{}
+}
+
+var valueFilterRe = regexp.MustCompile(`^\s*([^\s=!<>]+)\s*(==|!=|<=|>=|<|>)\s*(.*?)\s*$`)
+
+// CompileValueFilter parses a condition like `usage_idle < 90`. Numbers can be
+// compared with any of ==, !=, <, <=, > and >=, strings, which are quoted, and
+// booleans only with == and !=.
+func CompileValueFilter(s string) (*ValueFilter, error) {
+	m := valueFilterRe.FindStringSubmatch(s)
+	if m == nil || m[3] == "" {
+		return nil, fmt.Errorf("invalid condition %q, expected a condition "+
+			"like 'usage_idle < 90'", s)
+	}
+	f := &ValueFilter{Name: m[1], Op: m[2]}
+
+	literal := m[3]
+	switch {
+	case len(literal) >= 2 && (literal[0] == '"' || literal[0] == '\'') &&
+		literal[len(literal)-1] == literal[0]:
+		f.value = literal[1 : len(literal)-1]
+	case literal == "true" || literal == "false":
+		f.value = literal == "true"
+	default:
+		v, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q, %s is not a number, "+
+				"a quoted string or a boolean", s, literal)
+		}
+		f.value = v
+	}
+
+	if _, ok := f.value.(float64); !ok && f.Op != "==" && f.Op != "!=" {
+		return nil, fmt.Errorf("invalid condition %q, strings and booleans "+
+			"can only be compared with == and !=", s)
+	}
+	return f, nil
+}
+
+// Match returns true if the metric with the given tags and fields meets the
+// condition. It is false if the metric has no tag or field with the name, or if
+// its value can't be compared, ie, a string to a number.
+func (f *ValueFilter) Match(tags map[string]string, fields map[string]interface{}) bool {
+	var actual interface{}
+	if v, ok := tags[f.Name]; ok {
+		actual = v
+	} else if v, ok := fields[f.Name]; ok {
+		actual = v
+	} else {
+		return false
+	}
+
+	switch want := f.value.(type) {
+	case float64:
+		v, ok := floatValue(actual)
+		if !ok {
+			return false
+		}
+		return compareFloat(v, f.Op, want)
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		return (a == want) == (f.Op == "==")
+	case bool:
+		a, ok := actual.(bool)
+		if !ok {
+			return false
+		}
+		return (a == want) == (f.Op == "==")
+	}
+	return false
+}
+
+// floatValue returns the value as a float64, if it is a number. Inputs add
+// fields of any numeric kind, which are matched before the metric normalizes
+// them.
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		// tags are strings, but may hold numbers
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compareFloat(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
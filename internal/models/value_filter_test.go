@@ -0,0 +1,86 @@
+package internal_models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueFilter_Match(t *testing.T) {
+	tags := map[string]string{"state": "idle", "cpu": "3"}
+	fields := map[string]interface{}{
+		"usage_idle": float64(85.5),
+		"count":      int64(10),
+		"bytes":      uint64(18446744073709551615),
+		"status":     "ok",
+		"up":         true,
+	}
+
+	tests := []struct {
+		condition string
+		match     bool
+	}{
+		{"usage_idle < 90", true},
+		{"usage_idle >= 90", false},
+		{"usage_idle<=85.5", true},
+		{"count == 10", true},
+		{"count != 10", false},
+		{"count > 9", true},
+		{"bytes > 1e19", true},
+		{`state == "idle"`, true},
+		{`state != 'idle'`, false},
+		{"cpu >= 3", true},
+		{`status == "ok"`, true},
+		{"up == true", true},
+		{"up != true", false},
+		// a missing tag or field never matches
+		{"missing < 1", false},
+		{`missing != "x"`, false},
+		// neither do values of another type
+		{"status > 1", false},
+		{`count == "10"`, false},
+	}
+	for _, test := range tests {
+		vf, err := CompileValueFilter(test.condition)
+		require.NoError(t, err, test.condition)
+		assert.Equal(t, test.match, vf.Match(tags, fields), test.condition)
+	}
+}
+
+// Inputs add fields of any numeric kind, which the filter sees before they are
+// normalized.
+func TestValueFilter_MatchNumericKinds(t *testing.T) {
+	values := []interface{}{
+		int(42), int8(42), int16(42), int32(42), int64(42),
+		uint(42), uint8(42), uint16(42), uint32(42), uint64(42),
+		float32(42), float64(42), []byte("42"), "42",
+	}
+
+	vf, err := CompileValueFilter("value == 42")
+	require.NoError(t, err)
+	above, err := CompileValueFilter("value > 41.5")
+	require.NoError(t, err)
+	below, err := CompileValueFilter("value < 41.5")
+	require.NoError(t, err)
+	for _, v := range values {
+		fields := map[string]interface{}{"value": v}
+		assert.True(t, vf.Match(nil, fields), "%T", v)
+		assert.True(t, above.Match(nil, fields), "%T", v)
+		assert.False(t, below.Match(nil, fields), "%T", v)
+	}
+}
+
+func TestValueFilter_CompileError(t *testing.T) {
+	for _, condition := range []string{
+		"usage_idle",
+		"usage_idle <",
+		"< 90",
+		"usage_idle < ninety",
+		`state < "idle"`,
+		"up > false",
+	} {
+		_, err := CompileValueFilter(condition)
+		assert.Error(t, err, condition)
+	}
+}
@@ -0,0 +1,21 @@
+package telegraf
+
+// Logger logs the messages of a plugin at a level. The messages are prefixed
+// with the plugin's name and alias, and only written if the agent's log level
+// includes their level.
+type Logger interface {
+	// Errorf logs an error, which the plugin couldn't recover from
+	Errorf(format string, args ...interface{})
+	// Warnf logs a warning, ie, data that was dropped
+	Warnf(format string, args ...interface{})
+	// Infof logs an informational message, ie, a connection being opened
+	Infof(format string, args ...interface{})
+	// Debugf logs a message only useful when debugging
+	Debugf(format string, args ...interface{})
+}
+
+// LoggingPlugin is implemented by the plugins that log through a Logger. The
+// agent sets it when the plugin is loaded, before it is started.
+type LoggingPlugin interface {
+	SetLogger(Logger)
+}
@@ -0,0 +1,234 @@
+// logger routes the messages logged with the standard log package to stderr or
+// a log file, filtered by level and written as text or JSON lines.
+//
+// A message's level is given by a prefix: "E! " for errors, "W! " for
+// warnings, "I! " for informational messages and "D! " for debug messages.
+// Messages without one are informational, unless they start with ERROR or
+// WARNING, as in older code. A "[name] " following the prefix is the plugin
+// that logged the message, which the plugin loggers returned by New add.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Level is the severity of a message. A level includes the ones before it.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+var levelNames = []string{"error", "warn", "info", "debug"}
+
+// levelPrefixes are the prefixes giving the level of a message.
+var levelPrefixes = []string{"E! ", "W! ", "I! ", "D! "}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// prefix returns the prefix giving the level of a message.
+func (l Level) prefix() string {
+	return levelPrefixes[l]
+}
+
+// Config is where the messages are written, and how.
+type Config struct {
+	// Level is the most detailed level written
+	Level Level
+	// Logfile is the file the messages are written to, stderr if empty
+	Logfile string
+	// RotationMaxSize is the size a log file is rotated at, 0 for never
+	RotationMaxSize int64
+	// RotationMaxArchives is the number of rotated log files kept
+	RotationMaxArchives int
+	// Format is "text", the default, or "json" for a JSON object per line
+	Format string
+}
+
+var (
+	mu      sync.Mutex
+	current *writer
+)
+
+// Setup makes the standard logger write the messages as configured. The log
+// file of a previous Setup is closed.
+func Setup(c Config) error {
+	if err := CheckFormat(c.Format); err != nil {
+		return err
+	}
+
+	w := &writer{
+		level: c.Level,
+		json:  c.Format == "json",
+		out:   os.Stderr,
+	}
+	if c.Logfile != "" {
+		f, err := openRotatingFile(c.Logfile, c.RotationMaxSize,
+			c.RotationMaxArchives)
+		if err != nil {
+			return fmt.Errorf("could not open log file %s: %s", c.Logfile, err)
+		}
+		w.out = f
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the standard logger doesn't write to the previous writer any more once
+	// SetOutput returns
+	log.SetFlags(0)
+	log.SetOutput(w)
+	if current != nil {
+		current.close()
+	}
+	current = w
+	return nil
+}
+
+// CheckFormat returns an error if the log format isn't "text" or "json". An
+// empty format is "text".
+func CheckFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		return nil
+	}
+	return fmt.Errorf("invalid log_format %q, expected \"text\" or \"json\"",
+		format)
+}
+
+// writer writes the messages of the standard logger, which calls Write once per
+// message.
+type writer struct {
+	level Level
+	json  bool
+	out   io.Writer
+}
+
+// entry is a message written as JSON.
+type entry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Plugin  string `json:"plugin,omitempty"`
+	Message string `json:"msg"`
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	level, plugin, msg := parse(strings.TrimSuffix(string(b), "\n"))
+	if level > w.level {
+		return len(b), nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var line []byte
+	if w.json {
+		var err error
+		line, err = json.Marshal(entry{
+			Time:    now,
+			Level:   level.String(),
+			Plugin:  plugin,
+			Message: msg,
+		})
+		if err != nil {
+			return 0, err
+		}
+		line = append(line, '\n')
+	} else {
+		if plugin != "" {
+			msg = "[" + plugin + "] " + msg
+		}
+		line = []byte(now + " " + level.prefix() + msg + "\n")
+	}
+
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *writer) close() {
+	if c, ok := w.out.(io.Closer); ok && w.out != os.Stderr {
+		c.Close()
+	}
+}
+
+// parse returns the level of a message, the plugin that logged it if any, and
+// the message without them.
+func parse(line string) (Level, string, string) {
+	level := LevelInfo
+	prefixed := false
+	for l, prefix := range levelPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			level = Level(l)
+			line = line[len(prefix):]
+			prefixed = true
+			break
+		}
+	}
+	if !prefixed {
+		switch {
+		case strings.HasPrefix(line, "ERROR"), strings.HasPrefix(line, "Error"),
+			strings.HasPrefix(line, "FATAL"):
+			level = LevelError
+		case strings.HasPrefix(line, "WARN"), strings.HasPrefix(line, "Warning"):
+			level = LevelWarn
+		}
+		return level, "", line
+	}
+
+	var plugin string
+	if strings.HasPrefix(line, "[") {
+		if i := strings.Index(line, "] "); i > 0 {
+			plugin = line[1:i]
+			line = line[i+2:]
+		}
+	}
+	return level, plugin, line
+}
+
+// New returns the logger of a plugin, ie, New("inputs", "cpu", ""). Its
+// messages are prefixed with "[inputs.cpu]", or "[inputs.cpu::alias]" if the
+// plugin has an alias.
+func New(kind, name, alias string) telegraf.Logger {
+	context := kind + "." + name
+	if alias != "" {
+		context += "::" + alias
+	}
+	return &pluginLogger{prefix: "[" + context + "] "}
+}
+
+type pluginLogger struct {
+	prefix string
+}
+
+func (l *pluginLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+func (l *pluginLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+func (l *pluginLogger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+func (l *pluginLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+func (l *pluginLogger) logf(level Level, format string, args ...interface{}) {
+	log.Print(level.prefix() + l.prefix + fmt.Sprintf(format, args...))
+}
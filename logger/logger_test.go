@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line    string
+		level   Level
+		plugin  string
+		message string
+	}{
+		{"E! [inputs.cpu] failed", LevelError, "inputs.cpu", "failed"},
+		{"W! [outputs.file::stdout] slow", LevelWarn, "outputs.file::stdout", "slow"},
+		{"I! Starting Telegraf", LevelInfo, "", "Starting Telegraf"},
+		{"D! [inputs.exec] ran", LevelDebug, "inputs.exec", "ran"},
+		{"ERROR: legacy error", LevelError, "", "ERROR: legacy error"},
+		{"WARNING: legacy warning", LevelWarn, "", "WARNING: legacy warning"},
+		{"no level [x] here", LevelInfo, "", "no level [x] here"},
+	}
+	for _, test := range tests {
+		level, plugin, message := parse(test.line)
+		assert.Equal(t, test.level, level, test.line)
+		assert.Equal(t, test.plugin, plugin, test.line)
+		assert.Equal(t, test.message, message, test.line)
+	}
+}
+
+func TestWriterFiltersLevel(t *testing.T) {
+	var buf bytes.Buffer
+	w := &writer{level: LevelWarn, out: &buf}
+
+	for _, line := range []string{"E! error\n", "W! warning\n", "I! info\n", "D! debug\n"} {
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], " E! error"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], " W! warning"), lines[1])
+}
+
+func TestWriterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := &writer{level: LevelInfo, json: true, out: &buf}
+
+	_, err := w.Write([]byte("E! [inputs.cpu::local] failed: \"x\"\n"))
+	require.NoError(t, err)
+
+	var e entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "error", e.Level)
+	assert.Equal(t, "inputs.cpu::local", e.Plugin)
+	assert.Equal(t, `failed: "x"`, e.Message)
+	assert.NotEmpty(t, e.Time)
+}
+
+func TestPluginLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.log")
+
+	require.NoError(t, Setup(Config{Level: LevelInfo, Logfile: path}))
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	l := New("inputs", "cpu", "local")
+	l.Errorf("failed %d times", 2)
+	l.Debugf("not written")
+	require.NoError(t, Setup(Config{Level: LevelInfo}))
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(contents),
+		" E! [inputs.cpu::local] failed 2 times\n"), string(contents))
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	for file, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		contents, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(contents), file)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+)
+
+// rotatingFile is a log file which, once it would grow past maxSize bytes, is
+// renamed to <path>.1, the previous <path>.1 to <path>.2, and so on, keeping
+// maxArchives of them, and started again.
+type rotatingFile struct {
+	path        string
+	maxSize     int64
+	maxArchives int
+
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxArchives int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:        path,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+	}
+	if err := r.open(os.O_APPEND); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open(flag int) error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(b)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate archives the log file and starts a new one.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.maxArchives > 0 {
+		os.Remove(r.archive(r.maxArchives))
+		for i := r.maxArchives - 1; i > 0; i-- {
+			err := os.Rename(r.archive(i), r.archive(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(r.path, r.archive(1)); err != nil {
+			return err
+		}
+	}
+	return r.open(os.O_TRUNC)
+}
+
+// archive returns the path of the nth most recent rotated log file.
+func (r *rotatingFile) archive(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
@@ -1,11 +1,58 @@
 package telegraf
 
 import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/client/v2"
 )
 
+// ValueType is the kind of value a metric holds, which outputs that know about
+// metric types, like prometheus_client, use to describe it.
+type ValueType int
+
+const (
+	_ ValueType = iota
+	// Counter is a cumulative value that only goes up, or resets to zero.
+	Counter
+	// Gauge is a value that can go up and down.
+	Gauge
+	// Untyped is a value of unknown kind, the type of metrics made with
+	// NewMetric.
+	Untyped
+	// Summary is a set of quantiles of observations, with their count and
+	// sum.
+	Summary
+	// Histogram is a set of cumulative bucket counts of observations, with
+	// their count and sum.
+	Histogram
+)
+
+// String returns the lowercase name of the type, ie, "counter".
+func (t ValueType) String() string {
+	switch t {
+	case Counter:
+		return "counter"
+	case Gauge:
+		return "gauge"
+	case Summary:
+		return "summary"
+	case Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// Metric is a measurement with its tags, fields and timestamp. A metric can be
+// shared, ie, between outputs, so the maps returned by Tags and Fields must not
+// be modified, and the methods that modify a metric should only be called on a
+// metric that isn't shared, like one returned by Copy.
 type Metric interface {
 	// Name returns the measurement name of the metric
 	Name() string
@@ -22,6 +69,9 @@ type Metric interface {
 	// Fields returns the fields for the metric
 	Fields() map[string]interface{}
 
+	// Type returns the type of the metric's value
+	Type() ValueType
+
 	// String returns a line-protocol string of the metric
 	String() string
 
@@ -30,65 +80,398 @@ type Metric interface {
 
 	// Point returns a influxdb client.Point object
 	Point() *client.Point
+
+	// SetName sets the measurement name of the metric
+	SetName(name string)
+
+	// AddTag sets a tag of the metric, replacing its value if it exists. Tags
+	// with an empty key or value are not added.
+	AddTag(key, value string)
+
+	// RemoveTag removes a tag of the metric, if it exists
+	RemoveTag(key string)
+
+	// AddField sets a field of the metric, replacing its value if it exists.
+	// Values of unsupported types and float values that are NaN or infinite
+	// are not added.
+	AddField(key string, value interface{})
+
+	// RemoveField removes a field of the metric, if it exists
+	RemoveField(key string)
+
+	// Copy returns a copy of the metric, which can be modified without
+	// changing the original. The copy of a tracked metric is tracked as the
+	// same metric, it can stand in for it.
+	Copy() Metric
+
+	// Accept, Reject and Drop tell the input that added a tracked metric,
+	// see TrackingAccumulator, what became of it. Whoever holds a tracked
+	// metric, ie, an output, calls one of them once done with it: Accept once
+	// it is written, Reject if it is lost, and Drop if it is filtered out.
+	// They do nothing on metrics that aren't tracked.
+	Accept()
+	Reject()
+	Drop()
 }
 
-// metric is a wrapper of the influxdb client.Point struct
 type metric struct {
-	pt *client.Point
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	t      time.Time
+	mType  ValueType
 }
 
 // NewMetric returns a metric with the given timestamp. If a timestamp is not
 // given, then data is sent to the database without a timestamp, in which case
 // the server will assign local time upon reception. NOTE: it is recommended to
 // send data with a timestamp.
+//
+// The tags and fields maps are copied, the caller keeps them. Tags with an
+// empty key or value are dropped, integer fields are stored as int64, uint and
+// uint64 fields as uint64, and float fields as float64.
 func NewMetric(
 	name string,
 	tags map[string]string,
 	fields map[string]interface{},
 	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(name, tags, fields, Untyped, t...)
+}
+
+// NewCounterMetric returns a metric of type Counter, see NewMetric.
+func NewCounterMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(name, tags, fields, Counter, t...)
+}
+
+// NewGaugeMetric returns a metric of type Gauge, see NewMetric.
+func NewGaugeMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(name, tags, fields, Gauge, t...)
+}
+
+// NewSummaryMetric returns a metric of type Summary, see NewMetric. Its fields
+// are the quantiles, named by their value (ie, "0.99"), and "count" and "sum".
+func NewSummaryMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(name, tags, fields, Summary, t...)
+}
+
+// NewHistogramMetric returns a metric of type Histogram, see NewMetric. Its
+// fields are the cumulative bucket counts, named by their upper bound (ie,
+// "0.5" or "+Inf"), and "count" and "sum".
+func NewHistogramMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(name, tags, fields, Histogram, t...)
+}
+
+// NewTypedMetric returns a metric of the given type, see NewMetric. It is
+// useful to copy a metric, keeping its type.
+func NewTypedMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	mType ValueType,
+	t ...time.Time,
+) (Metric, error) {
+	// the maps are copied, as they are owned by the caller
+	mFields := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		mFields[k] = v
+	}
+	mTags := make(map[string]string, len(tags))
+	for k, v := range tags {
+		mTags[k] = v
+	}
+	return NewOwnedMetric(name, mTags, mFields, mType, t...)
+}
+
+// NewOwnedMetric is NewTypedMetric for callers building fresh maps for every
+// metric, like the agent's accumulator: the metric takes over the tags and
+// fields maps rather than copying them, and the caller must not use them
+// afterwards.
+func NewOwnedMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	mType ValueType,
+	t ...time.Time,
 ) (Metric, error) {
 	var T time.Time
 	if len(t) > 0 {
 		T = t[0]
 	}
 
-	pt, err := client.NewPoint(name, tags, fields, T)
-	if err != nil {
-		return nil, err
+	for k, v := range fields {
+		if v == nil {
+			delete(fields, k)
+			continue
+		}
+		value, err := fieldValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", k, err)
+		}
+		fields[k] = value
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("metric without fields is unsupported")
+	}
+	for k, v := range tags {
+		if k == "" || v == "" {
+			delete(tags, k)
+		}
 	}
+
 	return &metric{
-		pt: pt,
+		name:   name,
+		tags:   tags,
+		fields: fields,
+		t:      T,
+		mType:  mType,
 	}, nil
 }
 
 func (m *metric) Name() string {
-	return m.pt.Name()
+	return m.name
 }
 
 func (m *metric) Tags() map[string]string {
-	return m.pt.Tags()
+	return m.tags
 }
 
 func (m *metric) Time() time.Time {
-	return m.pt.Time()
+	return m.t
 }
 
 func (m *metric) UnixNano() int64 {
-	return m.pt.UnixNano()
+	return m.t.UnixNano()
 }
 
 func (m *metric) Fields() map[string]interface{} {
-	return m.pt.Fields()
+	return m.fields
+}
+
+func (m *metric) Type() ValueType {
+	return m.mType
 }
 
 func (m *metric) String() string {
-	return m.pt.String()
+	return m.PrecisionString("")
 }
 
 func (m *metric) PrecisionString(precison string) string {
-	return m.pt.PrecisionString(precison)
+	var buf bytes.Buffer
+	buf.WriteString(nameEscaper.Replace(m.name))
+
+	keys := make([]string, 0, len(m.tags))
+	for k := range m.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteByte(',')
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(keyEscaper.Replace(m.tags[k]))
+	}
+
+	keys = keys[:0]
+	for k := range m.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		switch v := m.fields[k].(type) {
+		case int64:
+			buf.WriteString(strconv.FormatInt(v, 10))
+			buf.WriteByte('i')
+		case uint64:
+			buf.WriteString(strconv.FormatUint(v, 10))
+			buf.WriteByte('u')
+		case float64:
+			buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			buf.WriteString(strconv.FormatBool(v))
+		case string:
+			buf.WriteByte('"')
+			buf.WriteString(stringFieldEscaper.Replace(v))
+			buf.WriteByte('"')
+		}
+	}
+
+	if !m.t.IsZero() {
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.UnixNano()/precisionMultiplier(precison), 10))
+	}
+	return buf.String()
 }
 
+// Point builds the client.Point of the metric, only outputs writing to
+// InfluxDB should need it. The client can't write unsigned integers, so
+// uint64 fields are written as int64, capped at MaxInt64.
 func (m *metric) Point() *client.Point {
-	return m.pt
+	fields := m.fields
+	for _, v := range m.fields {
+		if _, ok := v.(uint64); ok {
+			fields = intFields(m.fields)
+			break
+		}
+	}
+	// error is not possible, fields were checked when creating the metric
+	pt, _ := client.NewPoint(m.name, m.tags, fields, m.t)
+	return pt
+}
+
+// intFields returns a copy of fields with the uint64 values converted to
+// int64, capped at MaxInt64.
+func intFields(fields map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if u, ok := v.(uint64); ok {
+			if u > math.MaxInt64 {
+				u = math.MaxInt64
+			}
+			v = int64(u)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (m *metric) SetName(name string) {
+	m.name = name
+}
+
+func (m *metric) AddTag(key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	m.tags[key] = value
+}
+
+func (m *metric) RemoveTag(key string) {
+	delete(m.tags, key)
+}
+
+func (m *metric) AddField(key string, value interface{}) {
+	value, err := fieldValue(value)
+	if err != nil {
+		return
+	}
+	m.fields[key] = value
+}
+
+func (m *metric) RemoveField(key string) {
+	delete(m.fields, key)
+}
+
+func (m *metric) Copy() Metric {
+	c := *m
+	c.tags = make(map[string]string, len(m.tags))
+	for k, v := range m.tags {
+		c.tags[k] = v
+	}
+	c.fields = make(map[string]interface{}, len(m.fields))
+	for k, v := range m.fields {
+		c.fields[k] = v
+	}
+	return &c
+}
+
+func (m *metric) Accept() {}
+func (m *metric) Reject() {}
+func (m *metric) Drop()   {}
+
+var (
+	nameEscaper        = strings.NewReplacer(",", "\\,", " ", "\\ ")
+	keyEscaper         = strings.NewReplacer(",", "\\,", " ", "\\ ", "=", "\\=")
+	stringFieldEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+)
+
+// fieldValue returns the value of a field as one of the types a metric stores:
+// int64, uint64, float64, bool or string.
+func fieldValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64, bool, string:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v is an unsupported value", v)
+		}
+		return v, nil
+	case float32:
+		return fieldValue(float64(v))
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return uint64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return v, nil
+	case []byte:
+		// raw values, as parsers find them, are numbers when they can be
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return fieldValue(f)
+		}
+		return string(v), nil
+	case nil:
+		return nil, fmt.Errorf("nil is an unsupported value")
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// precisionMultiplier returns the number of nanoseconds in a unit of the
+// given precision, "n", "u", "ms", "s", "m" or "h". Nanoseconds are the
+// default.
+func precisionMultiplier(precision string) int64 {
+	switch precision {
+	case "u":
+		return int64(time.Microsecond)
+	case "ms":
+		return int64(time.Millisecond)
+	case "s":
+		return int64(time.Second)
+	case "m":
+		return int64(time.Minute)
+	case "h":
+		return int64(time.Hour)
+	default:
+		return 1
+	}
 }
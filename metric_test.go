@@ -28,6 +28,33 @@ func TestNewMetric(t *testing.T) {
 	assert.Equal(t, "cpu", m.Name())
 	assert.Equal(t, now, m.Time())
 	assert.Equal(t, now.UnixNano(), m.UnixNano())
+	assert.Equal(t, Untyped, m.Type())
+}
+
+func TestNewTypedMetrics(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{"host": "localhost"}
+	fields := map[string]interface{}{"value": float64(1)}
+
+	constructors := map[ValueType]func(string, map[string]string,
+		map[string]interface{}, ...time.Time) (Metric, error){
+		Counter:   NewCounterMetric,
+		Gauge:     NewGaugeMetric,
+		Summary:   NewSummaryMetric,
+		Histogram: NewHistogramMetric,
+	}
+	for mType, newMetric := range constructors {
+		m, err := newMetric("cpu", tags, fields, now)
+		assert.NoError(t, err)
+		assert.Equal(t, mType, m.Type())
+		assert.Equal(t, "cpu", m.Name())
+		assert.Equal(t, fields, m.Fields())
+	}
+
+	m, err := NewTypedMetric("cpu", tags, fields, Gauge, now)
+	assert.NoError(t, err)
+	assert.Equal(t, Gauge, m.Type())
+	assert.Equal(t, "gauge", m.Type().String())
 }
 
 func TestNewMetricString(t *testing.T) {
@@ -81,3 +108,170 @@ func TestNewMetricFailNaN(t *testing.T) {
 	_, err := NewMetric("cpu", tags, fields, now)
 	assert.Error(t, err)
 }
+
+func TestNewMetricFieldTypes(t *testing.T) {
+	fields := map[string]interface{}{
+		"int":     int(1),
+		"int32":   int32(2),
+		"uint16":  uint16(6),
+		"uint":    uint(7),
+		"uint64":  uint64(3),
+		"float32": float32(4.5),
+		"bytes":   []byte("5.5"),
+		"string":  "foo",
+		"bool":    true,
+		"nil":     nil,
+	}
+	m, err := NewMetric("test", nil, fields)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"int":     int64(1),
+		"int32":   int64(2),
+		"uint16":  int64(6),
+		"uint":    uint64(7),
+		"uint64":  uint64(3),
+		"float32": float64(4.5),
+		"bytes":   float64(5.5),
+		"string":  "foo",
+		"bool":    true,
+	}, m.Fields())
+
+	_, err = NewMetric("test", nil, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+// Test that the maps passed to NewMetric are left as they are.
+func TestNewMetricCopiesMaps(t *testing.T) {
+	tags := map[string]string{"host": "localhost", "empty": ""}
+	fields := map[string]interface{}{"int": int(1), "nil": nil}
+	m, err := NewMetric("test", tags, fields)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"host": "localhost", "empty": ""}, tags)
+	assert.Equal(t, map[string]interface{}{"int": int(1), "nil": nil}, fields)
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"int": int64(1)}, m.Fields())
+
+	tags["host"] = "changed"
+	fields["int"] = 2
+	assert.Equal(t, "localhost", m.Tags()["host"])
+	assert.Equal(t, int64(1), m.Fields()["int"])
+}
+
+// Test that NewOwnedMetric keeps the maps passed to it.
+func TestNewOwnedMetric(t *testing.T) {
+	tags := map[string]string{"host": "localhost", "empty": ""}
+	fields := map[string]interface{}{"int": int(1), "nil": nil}
+	m, err := NewOwnedMetric("test", tags, fields, Gauge)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"int": int64(1)}, m.Fields())
+	assert.Equal(t, Gauge, m.Type())
+
+	tags["host"] = "changed"
+	fields["int"] = int64(2)
+	assert.Equal(t, "changed", m.Tags()["host"])
+	assert.Equal(t, int64(2), m.Fields()["int"])
+}
+
+func TestMetricEscaping(t *testing.T) {
+	now := time.Now()
+
+	tags := map[string]string{
+		"host name": "local,host",
+		"a=b":       "c",
+		"empty":     "",
+	}
+	fields := map[string]interface{}{
+		"usage idle": float64(99),
+		"string":     `say "hi" \o/`,
+		"count":      int64(5),
+		"ok":         true,
+	}
+	m, err := NewMetric("cpu usage,total", tags, fields, now)
+	assert.NoError(t, err)
+
+	lineProto := fmt.Sprintf(`cpu\ usage\,total,a\=b=c,host\ name=local\,host `+
+		`count=5i,ok=true,string="say \"hi\" \\o/",usage\ idle=99 %d`,
+		now.UnixNano())
+	assert.Equal(t, lineProto, m.String())
+}
+
+func TestMetricUint64(t *testing.T) {
+	now := time.Now()
+
+	fields := map[string]interface{}{
+		"bytes_recv": uint64(math.MaxUint64),
+		"packets":    uint64(3),
+	}
+	m, err := NewMetric("net", nil, fields, now)
+	assert.NoError(t, err)
+
+	lineProto := fmt.Sprintf("net bytes_recv=18446744073709551615u,packets=3u %d",
+		now.UnixNano())
+	assert.Equal(t, lineProto, m.String())
+
+	// the InfluxDB client gets the values as int64, capped at MaxInt64
+	assert.Equal(t,
+		fmt.Sprintf("net bytes_recv=9223372036854775807i,packets=3i %d",
+			now.UnixNano()),
+		m.Point().String())
+	assert.Equal(t, uint64(math.MaxUint64), m.Fields()["bytes_recv"])
+}
+
+func TestMetricModify(t *testing.T) {
+	now := time.Now()
+
+	tags := map[string]string{"host": "localhost"}
+	fields := map[string]interface{}{"usage_idle": float64(99)}
+	m, err := NewGaugeMetric("cpu", tags, fields, now)
+	assert.NoError(t, err)
+
+	c := m.Copy()
+	c.SetName("cpu2")
+	c.AddTag("cpu", "cpu0")
+	c.RemoveTag("host")
+	c.AddField("usage_busy", 1)
+	c.AddField("usage_nan", math.NaN())
+	c.RemoveField("usage_idle")
+
+	assert.Equal(t, "cpu2", c.Name())
+	assert.Equal(t, map[string]string{"cpu": "cpu0"}, c.Tags())
+	assert.Equal(t, map[string]interface{}{"usage_busy": int64(1)}, c.Fields())
+	assert.Equal(t, Gauge, c.Type())
+	assert.Equal(t, now, c.Time())
+	assert.Equal(t, fmt.Sprintf("cpu2,cpu=cpu0 usage_busy=1i %d", now.UnixNano()),
+		c.String())
+
+	// the original is unchanged
+	assert.Equal(t, "cpu", m.Name())
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"usage_idle": float64(99)}, m.Fields())
+}
+
+func BenchmarkNewMetric(b *testing.B) {
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		tags := map[string]string{
+			"host":       "localhost",
+			"datacenter": "us-east-1",
+		}
+		fields := map[string]interface{}{
+			"usage_idle": float64(99),
+			"usage_busy": float64(1),
+		}
+		NewMetric("cpu", tags, fields, now)
+	}
+}
+
+func BenchmarkMetricString(b *testing.B) {
+	m, _ := NewMetric("cpu",
+		map[string]string{"host": "localhost", "datacenter": "us-east-1"},
+		map[string]interface{}{"usage_idle": float64(99), "usage_busy": float64(1)},
+		time.Now())
+	for i := 0; i < b.N; i++ {
+		_ = m.String()
+	}
+}
@@ -0,0 +1,128 @@
+package basicstats
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/stats"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type BasicStats struct {
+	cache map[uint64]aggregate
+}
+
+// aggregate holds the running statistics of every numeric field of a single
+// series (measurement name + tag set).
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*stats.RunningStats
+}
+
+func NewBasicStats() *BasicStats {
+	bs := &BasicStats{}
+	bs.Reset()
+	return bs
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+`
+
+func (b *BasicStats) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BasicStats) Description() string {
+	return "Keep the aggregate min/max/mean/count/stddev of each metric passing through."
+}
+
+func (b *BasicStats) Add(in telegraf.Metric) {
+	id := hashID(in)
+	agg, ok := b.cache[id]
+	if !ok {
+		agg = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*stats.RunningStats),
+		}
+		b.cache[id] = agg
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		rs, ok := agg.fields[k]
+		if !ok {
+			rs = &stats.RunningStats{}
+			agg.fields[k] = rs
+		}
+		rs.AddValue(fv)
+	}
+}
+
+func (b *BasicStats) Push(acc telegraf.Accumulator) {
+	for _, agg := range b.cache {
+		fields := make(map[string]interface{})
+		for k, rs := range agg.fields {
+			fields[k+"_min"] = rs.Lower()
+			fields[k+"_max"] = rs.Upper()
+			fields[k+"_mean"] = rs.Mean()
+			fields[k+"_count"] = rs.Count()
+			fields[k+"_stddev"] = rs.Stddev()
+		}
+		acc.AddFields(agg.name, fields, agg.tags)
+	}
+}
+
+func (b *BasicStats) Reset() {
+	b.cache = make(map[uint64]aggregate)
+}
+
+// hashID returns a hash of the metric's name and (sorted) tag set, uniquely
+// identifying the series it belongs to.
+func hashID(m telegraf.Metric) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.Name()))
+
+	tags := m.Tags()
+	tmp := make([]string, 0, len(tags))
+	for k, v := range tags {
+		tmp = append(tmp, k+"="+v)
+	}
+	sort.Strings(tmp)
+
+	for _, s := range tmp {
+		h.Write([]byte(s))
+	}
+	return h.Sum64()
+}
+
+// convert returns the float64 value of any numeric field type.
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("basicstats", func() telegraf.Aggregator {
+		return NewBasicStats()
+	})
+}
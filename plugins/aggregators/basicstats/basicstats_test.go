@@ -0,0 +1,128 @@
+package basicstats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var m1, _ = telegraf.NewMetric("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a": int64(1),
+		"b": int64(1),
+		"c": float64(2),
+		"d": float64(2),
+	},
+	time.Now(),
+)
+var m2, _ = telegraf.NewMetric("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a":        int64(1),
+		"b":        int64(3),
+		"c":        float64(4),
+		"d":        float64(6),
+		"e":        float64(200),
+		"ignoreme": "string",
+		"andme":    true,
+	},
+	time.Now(),
+)
+
+// Test two metrics getting added.
+func TestBasicStatsWithTwoMetrics(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	bs.Add(m1)
+	bs.Add(m2)
+	bs.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"a_count":  int64(2),
+		"a_max":    float64(1),
+		"a_min":    float64(1),
+		"a_mean":   float64(1),
+		"a_stddev": float64(0),
+		"b_count":  int64(2),
+		"b_max":    float64(3),
+		"b_min":    float64(1),
+		"b_mean":   float64(2),
+		"b_stddev": float64(1),
+		"c_count":  int64(2),
+		"c_max":    float64(4),
+		"c_min":    float64(2),
+		"c_mean":   float64(3),
+		"c_stddev": float64(1),
+		"d_count":  int64(2),
+		"d_max":    float64(6),
+		"d_min":    float64(2),
+		"d_mean":   float64(4),
+		"d_stddev": float64(2),
+		"e_count":  int64(1),
+		"e_max":    float64(200),
+		"e_min":    float64(200),
+		"e_mean":   float64(200),
+		"e_stddev": float64(0),
+	}
+	expectedTags := map[string]string{
+		"foo": "bar",
+	}
+	acc.AssertContainsTaggedFields(t, "m1", expectedFields, expectedTags)
+}
+
+// Test that metrics with different tags are aggregated separately.
+func TestBasicStatsDifferentSeries(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	m3, _ := telegraf.NewMetric("m1",
+		map[string]string{"foo": "baz"},
+		map[string]interface{}{"a": float64(10)},
+		time.Now(),
+	)
+	bs.Add(m1)
+	bs.Add(m3)
+	bs.Push(&acc)
+
+	assert.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{
+			"a_count":  int64(1),
+			"a_max":    float64(10),
+			"a_min":    float64(10),
+			"a_mean":   float64(10),
+			"a_stddev": float64(0),
+		},
+		map[string]string{"foo": "baz"})
+}
+
+// Test that Reset clears the aggregates.
+func TestBasicStatsReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	bs.Add(m1)
+	bs.Reset()
+	bs.Push(&acc)
+
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestBasicStatsStddev(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		m, _ := telegraf.NewMetric("m1", nil,
+			map[string]interface{}{"a": v}, time.Now())
+		bs.Add(m)
+	}
+	bs.Push(&acc)
+
+	stddev, ok := acc.Metrics[0].Fields["a_stddev"].(float64)
+	assert.True(t, ok)
+	assert.True(t, math.Abs(stddev-2) < 1e-9)
+}
@@ -0,0 +1,326 @@
+package external
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// Longest delay before a plugin that keeps exiting is restarted.
+	maxRestartDelay = time.Minute
+
+	// How long a plugin has to exit once its stdin is closed, before it is
+	// killed.
+	stopTimeout = 5 * time.Second
+)
+
+// Process runs an external plugin, restarting it with a growing delay whenever
+// it exits, until it is stopped.
+type Process struct {
+	// Command is the plugin's executable and its arguments.
+	Command []string
+	// Environment is added to the environment of the plugin.
+	Environment []string
+	// RestartDelay is how long to wait before restarting the plugin. It
+	// doubles, up to a minute, while the plugin keeps exiting.
+	RestartDelay time.Duration
+
+	// Log logs the plugin's log lines and what it writes to stderr.
+	Log telegraf.Logger
+	// Metric is called with each metric line the plugin writes.
+	Metric func(line string)
+
+	mu  sync.Mutex
+	run *run
+	// pending is the id of the request waiting for its answer, if any
+	pending string
+
+	// requests serializes the requests, answers gets their answers, and seq
+	// numbers them
+	requests sync.Mutex
+	answers  chan error
+	seq      uint64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// run is a running process of the plugin.
+type run struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	started time.Time
+
+	// exited is closed once the process exited, with its error in err
+	exited chan struct{}
+	err    error
+}
+
+// Start starts the plugin. It returns an error if it can't be started, and
+// otherwise keeps it running until Stop.
+func (p *Process) Start() error {
+	if len(p.Command) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	p.answers = make(chan error, 1)
+	p.done = make(chan struct{})
+
+	r, err := p.start()
+	if err != nil {
+		return err
+	}
+	p.wg.Add(1)
+	go p.supervise(r)
+	return nil
+}
+
+// Stop closes the plugin's stdin, asking it to exit, and kills it if it is
+// still running after 5s.
+func (p *Process) Stop() {
+	if p.done == nil {
+		return
+	}
+	close(p.done)
+	p.wg.Wait()
+	p.done = nil
+}
+
+// Request writes the lines to the plugin, followed by the control message, and
+// waits for at most timeout for its answer, returning the error it reports.
+// Each request has an id of its own, so that a late answer to a request that
+// timed out is not taken for the answer to a later one.
+func (p *Process) Request(lines []string, control string, timeout time.Duration) error {
+	p.requests.Lock()
+	defer p.requests.Unlock()
+
+	p.mu.Lock()
+	r := p.run
+	p.mu.Unlock()
+	if r == nil {
+		return fmt.Errorf("plugin is not running")
+	}
+	select {
+	case <-r.exited:
+		return fmt.Errorf("plugin is not running")
+	default:
+	}
+
+	// the answer to the last request may have come as it timed out
+	select {
+	case <-p.answers:
+	default:
+	}
+	p.seq++
+	id := strconv.FormatUint(p.seq, 10)
+	p.mu.Lock()
+	p.pending = id
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.pending = ""
+		p.mu.Unlock()
+	}()
+
+	var buf []byte
+	for _, line := range lines {
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, RequestLine(control, id)...)
+	buf = append(buf, '\n')
+	if _, err := r.stdin.Write(buf); err != nil {
+		return fmt.Errorf("writing to plugin: %s", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-p.answers:
+		return err
+	case <-r.exited:
+		return fmt.Errorf("plugin exited before answering %s", control)
+	case <-timer.C:
+		return fmt.Errorf("plugin did not answer %s within %s", control, timeout)
+	}
+}
+
+// start starts a process of the plugin.
+func (p *Process) start() (*run, error) {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	if len(p.Environment) > 0 {
+		cmd.Env = append(os.Environ(), p.Environment...)
+	}
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting '%s': %s", p.Command[0], err)
+	}
+
+	r := &run{
+		cmd:     cmd,
+		stdin:   stdin,
+		started: time.Now(),
+		exited:  make(chan struct{}),
+	}
+	p.mu.Lock()
+	p.run = r
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.readStdout(stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		p.readStderr(stderr)
+	}()
+	go func() {
+		wg.Wait()
+		r.err = cmd.Wait()
+		close(r.exited)
+	}()
+	return r, nil
+}
+
+// supervise restarts the plugin whenever it exits, with a growing delay,
+// until it is stopped.
+func (p *Process) supervise(r *run) {
+	defer p.wg.Done()
+
+	delay := p.RestartDelay
+	for {
+		err := p.wait(r)
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		// a plugin that ran for a while is restarted quickly again
+		if time.Since(r.started) >= maxRestartDelay {
+			delay = p.RestartDelay
+		}
+		for {
+			p.Log.Errorf("%s, restarting it in %s", err, delay)
+			select {
+			case <-p.done:
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > maxRestartDelay {
+				delay = maxRestartDelay
+			}
+
+			if r, err = p.start(); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// wait waits for the process to exit, or stops it when the plugin is stopped.
+func (p *Process) wait(r *run) error {
+	select {
+	case <-r.exited:
+	case <-p.done:
+		r.stdin.Close()
+		select {
+		case <-r.exited:
+		case <-time.After(stopTimeout):
+			killProcessGroup(r.cmd)
+			<-r.exited
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("plugin exited: %s", r.err)
+	}
+	return fmt.Errorf("plugin exited")
+}
+
+// readStdout reads the metrics, answers and log lines the plugin writes.
+func (p *Process) readStdout(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !IsControl(line) {
+			p.Metric(line)
+			continue
+		}
+
+		if ok, id, err := ParseAnswer(line); ok {
+			p.answer(id, err, line)
+		} else if ok, level, msg := ParseLog(line); ok {
+			p.log(level, msg)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		p.Log.Errorf("Error reading from plugin: %s", err)
+	}
+	// keep the pipe drained so that the plugin doesn't block on it
+	io.Copy(ioutil.Discard, stdout)
+}
+
+// answer hands the answer over to the request waiting for it. Answers to a
+// request that timed out, or without its id, are ignored.
+func (p *Process) answer(id string, err error, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id == "" || id != p.pending {
+		p.Log.Warnf("Ignoring answer from plugin to no pending request: %s", line)
+		return
+	}
+	// the request only gets one answer, there is room for it
+	p.pending = ""
+	p.answers <- err
+}
+
+// readStderr logs what the plugin writes to stderr.
+func (p *Process) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.Log.Errorf("%s", scanner.Text())
+	}
+	io.Copy(ioutil.Discard, stderr)
+}
+
+// log logs a log line of the plugin at its level.
+func (p *Process) log(level, msg string) {
+	switch level {
+	case LevelError:
+		p.Log.Errorf("%s", msg)
+	case LevelWarn:
+		p.Log.Warnf("%s", msg)
+	case LevelDebug:
+		p.Log.Debugf("%s", msg)
+	default:
+		p.Log.Infof("%s", msg)
+	}
+}
@@ -0,0 +1,195 @@
+package external
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skipWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test that runs sh on Windows")
+	}
+}
+
+// recordingLogger keeps the messages logged, prefixed with their level.
+type recordingLogger struct {
+	sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.log("E! ", format, args...)
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.log("W! ", format, args...)
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.log("I! ", format, args...)
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.log("D! ", format, args...)
+}
+
+func (l *recordingLogger) log(prefix, format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.messages = append(l.messages, prefix+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Messages() []string {
+	l.Lock()
+	defer l.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+// metricRecorder keeps the metric lines written by a plugin.
+type metricRecorder struct {
+	sync.Mutex
+	lines []string
+}
+
+func (r *metricRecorder) add(line string) {
+	r.Lock()
+	defer r.Unlock()
+	r.lines = append(r.lines, line)
+}
+
+func (r *metricRecorder) Lines() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+// waitFor waits for at most 5s for cond to be true.
+func waitFor(cond func() bool) bool {
+	for start := time.Now(); time.Since(start) < 5*time.Second; {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+const plugin = `
+while read line id; do
+  case "$line" in
+    "#gather")
+      echo "cpu value=1"
+      echo "#log warn almost full"
+      echo "#ok $id";;
+    "#write")
+      echo oops >&2
+      echo "#error $id disk full";;
+  esac
+done`
+
+func TestProcessRequest(t *testing.T) {
+	skipWindows(t)
+	var log recordingLogger
+	var metrics metricRecorder
+	p := &Process{
+		Command:      []string{"sh", "-c", plugin},
+		RestartDelay: time.Second,
+		Log:          &log,
+		Metric:       metrics.add,
+	}
+	require.NoError(t, p.Start())
+
+	require.NoError(t, p.Request(nil, Gather, 5*time.Second))
+	assert.Equal(t, []string{"cpu value=1"}, metrics.Lines())
+
+	err := p.Request([]string{"cpu value=1"}, Write, 5*time.Second)
+	assert.EqualError(t, err, "disk full")
+
+	start := time.Now()
+	p.Stop()
+	assert.True(t, time.Since(start) < stopTimeout)
+
+	messages := log.Messages()
+	assert.Contains(t, messages, "W! almost full")
+	assert.Contains(t, messages, "E! oops")
+}
+
+func TestProcessRequestTimeout(t *testing.T) {
+	skipWindows(t)
+	p := &Process{
+		Command:      []string{"sh", "-c", "while read line; do :; done"},
+		RestartDelay: time.Second,
+		Log:          &recordingLogger{},
+	}
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	err := p.Request(nil, Gather, 10*time.Millisecond)
+	assert.EqualError(t, err, "plugin did not answer #gather within 10ms")
+}
+
+// Test that the answer to a request that timed out is not taken for the
+// answer to the next one.
+func TestProcessLateAnswer(t *testing.T) {
+	skipWindows(t)
+	var log recordingLogger
+	p := &Process{
+		Command: []string{"sh", "-c", `
+n=0
+while read line id; do
+  n=$((n+1))
+  if [ $n = 1 ]; then
+    sleep 0.2
+    echo "#error $id too late"
+  else
+    echo "#ok $id"
+  fi
+done`},
+		RestartDelay: time.Second,
+		Log:          &log,
+	}
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	err := p.Request(nil, Gather, 10*time.Millisecond)
+	assert.EqualError(t, err, "plugin did not answer #gather within 10ms")
+	require.NoError(t, p.Request(nil, Gather, 5*time.Second))
+	assert.Contains(t, log.Messages(),
+		"W! Ignoring answer from plugin to no pending request: #error 1 too late")
+}
+
+func TestProcessRestart(t *testing.T) {
+	skipWindows(t)
+	var log recordingLogger
+	var metrics metricRecorder
+	p := &Process{
+		Command:      []string{"sh", "-c", "echo cpu value=1"},
+		RestartDelay: 10 * time.Millisecond,
+		Log:          &log,
+		Metric:       metrics.add,
+	}
+	require.NoError(t, p.Start())
+	require.True(t, waitFor(func() bool { return len(metrics.Lines()) >= 3 }))
+	p.Stop()
+
+	messages := log.Messages()
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "E! plugin exited, restarting it in 10ms", messages[0])
+	assert.True(t, strings.HasSuffix(messages[1], "restarting it in 20ms"), messages[1])
+}
+
+func TestProcessStartError(t *testing.T) {
+	p := &Process{
+		Command: []string{"/nonexistent/plugin"},
+		Log:     &recordingLogger{},
+	}
+	assert.Error(t, p.Start())
+	p.Stop()
+}
@@ -0,0 +1,17 @@
+// +build windows
+
+package external
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where only the command itself can
+// be killed.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kills the command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
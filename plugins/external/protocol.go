@@ -0,0 +1,143 @@
+// Package external implements the protocol between telegraf and the plugins
+// that run as processes of their own, external plugins, which the execd input
+// and output speak.
+//
+// An external plugin reads lines from stdin and writes lines to stdout, each
+// terminated by "\n". Lines starting with "#" are control messages, empty
+// lines are ignored, and every other line is a metric in InfluxDB line
+// protocol.
+//
+// Telegraf writes to an input plugin:
+//
+//	#gather <id>            gather metrics now
+//
+// and to an output plugin the metrics of a batch, followed by:
+//
+//	#write <id>             write the batch of metrics sent since the last #write
+//
+// The plugin answers each #gather and #write, in order, with the id of the
+// request, a token without spaces:
+//
+//	#ok <id>                it succeeded
+//	#error <id> <message>   it failed
+//
+// The id tells the answer to a request that timed out from the answer to the
+// next one, telegraf ignores answers to requests it no longer waits for. An
+// input plugin writes the metrics it gathers before the answer. It may also
+// write metrics at any other time, ie, as it receives them from a socket.
+// Either kind of plugin may write log lines, at any time:
+//
+//	#log <level> <message>
+//
+// where level is error, warn, info or debug. What it writes to stderr is logged
+// as errors. Other control messages are ignored, so that the protocol can grow.
+//
+// When telegraf closes stdin, the plugin must exit.
+package external
+
+import (
+	"errors"
+	"strings"
+)
+
+// The control messages.
+const (
+	Gather = "#gather"
+	Write  = "#write"
+	OK     = "#ok"
+	Error  = "#error"
+	Log    = "#log"
+)
+
+// The levels of the log lines.
+const (
+	LevelError = "error"
+	LevelWarn  = "warn"
+	LevelInfo  = "info"
+	LevelDebug = "debug"
+)
+
+// IsControl reports whether the line is a control message rather than a
+// metric.
+func IsControl(line string) bool {
+	return strings.HasPrefix(line, "#")
+}
+
+// RequestLine returns the control message requesting a #gather or #write,
+// with the request's id.
+func RequestLine(control, id string) string {
+	return control + " " + id
+}
+
+// ParseRequest parses a control message requesting a #gather or #write. It
+// returns the name of the control message, and the id of the request.
+func ParseRequest(line string) (string, string) {
+	name, arg := splitControl(strings.TrimSpace(line))
+	id, _ := splitControl(arg)
+	return name, id
+}
+
+// OKLine returns the control message answering that the #gather or #write
+// with the id succeeded.
+func OKLine(id string) string {
+	return OK + " " + id
+}
+
+// ErrorLine returns the control message answering that the #gather or #write
+// with the id failed with err. The message is kept on one line.
+func ErrorLine(id string, err error) string {
+	return Error + " " + id + " " + oneLine(err.Error())
+}
+
+// LogLine returns the control message logging msg at level.
+func LogLine(level, msg string) string {
+	return Log + " " + level + " " + oneLine(msg)
+}
+
+// ParseAnswer parses a control message answering a #gather or #write. It
+// returns whether the line is one, the id of the request it answers, and the
+// error it reports if it failed.
+func ParseAnswer(line string) (bool, string, error) {
+	name, arg := splitControl(line)
+	switch name {
+	case OK:
+		id, _ := splitControl(arg)
+		return true, id, nil
+	case Error:
+		id, msg := splitControl(arg)
+		if msg == "" {
+			msg = "unknown error"
+		}
+		return true, id, errors.New(msg)
+	}
+	return false, "", nil
+}
+
+// ParseLog parses a log control message. It returns whether the line is one,
+// and its level and message.
+func ParseLog(line string) (bool, string, string) {
+	name, arg := splitControl(line)
+	if name != Log {
+		return false, "", ""
+	}
+	parts := strings.SplitN(arg, " ", 2)
+	if len(parts) < 2 {
+		return true, LevelInfo, arg
+	}
+	return true, parts[0], parts[1]
+}
+
+// splitControl splits a control message into its name and argument.
+func splitControl(line string) (string, string) {
+	line = strings.TrimRight(line, "\r\n")
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// oneLine replaces the newlines of s with spaces.
+func oneLine(s string) string {
+	return strings.Replace(strings.TrimSpace(s), "\n", " ", -1)
+}
@@ -0,0 +1,60 @@
+package external
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAnswer(t *testing.T) {
+	ok, id, err := ParseAnswer(OKLine("7"))
+	assert.True(t, ok)
+	assert.Equal(t, "7", id)
+	assert.NoError(t, err)
+
+	ok, id, err = ParseAnswer(ErrorLine("8", fmt.Errorf("disk\nfull")))
+	assert.True(t, ok)
+	assert.Equal(t, "8", id)
+	assert.EqualError(t, err, "disk full")
+
+	ok, id, err = ParseAnswer("#error 9")
+	assert.True(t, ok)
+	assert.Equal(t, "9", id)
+	assert.EqualError(t, err, "unknown error")
+
+	// an answer without an id answers no request
+	ok, id, _ = ParseAnswer("#ok")
+	assert.True(t, ok)
+	assert.Equal(t, "", id)
+
+	ok, _, _ = ParseAnswer("#okay 1")
+	assert.False(t, ok)
+	ok, _, _ = ParseAnswer("#log info #ok 1")
+	assert.False(t, ok)
+}
+
+func TestParseRequest(t *testing.T) {
+	name, id := ParseRequest(RequestLine(Gather, "42") + "\r\n")
+	assert.Equal(t, Gather, name)
+	assert.Equal(t, "42", id)
+
+	name, id = ParseRequest("#write")
+	assert.Equal(t, Write, name)
+	assert.Equal(t, "", id)
+}
+
+func TestParseLog(t *testing.T) {
+	ok, level, msg := ParseLog(LogLine(LevelWarn, "disk almost full"))
+	assert.True(t, ok)
+	assert.Equal(t, LevelWarn, level)
+	assert.Equal(t, "disk almost full", msg)
+
+	ok, level, msg = ParseLog("#log started")
+	assert.True(t, ok)
+	assert.Equal(t, LevelInfo, level)
+	assert.Equal(t, "started", msg)
+
+	ok, _, _ = ParseLog("#ok")
+	assert.False(t, ok)
+}
@@ -0,0 +1,183 @@
+package shim
+
+import (
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// accumulator writes the metrics added by the input to stdout as they are
+// added, so that those of a Gather are written before it is answered.
+type accumulator struct {
+	w     *writer
+	log   *logger
+	debug bool
+}
+
+func (a *accumulator) Add(
+	measurement string,
+	value interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	fields := map[string]interface{}{"value": value}
+	a.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (a *accumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (a *accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (a *accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (a *accumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Summary, t...)
+}
+
+func (a *accumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Histogram, t...)
+}
+
+func (a *accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t ...time.Time,
+) {
+	timestamp := time.Now()
+	if len(t) > 0 {
+		timestamp = t[0]
+	}
+	if m := a.makeMetric(measurement, fields, tags, mType, timestamp); m != nil {
+		a.writeMetric(m)
+	}
+}
+
+// makeMetric returns a metric made of copies of the tags and fields, without
+// the NaN and Inf fields, which line protocol can't carry, or nil if it has no
+// fields left.
+func (a *accumulator) makeMetric(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t time.Time,
+) telegraf.Metric {
+	mTags := make(map[string]string, len(tags))
+	for k, v := range tags {
+		mTags[k] = v
+	}
+	mFields := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			continue
+		}
+		mFields[k] = v
+	}
+	if len(mFields) == 0 {
+		return nil
+	}
+
+	m, err := telegraf.NewTypedMetric(measurement, mTags, mFields, mType, t)
+	if err != nil {
+		a.log.Errorf("Error adding point [%s]: %s", measurement, err)
+		return nil
+	}
+	return m
+}
+
+func (a *accumulator) writeMetric(m telegraf.Metric) error {
+	return a.w.writeLines(m.String())
+}
+
+func (a *accumulator) AddError(err error) {
+	if err != nil {
+		a.log.Errorf("%s", err)
+	}
+}
+
+func (a *accumulator) Debug() bool {
+	return a.debug
+}
+
+func (a *accumulator) SetDebug(debug bool) {
+	a.debug = debug
+}
+
+func (a *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// trackingAccumulator reports the metrics added with AddTrackingMetricGroup
+// as delivered once they are written to stdout: telegraf doesn't tell an
+// external plugin whether its metrics were written to the outputs.
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	metrics := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		m = a.makeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time())
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+
+	metrics, id := telegraf.NewTrackingMetricGroup(metrics, a.onDelivery)
+	for _, m := range metrics {
+		if err := a.writeMetric(m); err != nil {
+			m.Reject()
+		} else {
+			m.Accept()
+		}
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
+}
@@ -0,0 +1,58 @@
+package shim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf/plugins/external"
+)
+
+// logger writes the messages of the plugin as log lines. It is also the
+// output of the standard log package, where the level of a message is given
+// by its "E! ", "W! ", "I! " or "D! " prefix, info if it has none.
+type logger struct {
+	w *writer
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log(external.LevelError, format, args...)
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log(external.LevelWarn, format, args...)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log(external.LevelInfo, format, args...)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log(external.LevelDebug, format, args...)
+}
+
+func (l *logger) log(level, format string, args ...interface{}) {
+	l.w.writeLines(external.LogLine(level, fmt.Sprintf(format, args...)))
+}
+
+// levelPrefixes are the prefixes of the messages logged with the standard log
+// package, and their levels.
+var levelPrefixes = map[string]string{
+	"E! ": external.LevelError,
+	"W! ": external.LevelWarn,
+	"I! ": external.LevelInfo,
+	"D! ": external.LevelDebug,
+}
+
+func (l *logger) Write(b []byte) (int, error) {
+	msg := strings.TrimRight(string(b), "\n")
+	level := external.LevelInfo
+	if len(msg) >= 3 {
+		if lvl, ok := levelPrefixes[msg[:3]]; ok {
+			level, msg = lvl, msg[3:]
+		}
+	}
+	if err := l.w.writeLines(external.LogLine(level, msg)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,153 @@
+// Package shim runs a telegraf input or output as an external plugin, a
+// process of its own that telegraf runs with the execd input or output. It
+// lets plugins that are not part of telegraf be used without rebuilding it:
+//
+//	func main() {
+//		if err := shim.RunInput(mycollector.New()); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+//
+// See package external for the protocol.
+package shim
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+// RunInput runs the input until stdin is closed. Its Gather is called on each
+// #gather, and if it is a service input, it is started first, and stopped
+// before RunInput returns. The metrics it adds are written to stdout, and what
+// it logs, with its telegraf.Logger or the standard log package, is written as
+// log lines.
+func RunInput(input telegraf.Input) error {
+	w := &writer{w: os.Stdout}
+	redirectLog(w)
+	return runInput(input, os.Stdin, w)
+}
+
+// RunOutput connects the output and runs it until stdin is closed, writing the
+// metrics of each batch read from stdin. If it is a service output, it is
+// started first. It is closed, and stopped, before RunOutput returns.
+func RunOutput(output telegraf.Output) error {
+	w := &writer{w: os.Stdout}
+	redirectLog(w)
+	return runOutput(output, os.Stdin, w)
+}
+
+// redirectLog makes the standard log package write log lines.
+func redirectLog(w *writer) {
+	log.SetFlags(0)
+	log.SetOutput(&logger{w: w})
+}
+
+func runInput(input telegraf.Input, stdin io.Reader, w *writer) error {
+	l := &logger{w: w}
+	if p, ok := input.(telegraf.LoggingPlugin); ok {
+		p.SetLogger(l)
+	}
+	acc := &accumulator{w: w, log: l}
+
+	if p, ok := input.(telegraf.ServiceInput); ok {
+		if err := p.Start(acc); err != nil {
+			return err
+		}
+		defer p.Stop()
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		name, id := external.ParseRequest(scanner.Text())
+		if name != external.Gather {
+			continue
+		}
+		answer := external.OKLine(id)
+		if err := input.Gather(acc); err != nil {
+			answer = external.ErrorLine(id, err)
+		}
+		if err := w.writeLines(answer); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func runOutput(output telegraf.Output, stdin io.Reader, w *writer) error {
+	l := &logger{w: w}
+	if p, ok := output.(telegraf.LoggingPlugin); ok {
+		p.SetLogger(l)
+	}
+
+	if p, ok := output.(telegraf.ServiceOutput); ok {
+		if err := p.Start(); err != nil {
+			return err
+		}
+		defer p.Stop()
+	}
+	if err := output.Connect(); err != nil {
+		return err
+	}
+	defer output.Close()
+
+	parser := &influx.InfluxParser{}
+	var batch []telegraf.Metric
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !external.IsControl(line) {
+			m, err := parser.ParseLine(line)
+			if err != nil {
+				l.Errorf("Error parsing metric '%s': %s", line, err)
+				continue
+			}
+			batch = append(batch, m)
+			continue
+		}
+		name, id := external.ParseRequest(line)
+		if name != external.Write {
+			continue
+		}
+
+		answer := external.OKLine(id)
+		if err := output.Write(batch); err != nil {
+			answer = external.ErrorLine(id, err)
+		}
+		batch = nil
+		if err := w.writeLines(answer); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// writer writes lines to stdout, so that those written by different
+// goroutines are not mixed.
+type writer struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (w *writer) writeLines(lines ...string) error {
+	var buf []byte
+	for _, line := range lines {
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	w.Lock()
+	defer w.Unlock()
+	_, err := w.w.Write(buf)
+	return err
+}
@@ -0,0 +1,118 @@
+package shim
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInput struct {
+	gathers int
+	logger  telegraf.Logger
+}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+
+func (i *testInput) SetLogger(logger telegraf.Logger) {
+	i.logger = logger
+}
+
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	i.gathers++
+	if i.gathers > 1 {
+		return fmt.Errorf("permission\ndenied")
+	}
+	i.logger.Infof("gathering")
+	acc.AddFields("cpu", map[string]interface{}{"value": 1},
+		map[string]string{"cpu": "cpu0"}, time.Unix(0, 1))
+	return nil
+}
+
+func TestRunInput(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader("#gather 1\n\n#something new\n#gather 2\n")
+	require.NoError(t, runInput(&testInput{}, stdin, &writer{w: &out}))
+
+	assert.Equal(t, "#log info gathering\n"+
+		"cpu,cpu=cpu0 value=1i 1\n"+
+		"#ok 1\n"+
+		"#error 2 permission denied\n", out.String())
+}
+
+type testOutput struct {
+	batches [][]telegraf.Metric
+	fail    bool
+	closed  bool
+}
+
+func (o *testOutput) Connect() error       { return nil }
+func (o *testOutput) Description() string  { return "" }
+func (o *testOutput) SampleConfig() string { return "" }
+
+func (o *testOutput) Close() error {
+	o.closed = true
+	return nil
+}
+
+func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return fmt.Errorf("disk full")
+	}
+	o.batches = append(o.batches, metrics)
+	o.fail = true
+	return nil
+}
+
+func TestRunOutput(t *testing.T) {
+	var out bytes.Buffer
+	stdin := strings.NewReader("cpu value=1 1\nmem value=2 2\n#write 1\n" +
+		"not line protocol\ncpu value=3 3\n#write 2\n")
+	output := &testOutput{}
+	require.NoError(t, runOutput(output, stdin, &writer{w: &out}))
+
+	require.Len(t, output.batches, 1)
+	require.Len(t, output.batches[0], 2)
+	assert.Equal(t, "cpu", output.batches[0][0].Name())
+	assert.Equal(t, "mem", output.batches[0][1].Name())
+	assert.True(t, output.closed)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "#ok 1", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "#log error Error parsing metric"), lines[1])
+	assert.Equal(t, "#error 2 disk full", lines[2])
+}
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	l := log.New(&logger{w: &writer{w: &out}}, "", 0)
+	l.Printf("W! disk almost full")
+	l.Printf("started")
+	assert.Equal(t, "#log warn disk almost full\n#log info started\n",
+		out.String())
+}
+
+func TestTrackingAccumulator(t *testing.T) {
+	var out bytes.Buffer
+	acc := &accumulator{w: &writer{w: &out}}
+	tracking := acc.WithTracking(1)
+
+	m, err := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": 1}, time.Unix(0, 1))
+	require.NoError(t, err)
+	id := tracking.AddTrackingMetricGroup([]telegraf.Metric{m})
+
+	info := <-tracking.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+	assert.Equal(t, "cpu value=1i 1\n", out.String())
+}
@@ -1,15 +1,23 @@
 package exec
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gonuts/go-shellquote"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
@@ -19,6 +27,23 @@ const sampleConfig = `
   ## Commands array
   commands = ["/tmp/test.sh", "/usr/bin/mycollector --foo=bar"]
 
+  ## Timeout for each command to complete. The command, and any process it
+  ## started, is killed once it expires. 0s means no timeout.
+  timeout = "5s"
+
+  ## Environment variables added to the environment of the commands, and the
+  ## directory they run in. Default is the environment and directory of
+  ## telegraf.
+  # environment = ["LC_ALL=C", "COLLECTOR_CONFIG=/etc/collector.conf"]
+  # working_directory = "/var/lib/collector"
+
+  ## Run the commands as daemons: each command is started once, when telegraf
+  ## starts, and every line it writes to stdout is parsed as it arrives. The
+  ## timeout doesn't apply. A command that exits is restarted after
+  ## restart_delay, doubling up to 1m while it keeps exiting.
+  # daemon = false
+  # restart_delay = "1s"
+
   ## measurement name suffix (for separating different commands)
   name_suffix = "_mycollector"
 
@@ -29,9 +54,27 @@ const sampleConfig = `
   data_format = "influx"
 `
 
+const (
+	// Longest delay before a daemon command that keeps exiting is restarted.
+	maxRestartDelay = time.Minute
+
+	// How long a daemon command has to exit when telegraf stops, before it is
+	// killed.
+	stopTimeout = 5 * time.Second
+
+	// Most bytes of stderr included in the error of a failed command.
+	maxStderrBytes = 1024
+)
+
 type Exec struct {
-	Commands []string
-	Command  string
+	Commands         []string
+	Command          string
+	Timeout          internal.Duration
+	Environment      []string
+	WorkingDirectory string
+
+	Daemon       bool
+	RestartDelay internal.Duration
 
 	parser parsers.Parser
 
@@ -39,11 +82,15 @@ type Exec struct {
 
 	runner  Runner
 	errChan chan error
+
+	// done stops the daemon commands
+	done chan struct{}
 }
 
 func NewExec() *Exec {
 	return &Exec{
-		runner: CommandRunner{},
+		runner:       CommandRunner{},
+		RestartDelay: internal.Duration{Duration: time.Second},
 	}
 }
 
@@ -74,31 +121,80 @@ func AddNagiosState(exitCode error, acc telegraf.Accumulator) error {
 }
 
 func (c CommandRunner) Run(e *Exec, command string, acc telegraf.Accumulator) ([]byte, error) {
+	cmd, err := e.command(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	_, isNagios := e.parser.(*nagios.NagiosParser)
+	if err := runTimeout(cmd, e.Timeout.Duration); err != nil {
+		// the exit code of a nagios plugin is its state, but a command that
+		// couldn't run or timed out is an error
+		if _, exited := err.(*exec.ExitError); !exited || !isNagios {
+			return nil, fmt.Errorf("exec: %s for command '%s'%s",
+				err, command, stderrMessage(stderr.Bytes()))
+		}
+		AddNagiosState(err, acc)
+	} else if isNagios {
+		AddNagiosState(nil, acc)
+	}
+
+	return out.Bytes(), nil
+}
+
+// command returns the command to run for the given command line, with the
+// configured environment and working directory, in its own process group.
+func (e *Exec) command(command string) (*exec.Cmd, error) {
 	split_cmd, err := shellquote.Split(command)
 	if err != nil || len(split_cmd) == 0 {
 		return nil, fmt.Errorf("exec: unable to parse command, %s", err)
 	}
 
 	cmd := exec.Command(split_cmd[0], split_cmd[1:]...)
+	if len(e.Environment) > 0 {
+		cmd.Env = append(os.Environ(), e.Environment...)
+	}
+	cmd.Dir = e.WorkingDirectory
+	setProcessGroup(cmd)
+	return cmd, nil
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
+// runTimeout runs the command, and kills it and its children if it is still
+// running after timeout. A timeout of 0 means no timeout.
+func runTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if timeout == 0 {
+		return cmd.Wait()
+	}
 
-	if err := cmd.Run(); err != nil {
-		switch e.parser.(type) {
-		case *nagios.NagiosParser:
-			AddNagiosState(err, acc)
-		default:
-			return nil, fmt.Errorf("exec: %s for command '%s'", err, command)
-		}
-	} else {
-		switch e.parser.(type) {
-		case *nagios.NagiosParser:
-			AddNagiosState(nil, acc)
+	timer := time.AfterFunc(timeout, func() {
+		if err := killProcessGroup(cmd); err != nil {
+			log.Printf("exec: error killing command %s: %s", cmd.Path, err)
 		}
+	})
+	err := cmd.Wait()
+	if !timer.Stop() {
+		return fmt.Errorf("command timed out after %s", timeout)
 	}
+	return err
+}
 
-	return out.Bytes(), nil
+// stderrMessage formats what a command wrote to stderr for its error message.
+func stderrMessage(stderr []byte) string {
+	stderr = bytes.TrimSpace(stderr)
+	if len(stderr) == 0 {
+		return ""
+	}
+	if len(stderr) > maxStderrBytes {
+		stderr = append(stderr[:maxStderrBytes:maxStderrBytes], "..."...)
+	}
+	return ": " + string(stderr)
 }
 
 func (e *Exec) ProcessCommand(command string, acc telegraf.Accumulator) {
@@ -133,10 +229,10 @@ func (e *Exec) SetParser(parser parsers.Parser) {
 }
 
 func (e *Exec) Gather(acc telegraf.Accumulator) error {
-	// Legacy single command support
-	if e.Command != "" {
-		e.Commands = append(e.Commands, e.Command)
-		e.Command = ""
+	e.legacyCommand()
+	if e.Daemon {
+		// the daemon commands add their metrics as they write them
+		return nil
 	}
 
 	e.errChan = make(chan error, len(e.Commands))
@@ -158,6 +254,164 @@ func (e *Exec) Gather(acc telegraf.Accumulator) error {
 
 }
 
+// legacyCommand adds the legacy single command to the commands.
+func (e *Exec) legacyCommand() {
+	if e.Command != "" {
+		e.Commands = append(e.Commands, e.Command)
+		e.Command = ""
+	}
+}
+
+// Start starts the commands when they run as daemons.
+func (e *Exec) Start(acc telegraf.Accumulator) error {
+	if !e.Daemon {
+		return nil
+	}
+	e.legacyCommand()
+
+	e.done = make(chan struct{})
+	for _, command := range e.Commands {
+		e.wg.Add(1)
+		go e.runDaemon(command, acc, e.done)
+	}
+	return nil
+}
+
+// Stop stops the daemon commands, killing those that don't exit within
+// stopTimeout.
+func (e *Exec) Stop() {
+	if e.done == nil {
+		return
+	}
+	close(e.done)
+	e.wg.Wait()
+	e.done = nil
+}
+
+// runDaemon runs the command until done is closed, restarting it with a
+// growing delay whenever it exits.
+func (e *Exec) runDaemon(
+	command string,
+	acc telegraf.Accumulator,
+	done chan struct{},
+) {
+	defer e.wg.Done()
+
+	delay := e.RestartDelay.Duration
+	for {
+		start := time.Now()
+		err := e.runDaemonOnce(command, acc, done)
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		// a command that ran for a while is restarted quickly again
+		if time.Since(start) >= maxRestartDelay {
+			delay = e.RestartDelay.Duration
+		}
+		if err == nil {
+			err = fmt.Errorf("exec: command '%s' exited", command)
+		}
+		acc.AddError(fmt.Errorf("%s, restarting it in %s", err, delay))
+
+		select {
+		case <-done:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// runDaemonOnce runs the command until it exits or done is closed, adding the
+// metrics parsed from each line of its output.
+func (e *Exec) runDaemonOnce(
+	command string,
+	acc telegraf.Accumulator,
+	done chan struct{},
+) error {
+	cmd, err := e.command(command)
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("exec: %s for command '%s'", err, command)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-exited:
+			return
+		case <-done:
+		}
+		terminateProcessGroup(cmd)
+		select {
+		case <-exited:
+		case <-time.After(stopTimeout):
+			killProcessGroup(cmd)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logStderr(command, stderr)
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		metric, err := e.parser.ParseLine(line)
+		if err != nil {
+			acc.AddError(fmt.Errorf("exec: %s parsing output of command '%s'",
+				err, command))
+			continue
+		}
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+	}
+	if err := scanner.Err(); err != nil {
+		acc.AddError(fmt.Errorf("exec: error reading output of command '%s': %s",
+			command, err))
+		// keep the pipe drained so that the command doesn't block on it
+		io.Copy(ioutil.Discard, stdout)
+	}
+	wg.Wait()
+
+	err = cmd.Wait()
+	close(exited)
+	if err != nil {
+		return fmt.Errorf("exec: %s for command '%s'", err, command)
+	}
+	return nil
+}
+
+// logStderr logs what a daemon command writes to stderr.
+func logStderr(command string, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("exec: command '%s': %s", command, scanner.Text())
+	}
+	io.Copy(ioutil.Discard, stderr)
+}
+
 func init() {
 	inputs.Add("exec", func() telegraf.Input {
 		return NewExec()
@@ -2,9 +2,15 @@ package exec
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/parsers"
 
 	"github.com/influxdata/telegraf/testutil"
@@ -169,3 +175,122 @@ func TestLineProtocolParseMultiple(t *testing.T) {
 		acc.AssertContainsTaggedFields(t, "cpu", fields, tags)
 	}
 }
+
+func skipWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test that runs sh on Windows")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	skipWindows(t)
+	parser, _ := parsers.NewInfluxParser()
+	e := NewExec()
+	e.parser = parser
+	e.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	// the background sleep keeps stdout open, it must be killed too
+	e.Commands = []string{"sh -c 'sleep 10 & sleep 10'"}
+
+	var acc testutil.Accumulator
+	start := time.Now()
+	err := e.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestCommandStderr(t *testing.T) {
+	skipWindows(t)
+	parser, _ := parsers.NewInfluxParser()
+	e := NewExec()
+	e.parser = parser
+	e.Commands = []string{"sh -c 'echo something went wrong >&2; exit 3'"}
+
+	var acc testutil.Accumulator
+	err := e.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Contains(t, err.Error(), "something went wrong")
+}
+
+func TestCommandEnvironmentAndDirectory(t *testing.T) {
+	skipWindows(t)
+	dir, err := ioutil.TempDir("", "telegraf-exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(dir+"/value", []byte("42"), 0644))
+
+	parser, _ := parsers.NewInfluxParser()
+	e := NewExec()
+	e.parser = parser
+	e.Environment = []string{"MEASUREMENT=collector"}
+	e.WorkingDirectory = dir
+	e.Commands = []string{`sh -c 'echo "$MEASUREMENT value=$(cat value)"'`}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	acc.AssertContainsFields(t, "collector",
+		map[string]interface{}{"value": float64(42)})
+}
+
+// waitMetrics waits for the accumulator to have n metrics.
+func waitMetrics(acc *testutil.Accumulator, n int) bool {
+	for i := 0; i < 500; i++ {
+		acc.Lock()
+		got := len(acc.Metrics)
+		acc.Unlock()
+		if got >= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestDaemon(t *testing.T) {
+	skipWindows(t)
+	parser, _ := parsers.NewInfluxParser()
+	e := NewExec()
+	e.parser = parser
+	e.Daemon = true
+	e.Commands = []string{
+		"sh -c 'echo cpu value=1; echo not line protocol; echo mem value=2; sleep 10'"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	require.True(t, waitMetrics(&acc, 2))
+
+	// Gather doesn't run the command again
+	require.NoError(t, e.Gather(&acc))
+
+	start := time.Now()
+	e.Stop()
+	assert.True(t, time.Since(start) < stopTimeout)
+
+	assert.Len(t, acc.Metrics, 2)
+	acc.AssertContainsFields(t, "cpu", map[string]interface{}{"value": float64(1)})
+	acc.AssertContainsFields(t, "mem", map[string]interface{}{"value": float64(2)})
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "parsing output")
+}
+
+func TestDaemonRestart(t *testing.T) {
+	skipWindows(t)
+	parser, _ := parsers.NewInfluxParser()
+	e := NewExec()
+	e.parser = parser
+	e.Daemon = true
+	e.RestartDelay = internal.Duration{Duration: 10 * time.Millisecond}
+	e.Commands = []string{"sh -c 'echo cpu value=1'"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	require.True(t, waitMetrics(&acc, 3))
+	e.Stop()
+
+	acc.Lock()
+	defer acc.Unlock()
+	require.NotEmpty(t, acc.Errors)
+	assert.True(t, strings.Contains(acc.Errors[0].Error(), "restarting it in 10ms"),
+		acc.Errors[0].Error())
+}
@@ -0,0 +1,22 @@
+// +build windows
+
+package exec
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where only the command itself can
+// be killed.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kills the command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// terminateProcessGroup kills the command, Windows can't ask it to exit.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
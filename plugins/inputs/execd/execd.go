@@ -0,0 +1,101 @@
+package execd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+const sampleConfig = `
+  ## The external plugin to run, and its arguments. It is started when
+  ## telegraf starts, and asked to gather on every interval.
+  command = ["/usr/bin/mycollector", "--config", "/etc/mycollector.conf"]
+
+  ## Environment variables added to the environment of the plugin.
+  # environment = ["LC_ALL=C"]
+
+  ## How long the plugin has to answer a gather.
+  timeout = "5s"
+
+  ## A plugin that exits is restarted after restart_delay, doubling up to 1m
+  ## while it keeps exiting.
+  restart_delay = "1s"
+`
+
+type Execd struct {
+	Command      []string
+	Environment  []string
+	Timeout      internal.Duration
+	RestartDelay internal.Duration
+
+	process *external.Process
+	parser  *influx.InfluxParser
+	logger  telegraf.Logger
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		RestartDelay: internal.Duration{Duration: time.Second},
+		parser:       &influx.InfluxParser{},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run an external input plugin, see the shim package to write one"
+}
+
+// SetLogger sets the logger the plugin logs with.
+func (e *Execd) SetLogger(logger telegraf.Logger) {
+	e.logger = logger
+}
+
+// Start starts the external plugin. The metrics it writes are added as they
+// arrive, whether they answer a gather or not.
+func (e *Execd) Start(acc telegraf.Accumulator) error {
+	e.process = &external.Process{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		Log:          e.logger,
+		Metric: func(line string) {
+			m, err := e.parser.ParseLine(line)
+			if err != nil {
+				acc.AddError(fmt.Errorf("execd: %s parsing metric '%s'", err, line))
+				return
+			}
+			acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		},
+	}
+	return e.process.Start()
+}
+
+// Stop stops the external plugin.
+func (e *Execd) Stop() {
+	if e.process != nil {
+		e.process.Stop()
+	}
+}
+
+// Gather asks the external plugin to gather, and waits for its answer.
+func (e *Execd) Gather(acc telegraf.Accumulator) error {
+	if e.process == nil {
+		return fmt.Errorf("execd: plugin was not started")
+	}
+	return e.process.Request(nil, external.Gather, e.Timeout.Duration)
+}
+
+func init() {
+	inputs.Add("execd", func() telegraf.Input {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,65 @@
+package execd
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plugin answers the first gather with a metric, and the next ones with an
+// error.
+const plugin = `
+n=0
+while read line id; do
+  if [ "$line" = "#gather" ]; then
+    n=$((n+1))
+    if [ $n -eq 1 ]; then
+      echo "cpu,cpu=cpu0 value=1"
+      echo "#ok $id"
+    else
+      echo "#error $id permission denied"
+    fi
+  fi
+done`
+
+func TestGather(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test that runs sh on Windows")
+	}
+	e := NewExecd()
+	e.Command = []string{"sh", "-c", plugin}
+	e.SetLogger(testutil.Logger{Name: "inputs.execd"})
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	defer e.Stop()
+
+	require.NoError(t, e.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value": float64(1)},
+		map[string]string{"cpu": "cpu0"})
+
+	assert.EqualError(t, e.Gather(&acc), "permission denied")
+}
+
+func TestGatherTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test that runs sh on Windows")
+	}
+	e := NewExecd()
+	e.Command = []string{"sh", "-c", "while read line; do :; done"}
+	e.Timeout = internal.Duration{Duration: 10 * time.Millisecond}
+	e.SetLogger(testutil.Logger{Name: "inputs.execd"})
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Start(&acc))
+	defer e.Stop()
+
+	assert.Error(t, e.Gather(&acc))
+}
@@ -1,6 +1,7 @@
 package kafka_consumer
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"sync"
@@ -28,6 +29,10 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	// MaxUndeliveredMessages is the most messages read whose metrics are not
+	// written yet
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
@@ -37,13 +42,33 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+
+	// commit commits the offset of the message, and of the ones before it in
+	// its partition. It is CommitUpto of the consumer group.
+	commit func(msg *sarama.ConsumerMessage) error
+
+	// undelivered are the messages read, by the id of their metrics, and
+	// pending the messages of each partition that are not committed yet, in
+	// order
+	undelivered map[telegraf.TrackingID]*pendingMessage
+	pending     map[topicPartition][]*pendingMessage
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
 
-	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
-	// this is mostly for test purposes, but there may be a use-case for it later.
-	doNotCommitMsgs bool
+// pendingMessage is a message waiting for its metrics, and the messages before
+// it in its partition, to be delivered before its offset is committed.
+type pendingMessage struct {
+	msg  *sarama.ConsumerMessage
+	done bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## topic(s) to consume
   topics = ["telegraf"]
@@ -56,6 +81,11 @@ var sampleConfig = `
   ## Offset (must be either "oldest" or "newest")
   offset = "oldest"
 
+  ## Maximum number of messages read whose metrics are not written yet. The
+  ## offset of a message is only committed once its metrics are written by
+  ## every output, so on a restart those messages are read again.
+  max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has it's own unique set of configuration options, read
   ## more about them here:
@@ -80,7 +110,12 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var consumerErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages <= 0 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
+	k.undelivered = make(map[telegraf.TrackingID]*pendingMessage)
+	k.pending = make(map[topicPartition][]*pendingMessage)
 
 	config := consumergroup.NewConfig()
 	config.Zookeeper.Chroot = k.ZookeeperChroot
@@ -110,6 +145,14 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 		k.in = k.Consumer.Messages()
 		k.errs = k.Consumer.Errors()
 	}
+	consumer := k.Consumer
+	k.commit = func(msg *sarama.ConsumerMessage) error {
+		// TODO(cam) this locking can be removed if this PR gets merged:
+		// https://github.com/wvanbergen/kafka/pull/84
+		k.Lock()
+		defer k.Unlock()
+		return consumer.CommitUpto(msg)
+	}
 
 	k.done = make(chan struct{})
 
@@ -121,36 +164,86 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. It stops reading while max_undelivered_messages
+// messages are waiting for their metrics to be written.
 func (k *Kafka) receiver() {
 	for {
+		in := k.in
+		if len(k.undelivered) >= k.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-k.done:
 			return
 		case err := <-k.errs:
-			log.Printf("Kafka Consumer Error: %s\n", err.Error())
-		case msg := <-k.in:
+			k.acc.AddError(fmt.Errorf("Kafka Consumer Error: %s", err.Error()))
+		case info := <-k.acc.Delivered():
+			k.onDelivery(info)
+		case msg := <-in:
 			metrics, err := k.parser.Parse(msg.Value)
 			if err != nil {
-				log.Printf("KAFKA PARSE ERROR\nmessage: %s\nerror: %s",
-					string(msg.Value), err.Error())
+				k.acc.AddError(fmt.Errorf("KAFKA PARSE ERROR\nmessage: %s\nerror: %s",
+					string(msg.Value), err.Error()))
 			}
 
-			for _, metric := range metrics {
-				k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-			}
-
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Consumer.CommitUpto(msg)
-				k.Unlock()
+			p := &pendingMessage{msg: msg}
+			tp := topicPartition{msg.Topic, msg.Partition}
+			k.pending[tp] = append(k.pending[tp], p)
+			if len(metrics) == 0 {
+				// nothing to wait for, ie, the message failed to parse
+				k.markDone(p)
+				continue
 			}
+			// a group whose metrics are all filtered out is delivered right
+			// away, but deliveries are only read by this loop, so it is in
+			// undelivered by the time it is handled
+			k.undelivered[k.acc.AddTrackingMetricGroup(metrics)] = p
 		}
 	}
 }
 
+// onDelivery marks the delivered message as done. A message whose metrics
+// were lost by an output is reported, and committed all the same.
+func (k *Kafka) onDelivery(info telegraf.DeliveryInfo) {
+	p, ok := k.undelivered[info.ID()]
+	if !ok {
+		return
+	}
+	delete(k.undelivered, info.ID())
+	if !info.Delivered() {
+		k.acc.AddError(fmt.Errorf("metrics of message at offset %d of topic %s "+
+			"partition %d were not written by every output",
+			p.msg.Offset, p.msg.Topic, p.msg.Partition))
+	}
+	k.markDone(p)
+}
+
+// markDone marks the message as done, and commits the offsets of the messages
+// of its partition up to the first one that is not done yet.
+func (k *Kafka) markDone(p *pendingMessage) {
+	p.done = true
+	tp := topicPartition{p.msg.Topic, p.msg.Partition}
+	pending := k.pending[tp]
+	n := 0
+	for n < len(pending) && pending[n].done {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	if err := k.commit(pending[n-1].msg); err != nil {
+		k.acc.AddError(fmt.Errorf("Kafka Consumer Error committing offset "+
+			"%d of topic %s partition %d: %s", pending[n-1].msg.Offset,
+			tp.topic, tp.partition, err))
+	}
+	if n == len(pending) {
+		delete(k.pending, tp)
+	} else {
+		k.pending[tp] = pending[n:]
+	}
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
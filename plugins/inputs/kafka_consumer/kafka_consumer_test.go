@@ -1,9 +1,11 @@
 package kafka_consumer
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -21,14 +23,17 @@ const (
 func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 	in := make(chan *sarama.ConsumerMessage, 1000)
 	k := Kafka{
-		ConsumerGroup:   "test",
-		Topics:          []string{"telegraf"},
-		ZookeeperPeers:  []string{"localhost:2181"},
-		Offset:          "oldest",
-		in:              in,
-		doNotCommitMsgs: true,
-		errs:            make(chan *sarama.ConsumerError, 1000),
-		done:            make(chan struct{}),
+		ConsumerGroup:          "test",
+		Topics:                 []string{"telegraf"},
+		ZookeeperPeers:         []string{"localhost:2181"},
+		Offset:                 "oldest",
+		MaxUndeliveredMessages: 10,
+		in:                     in,
+		commit:                 func(*sarama.ConsumerMessage) error { return nil },
+		undelivered:            make(map[telegraf.TrackingID]*pendingMessage),
+		pending:                make(map[topicPartition][]*pendingMessage),
+		errs:                   make(chan *sarama.ConsumerError, 1000),
+		done:                   make(chan struct{}),
 	}
 	return &k, in
 }
@@ -123,6 +128,143 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that the offset of a message is committed once its metrics are
+// delivered
+func TestRunParserCommitsDelivered(t *testing.T) {
+	k, in := newTestKafka()
+	acc := testutil.Accumulator{}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
+	defer close(k.done)
+
+	var mu sync.Mutex
+	var committed []int64
+	k.commit = func(msg *sarama.ConsumerMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		committed = append(committed, msg.Offset)
+		return nil
+	}
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	msg := saramaMsg(testMsg)
+	msg.Offset = 7
+	in <- msg
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := len(committed)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{7}, committed)
+	assert.Equal(t, acc.NFields(), 1)
+}
+
+// Test that the offsets of messages without metrics, because they failed to
+// parse or their metrics are all filtered out, are committed, and that they
+// don't stop the consumer once there are max_undelivered_messages of them.
+func TestRunParserCommitsEmpty(t *testing.T) {
+	k, in := newTestKafka()
+	k.MaxUndeliveredMessages = 2
+	acc := testutil.Accumulator{}
+	k.acc = filteringAccumulator{acc.WithTracking(k.MaxUndeliveredMessages)}
+	defer close(k.done)
+
+	var mu sync.Mutex
+	var committed []int64
+	k.commit = func(msg *sarama.ConsumerMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		committed = append(committed, msg.Offset)
+		return nil
+	}
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	for i := 0; i < 6; i++ {
+		msg := saramaMsg(testMsg)
+		if i%2 == 0 {
+			msg = saramaMsg(invalidMsg)
+		}
+		msg.Offset = int64(i)
+		in <- msg
+	}
+	// offsets are committed up to the last message done, which may skip some
+	last := int64(-1)
+	for i := 0; i < 1000 && last != 5; i++ {
+		mu.Lock()
+		if n := len(committed); n > 0 {
+			last = committed[n-1]
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, int64(5), last)
+	assert.Equal(t, 0, acc.NFields())
+	assert.Len(t, acc.Errors, 3)
+}
+
+// filteringAccumulator filters out every metric of the tracked groups, like
+// an input whose namepass matches none of them.
+type filteringAccumulator struct {
+	telegraf.TrackingAccumulator
+}
+
+func (a filteringAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	return a.TrackingAccumulator.AddTrackingMetricGroup(nil)
+}
+
+type testDelivery struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d testDelivery) ID() telegraf.TrackingID { return d.id }
+func (d testDelivery) Delivered() bool         { return d.delivered }
+
+// Test that offsets are only committed up to the first message of the
+// partition that is not delivered
+func TestOnDeliveryCommitsInOrder(t *testing.T) {
+	k, _ := newTestKafka()
+	acc := testutil.Accumulator{}
+	k.acc = &acc
+
+	var committed []int64
+	k.commit = func(msg *sarama.ConsumerMessage) error {
+		committed = append(committed, msg.Offset)
+		return nil
+	}
+
+	tp := topicPartition{"telegraf", 0}
+	for i := 0; i < 3; i++ {
+		msg := saramaMsg(testMsg)
+		msg.Topic = "telegraf"
+		msg.Offset = int64(i)
+		p := &pendingMessage{msg: msg}
+		k.pending[tp] = append(k.pending[tp], p)
+		k.undelivered[telegraf.TrackingID(i+1)] = p
+	}
+
+	k.onDelivery(testDelivery{id: 2, delivered: true})
+	assert.Empty(t, committed)
+	k.onDelivery(testDelivery{id: 1, delivered: true})
+	assert.Equal(t, []int64{1}, committed)
+	k.onDelivery(testDelivery{id: 3, delivered: false})
+	assert.Equal(t, []int64{1, 2}, committed)
+	assert.Len(t, acc.Errors, 1)
+	assert.Empty(t, k.pending)
+	assert.Empty(t, k.undelivered)
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
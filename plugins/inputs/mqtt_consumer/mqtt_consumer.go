@@ -27,6 +27,10 @@ type MQTTConsumer struct {
 	// Legacy metric buffer support
 	MetricBuffer int
 
+	// MaxUndeliveredMessages is the most messages read whose metrics are not
+	// written yet
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	PersistentSession bool
 	ClientID          string `toml:"client_id"`
 
@@ -46,11 +50,17 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+
+	// undelivered are the messages whose metrics are not written yet, which
+	// are acknowledged once they are
+	undelivered map[telegraf.TrackingID]mqtt.Message
 
 	started bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   servers = ["localhost:1883"]
   ## MQTT QoS, must be 0, 1, or 2
@@ -70,6 +80,10 @@ var sampleConfig = `
   # If empty, a random client ID will be generated.
   client_id = ""
 
+  ## Maximum number of messages read whose metrics are not written yet. Once
+  ## reached, no more messages are read until some are written.
+  max_undelivered_messages = 1000
+
   ## username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
@@ -110,7 +124,11 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages <= 0 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
+	m.undelivered = make(map[telegraf.TrackingID]mqtt.Message)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -120,14 +138,18 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	// messages are not buffered, and are received only while fewer than
+	// max_undelivered_messages are undelivered. The channels are made before
+	// connecting, as the client subscribes, and may receive messages, once
+	// it is connected.
+	m.in = make(chan mqtt.Message)
+	m.done = make(chan struct{})
+
 	m.client = mqtt.NewClient(opts)
 	if token := m.client.Connect(); token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
 
-	m.in = make(chan mqtt.Message, 1000)
-	m.done = make(chan struct{})
-
 	go m.receiver()
 
 	return nil
@@ -156,31 +178,63 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. It stops reading while max_undelivered_messages
+// messages are waiting for their metrics to be written.
 func (m *MQTTConsumer) receiver() {
 	for {
+		in := m.in
+		if len(m.undelivered) >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case info := <-m.acc.Delivered():
+			m.onDelivery(info)
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
-				log.Printf("MQTT PARSE ERROR\nmessage: %s\nerror: %s",
-					string(msg.Payload()), err.Error())
+				m.acc.AddError(fmt.Errorf("MQTT PARSE ERROR\nmessage: %s\nerror: %s",
+					string(msg.Payload()), err.Error()))
 			}
 
+			if len(metrics) == 0 {
+				// nothing to wait for, ie, the message failed to parse
+				msg.Ack()
+				continue
+			}
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			m.undelivered[m.acc.AddTrackingMetricGroup(metrics)] = msg
 		}
 	}
 }
 
+// onDelivery acknowledges the delivered message. A message whose metrics were
+// lost by an output is reported, and acknowledged all the same.
+func (m *MQTTConsumer) onDelivery(info telegraf.DeliveryInfo) {
+	msg, ok := m.undelivered[info.ID()]
+	if !ok {
+		return
+	}
+	delete(m.undelivered, info.ID())
+	if !info.Delivered() {
+		m.acc.AddError(fmt.Errorf("metrics of message %d of topic %s were not "+
+			"written by every output", msg.MessageID(), msg.Topic()))
+	}
+	msg.Ack()
+}
+
+// recvMessage hands the message over to the receiver, or drops it, without
+// acknowledging it, once the consumer is stopped.
 func (m *MQTTConsumer) recvMessage(_ mqtt.Client, msg mqtt.Message) {
-	m.in <- msg
+	select {
+	case m.in <- msg:
+	case <-m.done:
+	}
 }
 
 func (m *MQTTConsumer) Stop() {
@@ -236,6 +290,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 	opts.SetAutoReconnect(true)
 	opts.SetKeepAlive(time.Second * 60)
 	opts.SetCleanSession(!m.PersistentSession)
+	// messages are acknowledged once their metrics are written
+	opts.SetAutoAckDisabled(true)
 	opts.SetOnConnectHandler(m.onConnect)
 	opts.SetConnectionLostHandler(m.onConnectionLost)
 	return opts, nil
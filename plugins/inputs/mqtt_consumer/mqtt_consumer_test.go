@@ -1,9 +1,11 @@
 package mqtt_consumer
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -22,10 +24,12 @@ const (
 func newTestMQTTConsumer() (*MQTTConsumer, chan mqtt.Message) {
 	in := make(chan mqtt.Message, 100)
 	n := &MQTTConsumer{
-		Topics:  []string{"telegraf"},
-		Servers: []string{"localhost:1883"},
-		in:      in,
-		done:    make(chan struct{}),
+		Topics:                 []string{"telegraf"},
+		Servers:                []string{"localhost:1883"},
+		MaxUndeliveredMessages: 100,
+		in:                     in,
+		done:                   make(chan struct{}),
+		undelivered:            make(map[telegraf.TrackingID]mqtt.Message),
 	}
 	return n, in
 }
@@ -167,6 +171,51 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that messages are acknowledged once their metrics are delivered, or
+// right away if they have none.
+func TestRunParserAcksDelivered(t *testing.T) {
+	n, in := newTestMQTTConsumer()
+	acc := testutil.Accumulator{}
+	n.acc = acc.WithTracking(10)
+	defer close(n.done)
+
+	n.parser, _ = parsers.NewInfluxParser()
+	go n.receiver()
+	valid := &message{topic: "telegraf/unit_test", payload: []byte(testMsg)}
+	invalid := &message{topic: "telegraf/unit_test", payload: []byte(invalidMsg)}
+	in <- valid
+	in <- invalid
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&valid.acked) == 0 || atomic.LoadInt32(&invalid.acked) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("messages not acknowledged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&valid.acked))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&invalid.acked))
+}
+
+// Test that a message received once the consumer is stopped doesn't block
+// the client.
+func TestRecvMessageStopped(t *testing.T) {
+	n, _ := newTestMQTTConsumer()
+	n.in = make(chan mqtt.Message)
+	close(n.done)
+
+	received := make(chan struct{})
+	go func() {
+		n.recvMessage(nil, mqttMsg(testMsg))
+		close(received)
+	}()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("recvMessage blocked after Stop")
+	}
+}
+
 func mqttMsg(val string) mqtt.Message {
 	return &message{
 		topic:   "telegraf/unit_test",
@@ -183,6 +232,7 @@ type message struct {
 	topic     string
 	messageID uint16
 	payload   []byte
+	acked     int32
 }
 
 func (m *message) Duplicate() bool {
@@ -208,3 +258,7 @@ func (m *message) MessageID() uint16 {
 func (m *message) Payload() []byte {
 	return m.payload
 }
+
+func (m *message) Ack() {
+	atomic.AddInt32(&m.acked, 1)
+}
@@ -121,12 +121,10 @@ func (m *Mysql) Gather(acc telegraf.Accumulator) error {
 		m.InitMysql()
 	}
 
-	// Loop through each server and collect metrics
+	// Loop through each server and collect metrics, a failing server doesn't
+	// keep the remaining servers from being collected.
 	for _, serv := range m.Servers {
-		err := m.gatherServer(serv, acc)
-		if err != nil {
-			return err
-		}
+		acc.AddError(m.gatherServer(serv, acc))
 	}
 
 	return nil
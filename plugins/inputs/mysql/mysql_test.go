@@ -23,6 +23,7 @@ func TestMysqlDefaultsToLocal(t *testing.T) {
 
 	err := m.Gather(&acc)
 	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
 
 	assert.True(t, acc.HasMeasurement("mysql"))
 }
@@ -31,6 +31,10 @@ type natsConsumer struct {
 	// Legacy metric buffer support
 	MetricBuffer int
 
+	// MaxUndeliveredMessages is the most messages read whose metrics are not
+	// written yet
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 
 	sync.Mutex
@@ -42,9 +46,14 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
+
+	// undelivered is the number of messages whose metrics are not written
+	undelivered int
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## urls of NATS servers
   servers = ["nats://localhost:4222"]
@@ -55,6 +64,10 @@ var sampleConfig = `
   ## name a queue group
   queue_group = "telegraf_consumers"
 
+  ## Maximum number of messages read whose metrics are not written yet. Once
+  ## reached, no more messages are read until some are written.
+  max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has it's own unique set of configuration options, read
   ## more about them here:
@@ -87,7 +100,11 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages <= 0 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	n.undelivered = 0
 
 	var connectErr error
 
@@ -126,25 +143,32 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from NATS, and parses them into
-// telegraf metrics.
+// telegraf metrics. It stops reading while max_undelivered_messages messages
+// are waiting for their metrics to be written.
 func (n *natsConsumer) receiver() {
 	defer n.clean()
 	for {
+		in := n.in
+		if n.undelivered >= n.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-n.done:
 			return
 		case err := <-n.errs:
-			log.Printf("error reading from %s\n", err.Error())
-		case msg := <-n.in:
+			n.acc.AddError(fmt.Errorf("error reading from %s", err.Error()))
+		case <-n.acc.Delivered():
+			n.undelivered--
+		case msg := <-in:
 			metrics, err := n.parser.Parse(msg.Data)
 			if err != nil {
-				log.Printf("subject: %s, error: %s", msg.Subject, err.Error())
-			}
-
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+				n.acc.AddError(fmt.Errorf("subject: %s, error: %s",
+					msg.Subject, err.Error()))
 			}
 
+			n.acc.AddTrackingMetricGroup(metrics)
+			n.undelivered++
 		}
 	}
 }
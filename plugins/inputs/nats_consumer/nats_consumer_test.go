@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/nats-io/nats"
@@ -20,13 +21,14 @@ const (
 func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 	in := make(chan *nats.Msg, metricBuffer)
 	n := &natsConsumer{
-		QueueGroup: "test",
-		Subjects:   []string{"telegraf"},
-		Servers:    []string{"nats://localhost:4222"},
-		Secure:     false,
-		in:         in,
-		errs:       make(chan error, metricBuffer),
-		done:       make(chan struct{}),
+		QueueGroup:             "test",
+		Subjects:               []string{"telegraf"},
+		Servers:                []string{"nats://localhost:4222"},
+		Secure:                 false,
+		MaxUndeliveredMessages: metricBuffer,
+		in:                     in,
+		errs:                   make(chan error, metricBuffer),
+		done:                   make(chan struct{}),
 	}
 	return n, in
 }
@@ -122,6 +124,56 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// heldAccumulator only reports deliveries sent on its delivered channel
+type heldAccumulator struct {
+	*testutil.Accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *heldAccumulator) AddTrackingMetricGroup(
+	group []telegraf.Metric,
+) telegraf.TrackingID {
+	for _, m := range group {
+		a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	return 0
+}
+
+func (a *heldAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// Test that no more messages are read while max_undelivered_messages are not
+// delivered
+func TestRunParserMaxUndelivered(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.MaxUndeliveredMessages = 2
+	acc := &heldAccumulator{
+		Accumulator: &testutil.Accumulator{},
+		delivered:   make(chan telegraf.DeliveryInfo, 1),
+	}
+	n.acc = acc
+	defer close(n.done)
+
+	n.parser, _ = parsers.NewInfluxParser()
+	go n.receiver()
+	for i := 0; i < 3; i++ {
+		in <- natsMsg(testMsg)
+	}
+	time.Sleep(time.Millisecond * 25)
+	if acc.NFields() != 2 {
+		t.Errorf("got %v, expected %v", acc.NFields(), 2)
+	}
+
+	telegraf.NewTrackingMetricGroup(nil, func(info telegraf.DeliveryInfo) {
+		acc.delivered <- info
+	})
+	time.Sleep(time.Millisecond * 25)
+	if acc.NFields() != 3 {
+		t.Errorf("got %v, expected %v", acc.NFields(), 3)
+	}
+}
+
 func natsMsg(val string) *nats.Msg {
 	return &nats.Msg{
 		Subject: "telegraf",
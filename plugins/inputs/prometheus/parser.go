@@ -10,6 +10,7 @@ import (
 	"io"
 	"math"
 	"mime"
+	"time"
 
 	"github.com/influxdata/telegraf"
 
@@ -70,25 +71,36 @@ func (p *PrometheusParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 					}
 				*/
 				// reading fields
-				fields := make(map[string]interface{})
-				if mf.GetType() == dto.MetricType_SUMMARY {
+				var fields map[string]interface{}
+				var newMetric func(string, map[string]string,
+					map[string]interface{}, ...time.Time) (telegraf.Metric, error)
+				switch mf.GetType() {
+				case dto.MetricType_SUMMARY:
 					// summary metric
 					fields = makeQuantiles(m)
-					fields["count"] = float64(m.GetHistogram().GetSampleCount())
+					fields["count"] = float64(m.GetSummary().GetSampleCount())
 					fields["sum"] = float64(m.GetSummary().GetSampleSum())
-				} else if mf.GetType() == dto.MetricType_HISTOGRAM {
+					newMetric = telegraf.NewSummaryMetric
+				case dto.MetricType_HISTOGRAM:
 					// historgram metric
 					fields = makeBuckets(m)
 					fields["count"] = float64(m.GetHistogram().GetSampleCount())
-					fields["sum"] = float64(m.GetSummary().GetSampleSum())
-
-				} else {
+					fields["sum"] = float64(m.GetHistogram().GetSampleSum())
+					newMetric = telegraf.NewHistogramMetric
+				case dto.MetricType_COUNTER:
+					fields = getNameAndValue(m)
+					newMetric = telegraf.NewCounterMetric
+				case dto.MetricType_GAUGE:
+					fields = getNameAndValue(m)
+					newMetric = telegraf.NewGaugeMetric
+				default:
 					// standard metric
 					fields = getNameAndValue(m)
+					newMetric = telegraf.NewMetric
 				}
 				// converting to telegraf metric
 				if len(fields) > 0 {
-					metric, err := telegraf.NewMetric(metricName, tags, fields)
+					metric, err := newMetric(metricName, tags, fields)
 					if err == nil {
 						metrics = append(metrics, metric)
 					}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -108,6 +109,7 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "cadvisor_version_info", metrics[0].Name())
+	assert.Equal(t, telegraf.Gauge, metrics[0].Type())
 	assert.Equal(t, map[string]interface{}{
 		"gauge": float64(1),
 	}, metrics[0].Fields())
@@ -123,6 +125,7 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "get_token_fail_count", metrics[0].Name())
+	assert.Equal(t, telegraf.Counter, metrics[0].Type())
 	assert.Equal(t, map[string]interface{}{
 		"counter": float64(0),
 	}, metrics[0].Fields())
@@ -134,11 +137,12 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "http_request_duration_microseconds", metrics[0].Name())
+	assert.Equal(t, telegraf.Summary, metrics[0].Type())
 	assert.Equal(t, map[string]interface{}{
 		"0.5":   552048.506,
 		"0.9":   5.876804288e+06,
 		"0.99":  5.876804288e+06,
-		"count": 0.0,
+		"count": 9.0,
 		"sum":   1.8909097205e+07,
 	}, metrics[0].Fields())
 	assert.Equal(t, map[string]string{"handler": "prometheus"}, metrics[0].Tags())
@@ -148,10 +152,11 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, metrics, 1)
 	assert.Equal(t, "apiserver_request_latencies", metrics[0].Name())
+	assert.Equal(t, telegraf.Histogram, metrics[0].Type())
 	assert.Equal(t, map[string]interface{}{
 		"500000": 2000.0,
 		"count":  2025.0,
-		"sum":    0.0,
+		"sum":    1.02726334e+08,
 		"250000": 1997.0,
 		"2e+06":  2012.0,
 		"4e+06":  2017.0,
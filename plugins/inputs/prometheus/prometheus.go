@@ -43,23 +43,22 @@ func (p *Prometheus) Description() string {
 var ErrProtocolError = errors.New("prometheus protocol error")
 
 // Reads stats from all configured servers accumulates stats.
-// Returns one of the errors encountered while gather stats (if any).
+// Errors encountered while gathering from a server are added to the
+// accumulator.
 func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
 
-	var outerr error
-
 	for _, serv := range p.Urls {
 		wg.Add(1)
 		go func(serv string) {
 			defer wg.Done()
-			outerr = p.gatherURL(serv, acc)
+			acc.AddError(p.gatherURL(serv, acc))
 		}(serv)
 	}
 
 	wg.Wait()
 
-	return outerr
+	return nil
 }
 
 var tr = &http.Transport{
@@ -130,9 +129,20 @@ func (p *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
 	}
 	// Add (or not) collected metrics
 	for _, metric := range metrics {
+		metric.AddTag("url", url)
 		tags := metric.Tags()
-		tags["url"] = url
-		acc.AddFields(metric.Name(), metric.Fields(), tags, collectDate)
+		switch metric.Type() {
+		case telegraf.Counter:
+			acc.AddCounter(metric.Name(), metric.Fields(), tags, collectDate)
+		case telegraf.Gauge:
+			acc.AddGauge(metric.Name(), metric.Fields(), tags, collectDate)
+		case telegraf.Summary:
+			acc.AddSummary(metric.Name(), metric.Fields(), tags, collectDate)
+		case telegraf.Histogram:
+			acc.AddHistogram(metric.Name(), metric.Fields(), tags, collectDate)
+		default:
+			acc.AddFields(metric.Name(), metric.Fields(), tags, collectDate)
+		}
 	}
 
 	return nil
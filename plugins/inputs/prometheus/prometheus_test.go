@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,7 +40,32 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 
 	err := p.Gather(&acc)
 	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
 
 	assert.True(t, acc.HasFloatField("go_gc_duration_seconds", "count"))
 	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+
+	summary, ok := acc.Get("go_gc_duration_seconds")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Summary, summary.Type)
+	gauge, ok := acc.Get("go_goroutines")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Gauge, gauge.Type)
+}
+
+func TestPrometheusReportsEveryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Urls: []string{ts.URL + "/a", ts.URL + "/b"},
+	}
+
+	var acc testutil.Accumulator
+
+	err := p.Gather(&acc)
+	require.NoError(t, err)
+	assert.Len(t, acc.Errors, 2)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/stats"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -27,8 +28,8 @@ const (
 	defaultSeparator = "_"
 )
 
-var dropwarn = "ERROR: Message queue full. Discarding line [%s] " +
-	"You may want to increase allowed_pending_messages in the config\n"
+var dropwarn = "Message queue full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_messages in the config"
 
 var prevInstance *Statsd
 
@@ -64,12 +65,21 @@ type Statsd struct {
 	UDPPacketSize int `toml:"udp_packet_size"`
 
 	sync.Mutex
-	wg sync.WaitGroup
+	// wg is for the listener, parsing for the parser, which only stops once
+	// the packets received before Stop are parsed
+	wg      sync.WaitGroup
+	parsing sync.WaitGroup
 
 	// Channel for all incoming statsd packets
 	in   chan []byte
 	done chan struct{}
 
+	// acc is the accumulator the service was started with, which the data
+	// received is reported to when it stops
+	acc telegraf.Accumulator
+
+	logger telegraf.Logger
+
 	// Cache gauges, counters & sets so they can be aggregated as they arrive
 	// gauges and counters map measurement/tags hash -> field name -> metrics
 	// sets and timings map measurement/tags hash -> metrics
@@ -118,7 +128,7 @@ type cachedcounter struct {
 
 type cachedtimings struct {
 	name   string
-	fields map[string]RunningStats
+	fields map[string]stats.RunningStats
 	tags   map[string]string
 }
 
@@ -200,14 +210,14 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 	}
 
 	for _, metric := range s.gauges {
-		acc.AddFields(metric.name, metric.fields, metric.tags, now)
+		acc.AddGauge(metric.name, metric.fields, metric.tags, now)
 	}
 	if s.DeleteGauges {
 		s.gauges = make(map[string]cachedgauge)
 	}
 
 	for _, metric := range s.counters {
-		acc.AddFields(metric.name, metric.fields, metric.tags, now)
+		acc.AddCounter(metric.name, metric.fields, metric.tags, now)
 	}
 	if s.DeleteCounters {
 		s.counters = make(map[string]cachedcounter)
@@ -218,7 +228,7 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 		for field, set := range metric.fields {
 			fields[field] = int64(len(set))
 		}
-		acc.AddFields(metric.name, fields, metric.tags, now)
+		acc.AddGauge(metric.name, fields, metric.tags, now)
 	}
 	if s.DeleteSets {
 		s.sets = make(map[string]cachedset)
@@ -227,7 +237,9 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func (s *Statsd) Start(_ telegraf.Accumulator) error {
+func (s *Statsd) Start(acc telegraf.Accumulator) error {
+	s.acc = acc
+
 	// Make data structures
 	s.done = make(chan struct{})
 	s.in = make(chan []byte, s.AllowedPendingMessages)
@@ -245,7 +257,7 @@ func (s *Statsd) Start(_ telegraf.Accumulator) error {
 	}
 
 	if s.ConvertNames {
-		log.Printf("WARNING statsd: convert_names config option is deprecated," +
+		s.logger.Warnf("convert_names config option is deprecated," +
 			" please use metric_separator instead")
 	}
 
@@ -253,12 +265,13 @@ func (s *Statsd) Start(_ telegraf.Accumulator) error {
 		s.MetricSeparator = defaultSeparator
 	}
 
-	s.wg.Add(2)
 	// Start the UDP listener
+	s.wg.Add(1)
 	go s.udpListen()
 	// Start the line parser
+	s.parsing.Add(1)
 	go s.parser()
-	log.Printf("Started the statsd service on %s\n", s.ServiceAddress)
+	s.logger.Infof("Started the statsd service on %s", s.ServiceAddress)
 	prevInstance = s
 	return nil
 }
@@ -270,9 +283,9 @@ func (s *Statsd) udpListen() error {
 	address, _ := net.ResolveUDPAddr("udp", s.ServiceAddress)
 	s.listener, err = net.ListenUDP("udp", address)
 	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+		log.Fatalf("E! [inputs.statsd] ListenUDP - %s", err)
 	}
-	log.Println("Statsd listener listening on: ", s.listener.LocalAddr().String())
+	s.logger.Infof("Statsd listener listening on: %s", s.listener.LocalAddr())
 
 	buf := make([]byte, UDP_MAX_PACKET_SIZE)
 	for {
@@ -282,7 +295,7 @@ func (s *Statsd) udpListen() error {
 		default:
 			n, _, err := s.listener.ReadFromUDP(buf)
 			if err != nil && !strings.Contains(err.Error(), "closed network") {
-				log.Printf("ERROR READ: %s\n", err.Error())
+				s.logger.Errorf("Read: %s", err)
 				continue
 			}
 			bufCopy := make([]byte, n)
@@ -291,7 +304,7 @@ func (s *Statsd) udpListen() error {
 			select {
 			case s.in <- bufCopy:
 			default:
-				log.Printf(dropwarn, string(buf[:n]))
+				s.logger.Warnf(dropwarn, string(buf[:n]))
 			}
 		}
 	}
@@ -299,24 +312,20 @@ func (s *Statsd) udpListen() error {
 
 // parser monitors the s.in channel, if there is a packet ready, it parses the
 // packet into statsd strings and then calls parseStatsdLine, which parses a
-// single statsd metric into a struct.
+// single statsd metric into a struct. It returns once s.in is closed and
+// every packet in it is parsed.
 func (s *Statsd) parser() error {
-	defer s.wg.Done()
-	var packet []byte
-	for {
-		select {
-		case <-s.done:
-			return nil
-		case packet = <-s.in:
-			lines := strings.Split(string(packet), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line != "" {
-					s.parseStatsdLine(line)
-				}
+	defer s.parsing.Done()
+	for packet := range s.in {
+		lines := strings.Split(string(packet), "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				s.parseStatsdLine(line)
 			}
 		}
 	}
+	return nil
 }
 
 // parseStatsdLine will parse the given statsd line, validating it as it goes.
@@ -365,7 +374,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 	// Validate splitting the line on ":"
 	bits := strings.Split(line, ":")
 	if len(bits) < 2 {
-		log.Printf("Error: splitting ':', Unable to parse metric: %s\n", line)
+		s.logger.Errorf("Splitting ':', Unable to parse metric: %s", line)
 		return errors.New("Error Parsing statsd line")
 	}
 
@@ -381,22 +390,22 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		// Validate splitting the bit on "|"
 		pipesplit := strings.Split(bit, "|")
 		if len(pipesplit) < 2 {
-			log.Printf("Error: splitting '|', Unable to parse metric: %s\n", line)
+			s.logger.Errorf("Splitting '|', Unable to parse metric: %s", line)
 			return errors.New("Error Parsing statsd line")
 		} else if len(pipesplit) > 2 {
 			sr := pipesplit[2]
-			errmsg := "Error: parsing sample rate, %s, it must be in format like: " +
-				"@0.1, @0.5, etc. Ignoring sample rate for line: %s\n"
+			errmsg := "Parsing sample rate, %s, it must be in format like: " +
+				"@0.1, @0.5, etc. Ignoring sample rate for line: %s"
 			if strings.Contains(sr, "@") && len(sr) > 1 {
 				samplerate, err := strconv.ParseFloat(sr[1:], 64)
 				if err != nil {
-					log.Printf(errmsg, err.Error(), line)
+					s.logger.Errorf(errmsg, err.Error(), line)
 				} else {
 					// sample rate successfully parsed
 					m.samplerate = samplerate
 				}
 			} else {
-				log.Printf(errmsg, "", line)
+				s.logger.Errorf(errmsg, "", line)
 			}
 		}
 
@@ -405,14 +414,14 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "c", "s", "ms", "h":
 			m.mtype = pipesplit[1]
 		default:
-			log.Printf("Error: Statsd Metric type %s unsupported", pipesplit[1])
+			s.logger.Errorf("Statsd Metric type %s unsupported", pipesplit[1])
 			return errors.New("Error Parsing statsd line")
 		}
 
 		// Parse the value
 		if strings.ContainsAny(pipesplit[0], "-+") {
 			if m.mtype != "g" {
-				log.Printf("Error: +- values are only supported for gauges: %s\n", line)
+				s.logger.Errorf("+- values are only supported for gauges: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.additive = true
@@ -422,7 +431,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "ms", "h":
 			v, err := strconv.ParseFloat(pipesplit[0], 64)
 			if err != nil {
-				log.Printf("Error: parsing value to float64: %s\n", line)
+				s.logger.Errorf("Parsing value to float64: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.floatvalue = v
@@ -432,7 +441,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 			if err != nil {
 				v2, err2 := strconv.ParseFloat(pipesplit[0], 64)
 				if err2 != nil {
-					log.Printf("Error: parsing value to int64: %s\n", line)
+					s.logger.Errorf("Parsing value to int64: %s", line)
 					return errors.New("Error Parsing statsd line")
 				}
 				v = int64(v2)
@@ -543,7 +552,7 @@ func (s *Statsd) aggregate(m metric) {
 		if !ok {
 			cached = cachedtimings{
 				name:   m.name,
-				fields: make(map[string]RunningStats),
+				fields: make(map[string]stats.RunningStats),
 				tags:   m.tags,
 			}
 		}
@@ -551,7 +560,7 @@ func (s *Statsd) aggregate(m metric) {
 		// this will be the default field name, eg. "value"
 		field, ok := cached.fields[m.field]
 		if !ok {
-			field = RunningStats{
+			field = stats.RunningStats{
 				PercLimit: s.PercentileLimit,
 			}
 		}
@@ -621,14 +630,24 @@ func (s *Statsd) aggregate(m metric) {
 	}
 }
 
+// Stop stops listening and, once the packets already received are parsed,
+// reports the stats a last time, so that no data accepted is lost on shutdown.
 func (s *Statsd) Stop() {
-	s.Lock()
-	defer s.Unlock()
-	log.Println("Stopping the statsd service")
+	s.logger.Infof("Stopping the statsd service")
 	close(s.done)
 	s.listener.Close()
 	s.wg.Wait()
 	close(s.in)
+	s.parsing.Wait()
+
+	if s.acc != nil {
+		s.Gather(s.acc)
+	}
+}
+
+// SetLogger sets the logger the plugin logs with.
+func (s *Statsd) SetLogger(logger telegraf.Logger) {
+	s.logger = logger
 }
 
 func init() {
@@ -3,8 +3,10 @@ package statsd
 import (
 	"errors"
 	"fmt"
+	"net"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -20,6 +22,7 @@ func NewTestStatsd() *Statsd {
 	s.timings = make(map[string]cachedtimings)
 
 	s.MetricSeparator = "_"
+	s.logger = testutil.Logger{Name: "inputs.statsd"}
 
 	return &s
 }
@@ -655,12 +658,13 @@ func TestParse_MeasurementsWithMultipleValues(t *testing.T) {
 		// A 0 with invalid samplerate will add a single 0,
 		// plus the last bit of value 1
 		// which adds up to 12 individual datapoints to be cached
-		if cachedtiming.fields[defaultFieldName].n != 12 {
-			t.Errorf("Expected 11 additions, got %d", cachedtiming.fields[defaultFieldName].n)
+		field := cachedtiming.fields[defaultFieldName]
+		if field.Count() != 12 {
+			t.Errorf("Expected 11 additions, got %d", field.Count())
 		}
 
-		if cachedtiming.fields[defaultFieldName].upper != 1 {
-			t.Errorf("Expected max input to be 1, got %f", cachedtiming.fields[defaultFieldName].upper)
+		if field.Upper() != 1 {
+			t.Errorf("Expected max input to be 1, got %f", field.Upper())
 		}
 	}
 
@@ -902,6 +906,45 @@ func TestParse_Counters(t *testing.T) {
 	}
 }
 
+// Tests that counters, gauges and sets are gathered with their metric type
+func TestParse_MetricTypes(t *testing.T) {
+	s := NewTestStatsd()
+	acc := &testutil.Accumulator{}
+
+	valid_lines := []string{
+		"test.counter:1|c",
+		"test.gauge:1|g",
+		"test.set:100|s",
+		"test.timing:1|ms",
+	}
+
+	for _, line := range valid_lines {
+		err := s.parseStatsdLine(line)
+		if err != nil {
+			t.Errorf("Parsing line %s should not have resulted in an error\n", line)
+		}
+	}
+
+	s.Gather(acc)
+
+	types := map[string]telegraf.ValueType{
+		"test_counter": telegraf.Counter,
+		"test_gauge":   telegraf.Gauge,
+		"test_set":     telegraf.Gauge,
+		"test_timing":  telegraf.Untyped,
+	}
+	for name, mType := range types {
+		m, ok := acc.Get(name)
+		if !ok {
+			t.Errorf("Measurement %s was not gathered", name)
+			continue
+		}
+		if m.Type != mType {
+			t.Errorf("Measurement %s has type %s, expected %s", name, m.Type, mType)
+		}
+	}
+}
+
 // Tests low-level functionality of timings
 func TestParse_Timings(t *testing.T) {
 	s := NewTestStatsd()
@@ -938,6 +981,28 @@ func TestParse_Timings(t *testing.T) {
 	acc.AssertContainsFields(t, "test_timing", valid)
 }
 
+// Packets received before Stop are parsed and reported when it stops
+func TestStop_ReportsPendingPackets(t *testing.T) {
+	s := NewTestStatsd()
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = listener
+	acc := &testutil.Accumulator{}
+	s.acc = acc
+
+	s.in = make(chan []byte, 2)
+	s.in <- []byte("pending.counter:1|c\npending.counter:2|c")
+	s.in <- []byte("pending.counter:3|c")
+	s.parsing.Add(1)
+	go s.parser()
+	s.Stop()
+
+	acc.AssertContainsFields(t, "pending_counter",
+		map[string]interface{}{"value": int64(6)})
+}
+
 // Tests low-level functionality of timings when multiple fields is enabled
 // and a measurement template has been defined which can parse field names
 func TestParse_Timings_MultipleFieldsWithTemplate(t *testing.T) {
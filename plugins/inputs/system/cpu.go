@@ -55,7 +55,7 @@ func (s *CPUStats) Gather(acc telegraf.Accumulator) error {
 		total := totalCpuTime(cts)
 
 		// Add cpu time metrics
-		fields := map[string]interface{}{
+		fieldsC := map[string]interface{}{
 			"time_user":       cts.User,
 			"time_system":     cts.System,
 			"time_idle":       cts.Idle,
@@ -67,10 +67,10 @@ func (s *CPUStats) Gather(acc telegraf.Accumulator) error {
 			"time_guest":      cts.Guest,
 			"time_guest_nice": cts.GuestNice,
 		}
+		acc.AddCounter("cpu", fieldsC, tags, now)
 
 		// Add in percentage
 		if len(s.lastStats) == 0 {
-			acc.AddFields("cpu", fields, tags, now)
 			// If it's the 1st gather, can't get CPU Usage stats yet
 			continue
 		}
@@ -87,17 +87,18 @@ func (s *CPUStats) Gather(acc telegraf.Accumulator) error {
 			continue
 		}
 
-		fields["usage_user"] = 100 * (cts.User - lastCts.User) / totalDelta
-		fields["usage_system"] = 100 * (cts.System - lastCts.System) / totalDelta
-		fields["usage_idle"] = 100 * (cts.Idle - lastCts.Idle) / totalDelta
-		fields["usage_nice"] = 100 * (cts.Nice - lastCts.Nice) / totalDelta
-		fields["usage_iowait"] = 100 * (cts.Iowait - lastCts.Iowait) / totalDelta
-		fields["usage_irq"] = 100 * (cts.Irq - lastCts.Irq) / totalDelta
-		fields["usage_softirq"] = 100 * (cts.Softirq - lastCts.Softirq) / totalDelta
-		fields["usage_steal"] = 100 * (cts.Steal - lastCts.Steal) / totalDelta
-		fields["usage_guest"] = 100 * (cts.Guest - lastCts.Guest) / totalDelta
-		fields["usage_guest_nice"] = 100 * (cts.GuestNice - lastCts.GuestNice) / totalDelta
-		acc.AddFields("cpu", fields, tags, now)
+		fieldsG := map[string]interface{}{}
+		fieldsG["usage_user"] = 100 * (cts.User - lastCts.User) / totalDelta
+		fieldsG["usage_system"] = 100 * (cts.System - lastCts.System) / totalDelta
+		fieldsG["usage_idle"] = 100 * (cts.Idle - lastCts.Idle) / totalDelta
+		fieldsG["usage_nice"] = 100 * (cts.Nice - lastCts.Nice) / totalDelta
+		fieldsG["usage_iowait"] = 100 * (cts.Iowait - lastCts.Iowait) / totalDelta
+		fieldsG["usage_irq"] = 100 * (cts.Irq - lastCts.Irq) / totalDelta
+		fieldsG["usage_softirq"] = 100 * (cts.Softirq - lastCts.Softirq) / totalDelta
+		fieldsG["usage_steal"] = 100 * (cts.Steal - lastCts.Steal) / totalDelta
+		fieldsG["usage_guest"] = 100 * (cts.Guest - lastCts.Guest) / totalDelta
+		fieldsG["usage_guest_nice"] = 100 * (cts.GuestNice - lastCts.GuestNice) / totalDelta
+		acc.AddGauge("cpu", fieldsG, tags, now)
 	}
 
 	s.lastStats = times
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/stretchr/testify/assert"
@@ -96,6 +97,15 @@ func TestCPUStats(t *testing.T) {
 	assertContainsTaggedFloat(t, &acc, "cpu", "usage_steal", 0.2301, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "usage_guest", 4.8, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "usage_guest_nice", 2.2, 0.0005, cputags)
+
+	for _, m := range acc.Metrics {
+		if _, ok := m.Fields["time_user"]; ok {
+			assert.Equal(t, telegraf.Counter, m.Type)
+		}
+		if _, ok := m.Fields["usage_user"]; ok {
+			assert.Equal(t, telegraf.Gauge, m.Type)
+		}
+	}
 }
 
 // Asserts that a given accumulator contains a measurment of type float64 with
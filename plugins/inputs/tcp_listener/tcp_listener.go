@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 type TcpListener struct {
@@ -37,10 +38,14 @@ type TcpListener struct {
 
 	parser parsers.Parser
 	acc    telegraf.Accumulator
+	logger telegraf.Logger
+
+	// dropped counts the lines dropped because the queue was full
+	dropped selfstat.Stat
 }
 
-var dropwarn = "ERROR: Message queue full. Discarding metric [%s], " +
-	"You may want to increase allowed_pending_messages in the config\n"
+var dropwarn = "Message queue full. Discarding metric [%s], " +
+	"You may want to increase allowed_pending_messages in the config"
 
 const sampleConfig = `
   ## Address and port to host TCP listener on
@@ -78,12 +83,19 @@ func (t *TcpListener) SetParser(parser parsers.Parser) {
 	t.parser = parser
 }
 
+// SetLogger sets the logger the plugin logs with.
+func (t *TcpListener) SetLogger(logger telegraf.Logger) {
+	t.logger = logger
+}
+
 // Start starts the tcp listener service.
 func (t *TcpListener) Start(acc telegraf.Accumulator) error {
 	t.Lock()
 	defer t.Unlock()
 
 	t.acc = acc
+	t.dropped = selfstat.Register("tcp_listener", "lines_dropped",
+		map[string]string{"address": t.ServiceAddress})
 	t.in = make(chan []byte, t.AllowedPendingMessages)
 	t.done = make(chan struct{})
 	t.accept = make(chan bool, t.MaxTCPConnections)
@@ -97,16 +109,16 @@ func (t *TcpListener) Start(acc telegraf.Accumulator) error {
 	address, _ := net.ResolveTCPAddr("tcp", t.ServiceAddress)
 	t.listener, err = net.ListenTCP("tcp", address)
 	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+		log.Fatalf("E! [inputs.tcp_listener] ListenTCP - %s", err)
 		return err
 	}
-	log.Println("TCP server listening on: ", t.listener.Addr().String())
+	t.logger.Infof("TCP server listening on: %s", t.listener.Addr())
 
 	t.wg.Add(2)
 	go t.tcpListen()
 	go t.tcpParser()
 
-	log.Printf("Started TCP listener service on %s\n", t.ServiceAddress)
+	t.logger.Infof("Started TCP listener service on %s", t.ServiceAddress)
 	return nil
 }
 
@@ -133,7 +145,7 @@ func (t *TcpListener) Stop() {
 
 	t.wg.Wait()
 	close(t.in)
-	log.Println("Stopped TCP listener service on ", t.ServiceAddress)
+	t.logger.Infof("Stopped TCP listener service on %s", t.ServiceAddress)
 }
 
 // tcpListen listens for incoming TCP connections.
@@ -151,7 +163,7 @@ func (t *TcpListener) tcpListen() error {
 				return err
 			}
 
-			log.Printf("Received TCP Connection from %s", conn.RemoteAddr())
+			t.logger.Debugf("Received TCP Connection from %s", conn.RemoteAddr())
 
 			select {
 			case <-t.accept:
@@ -176,9 +188,9 @@ func (t *TcpListener) refuser(conn *net.TCPConn) {
 		" reached, closing.\nYou may want to increase max_tcp_connections in"+
 		" the Telegraf tcp listener configuration.\n", t.MaxTCPConnections)
 	conn.Close()
-	log.Printf("Refused TCP Connection from %s", conn.RemoteAddr())
-	log.Printf("WARNING: Maximum TCP Connections reached, you may want to" +
-		" adjust max_tcp_connections")
+	t.logger.Warnf("Refused TCP Connection from %s, maximum TCP "+
+		"Connections reached, you may want to adjust max_tcp_connections",
+		conn.RemoteAddr())
 }
 
 // handler handles a single TCP Connection
@@ -187,7 +199,7 @@ func (t *TcpListener) handler(conn *net.TCPConn, id string) {
 	defer func() {
 		t.wg.Done()
 		conn.Close()
-		log.Printf("Closed TCP Connection from %s", conn.RemoteAddr())
+		t.logger.Debugf("Closed TCP Connection from %s", conn.RemoteAddr())
 		// Add one connection potential back to channel when this one closes
 		t.accept <- true
 		t.forget(id)
@@ -213,7 +225,8 @@ func (t *TcpListener) handler(conn *net.TCPConn, id string) {
 			select {
 			case t.in <- bufCopy:
 			default:
-				log.Printf(dropwarn, scanner.Text())
+				t.dropped.Incr(1)
+				t.logger.Warnf(dropwarn, scanner.Text())
 			}
 		}
 	}
@@ -235,7 +248,7 @@ func (t *TcpListener) tcpParser() error {
 			if err == nil {
 				t.storeMetrics(metrics)
 			} else {
-				log.Printf("Malformed packet: [%s], Error: %s\n",
+				t.logger.Errorf("Malformed packet: [%s], Error: %s",
 					string(packet), err)
 			}
 		}
@@ -33,6 +33,7 @@ func newTestTcpListener() (*TcpListener, chan []byte) {
 		MaxTCPConnections:      250,
 		in:                     in,
 		done:                   make(chan struct{}),
+		logger:                 testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	return listener, in
 }
@@ -42,6 +43,7 @@ func TestConnectTCP(t *testing.T) {
 		ServiceAddress:         ":8194",
 		AllowedPendingMessages: 10000,
 		MaxTCPConnections:      250,
+		logger:                 testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -80,6 +82,7 @@ func TestConcurrentConns(t *testing.T) {
 		ServiceAddress:         ":8195",
 		AllowedPendingMessages: 10000,
 		MaxTCPConnections:      2,
+		logger:                 testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -118,6 +121,7 @@ func TestConcurrentConns1(t *testing.T) {
 		ServiceAddress:         ":8196",
 		AllowedPendingMessages: 10000,
 		MaxTCPConnections:      1,
+		logger:                 testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -154,6 +158,7 @@ func TestCloseConcurrentConns(t *testing.T) {
 		ServiceAddress:         ":8195",
 		AllowedPendingMessages: 10000,
 		MaxTCPConnections:      2,
+		logger:                 testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
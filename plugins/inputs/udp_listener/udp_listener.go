@@ -9,6 +9,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 type UdpListener struct {
@@ -31,15 +32,20 @@ type UdpListener struct {
 	// Keep the accumulator in this struct
 	acc telegraf.Accumulator
 
+	logger telegraf.Logger
+
 	listener *net.UDPConn
+
+	// dropped counts the packets dropped because the queue was full
+	dropped selfstat.Stat
 }
 
 // UDP packet limit, see
 // https://en.wikipedia.org/wiki/User_Datagram_Protocol#Packet_structure
 const UDP_MAX_PACKET_SIZE int = 64 * 1024
 
-var dropwarn = "ERROR: Message queue full. Discarding line [%s] " +
-	"You may want to increase allowed_pending_messages in the config\n"
+var dropwarn = "Message queue full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_messages in the config"
 
 const sampleConfig = `
   ## Address and port to host UDP listener on
@@ -74,11 +80,18 @@ func (u *UdpListener) SetParser(parser parsers.Parser) {
 	u.parser = parser
 }
 
+// SetLogger sets the logger the plugin logs with.
+func (u *UdpListener) SetLogger(logger telegraf.Logger) {
+	u.logger = logger
+}
+
 func (u *UdpListener) Start(acc telegraf.Accumulator) error {
 	u.Lock()
 	defer u.Unlock()
 
 	u.acc = acc
+	u.dropped = selfstat.Register("udp_listener", "packets_dropped",
+		map[string]string{"address": u.ServiceAddress})
 	u.in = make(chan []byte, u.AllowedPendingMessages)
 	u.done = make(chan struct{})
 
@@ -86,7 +99,7 @@ func (u *UdpListener) Start(acc telegraf.Accumulator) error {
 	go u.udpListen()
 	go u.udpParser()
 
-	log.Printf("Started UDP listener service on %s\n", u.ServiceAddress)
+	u.logger.Infof("Started UDP listener service on %s", u.ServiceAddress)
 	return nil
 }
 
@@ -95,7 +108,7 @@ func (u *UdpListener) Stop() {
 	u.listener.Close()
 	u.wg.Wait()
 	close(u.in)
-	log.Println("Stopped UDP listener service on ", u.ServiceAddress)
+	u.logger.Infof("Stopped UDP listener service on %s", u.ServiceAddress)
 }
 
 func (u *UdpListener) udpListen() error {
@@ -104,9 +117,9 @@ func (u *UdpListener) udpListen() error {
 	address, _ := net.ResolveUDPAddr("udp", u.ServiceAddress)
 	u.listener, err = net.ListenUDP("udp", address)
 	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+		log.Fatalf("E! [inputs.udp_listener] ListenUDP - %s", err)
 	}
-	log.Println("UDP server listening on: ", u.listener.LocalAddr().String())
+	u.logger.Infof("UDP server listening on: %s", u.listener.LocalAddr())
 
 	buf := make([]byte, UDP_MAX_PACKET_SIZE)
 	for {
@@ -116,7 +129,7 @@ func (u *UdpListener) udpListen() error {
 		default:
 			n, _, err := u.listener.ReadFromUDP(buf)
 			if err != nil && !strings.Contains(err.Error(), "closed network") {
-				log.Printf("ERROR: %s\n", err.Error())
+				u.logger.Errorf("%s", err)
 				continue
 			}
 			bufCopy := make([]byte, n)
@@ -125,7 +138,8 @@ func (u *UdpListener) udpListen() error {
 			select {
 			case u.in <- bufCopy:
 			default:
-				log.Printf(dropwarn, string(bufCopy))
+				u.dropped.Incr(1)
+				u.logger.Warnf(dropwarn, string(bufCopy))
 			}
 		}
 	}
@@ -144,7 +158,7 @@ func (u *UdpListener) udpParser() error {
 			if err == nil {
 				u.storeMetrics(metrics)
 			} else {
-				log.Printf("Malformed packet: [%s], Error: %s\n", packet, err)
+				u.logger.Errorf("Malformed packet: [%s], Error: %s", packet, err)
 			}
 		}
 	}
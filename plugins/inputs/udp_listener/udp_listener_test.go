@@ -16,8 +16,9 @@ func newTestUdpListener() (*UdpListener, chan []byte) {
 		ServiceAddress:         ":8125",
 		UDPPacketSize:          1500,
 		AllowedPendingMessages: 10000,
-		in:   in,
-		done: make(chan struct{}),
+		in:                     in,
+		done:                   make(chan struct{}),
+		logger:                 testutil.Logger{Name: "inputs.udp_listener"},
 	}
 	return listener, in
 }
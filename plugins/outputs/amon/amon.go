@@ -136,6 +136,8 @@ func (p *Point) setValue(v interface{}) error {
 		p[1] = float64(int32(d))
 	case int64:
 		p[1] = float64(int64(d))
+	case uint64:
+		p[1] = float64(d)
 	case float32:
 		p[1] = float64(d)
 	case float64:
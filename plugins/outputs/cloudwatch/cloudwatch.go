@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
@@ -22,6 +23,8 @@ type CloudWatch struct {
 	Region    string // AWS Region
 	Namespace string // CloudWatch Metrics Namespace
 	svc       *cloudwatch.CloudWatch
+
+	counters internal.CounterDeltas
 }
 
 var sampleConfig = `
@@ -88,6 +91,9 @@ func (c *CloudWatch) Write(metrics []telegraf.Metric) error {
 // request can have so we process one Point at a time.
 func (c *CloudWatch) WriteSinglePoint(point telegraf.Metric) error {
 	datums := BuildMetricDatum(point)
+	if point.Type() == telegraf.Counter {
+		datums = c.counterDeltas(datums)
+	}
 
 	const maxDatumsPerCall = 20 // PutMetricData only supports up to 20 data metrics per call
 
@@ -157,6 +163,8 @@ func BuildMetricDatum(point telegraf.Metric) []*cloudwatch.MetricDatum {
 			value = float64(t)
 		case int64:
 			value = float64(t)
+		case uint64:
+			value = float64(t)
 		case float64:
 			value = t
 		case bool:
@@ -186,6 +194,27 @@ func BuildMetricDatum(point telegraf.Metric) []*cloudwatch.MetricDatum {
 	return datums
 }
 
+// counterDeltas replaces the values of the datums of a counter with their
+// increase since the last write, as CloudWatch adds up the values it gets in
+// each period. The first value of each counter is dropped.
+func (c *CloudWatch) counterDeltas(datums []*cloudwatch.MetricDatum) []*cloudwatch.MetricDatum {
+	deltas := datums[:0]
+	for _, datum := range datums {
+		key := *datum.MetricName
+		for _, dim := range datum.Dimensions {
+			key += " " + *dim.Name + "=" + *dim.Value
+		}
+		delta, ok := c.counters.Delta(key, *datum.Value)
+		if !ok {
+			continue
+		}
+		datum.Value = aws.Float64(delta)
+		datum.Unit = aws.String("Count")
+		deltas = append(deltas, datum)
+	}
+	return deltas
+}
+
 // Make a list of Dimensions by using a Point's tags. CloudWatch supports up to
 // 10 dimensions per metric so we only keep up to the first 10 alphabetically.
 // This always includes the "host" tag if it exists.
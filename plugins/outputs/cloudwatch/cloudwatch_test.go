@@ -3,6 +3,7 @@ package cloudwatch
 import (
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
@@ -69,6 +70,29 @@ func TestBuildMetricDatums(t *testing.T) {
 	assert.Equal(0, len(BuildMetricDatum(nonValidPoint)), "Invalid type should not create a Datum")
 }
 
+// Test that the datums of counters are sent as the increase since the last write
+func TestCounterDeltas(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &CloudWatch{}
+	counter := func(value int64) telegraf.Metric {
+		m, err := telegraf.NewCounterMetric("test",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": value},
+			time.Now())
+		assert.NoError(err)
+		return m
+	}
+
+	datums := c.counterDeltas(BuildMetricDatum(counter(10)))
+	assert.Equal(0, len(datums), "First value of a counter should be dropped")
+
+	datums = c.counterDeltas(BuildMetricDatum(counter(15)))
+	assert.Equal(1, len(datums))
+	assert.Equal(5.0, *datums[0].Value)
+	assert.Equal("Count", *datums[0].Unit)
+}
+
 func TestPartitionDatums(t *testing.T) {
 
 	assert := assert.New(t)
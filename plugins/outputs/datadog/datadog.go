@@ -19,8 +19,9 @@ type Datadog struct {
 	Apikey  string
 	Timeout internal.Duration
 
-	apiUrl string
-	client *http.Client
+	apiUrl   string
+	client   *http.Client
+	counters internal.CounterDeltas
 }
 
 var sampleConfig = `
@@ -40,6 +41,7 @@ type Metric struct {
 	Points [1]Point `json:"points"`
 	Host   string   `json:"host"`
 	Tags   []string `json:"tags,omitempty"`
+	Type   string   `json:"type,omitempty"`
 }
 
 type Point [2]float64
@@ -87,6 +89,20 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 					Tags:   buildTags(m.Tags()),
 					Host:   m.Tags()["host"],
 				}
+				switch m.Type() {
+				case telegraf.Counter:
+					// datadog counts are per interval, so send the
+					// increase since the last write
+					key := dname + " " + strings.Join(metric.Tags, ",")
+					delta, ok := d.counters.Delta(key, dogM[1])
+					if !ok {
+						continue
+					}
+					dogM[1] = delta
+					metric.Type = "count"
+				case telegraf.Gauge:
+					metric.Type = "gauge"
+				}
 				metric.Points[0] = dogM
 				tempSeries = append(tempSeries, metric)
 				metricCounter++
@@ -96,6 +112,9 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 		}
 	}
 
+	if metricCounter == 0 {
+		return nil
+	}
 	ts.Series = make([]*Metric, metricCounter)
 	copy(ts.Series, tempSeries[0:])
 	tsBytes, err := json.Marshal(ts)
@@ -179,6 +198,8 @@ func (p *Point) setValue(v interface{}) error {
 		p[1] = float64(int32(d))
 	case int64:
 		p[1] = float64(int64(d))
+	case uint64:
+		p[1] = float64(d)
 	case float32:
 		p[1] = float64(d)
 	case float64:
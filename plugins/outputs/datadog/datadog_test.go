@@ -152,6 +152,14 @@ func TestBuildPoint(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			testutil.TestMetric(uint64(18446744073709551615), "test7"),
+			Point{
+				float64(time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC).Unix()),
+				18446744073709551615.0,
+			},
+			nil,
+		},
 	}
 	for _, tt := range tagtests {
 		pt, err := buildMetrics(tt.ptIn)
@@ -189,3 +197,42 @@ func TestVerifyValue(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteTypedMetrics(t *testing.T) {
+	var series []*Metric
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body TimeSeries
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		series = body.Series
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	d := NewDatadog(ts.URL)
+	d.Apikey = "123456"
+	require.NoError(t, d.Connect())
+
+	now := time.Now()
+	tags := map[string]string{"host": "localhost"}
+	gauge, err := telegraf.NewGaugeMetric("test_gauge", tags,
+		map[string]interface{}{"value": 1.0}, now)
+	require.NoError(t, err)
+	counter, err := telegraf.NewCounterMetric("test_counter", tags,
+		map[string]interface{}{"value": 10.0}, now)
+	require.NoError(t, err)
+
+	// the first value of a counter has nothing to be compared to
+	require.NoError(t, d.Write([]telegraf.Metric{gauge, counter}))
+	require.Len(t, series, 1)
+	assert.Equal(t, "test.gauge", series[0].Metric)
+	assert.Equal(t, "gauge", series[0].Type)
+
+	counter, err = telegraf.NewCounterMetric("test_counter", tags,
+		map[string]interface{}{"value": 15.0}, now)
+	require.NoError(t, err)
+	require.NoError(t, d.Write([]telegraf.Metric{counter}))
+	require.Len(t, series, 1)
+	assert.Equal(t, "test.counter", series[0].Metric)
+	assert.Equal(t, "count", series[0].Type)
+	assert.Equal(t, 5.0, series[0].Points[0][1])
+}
@@ -0,0 +1,97 @@
+package execd
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const sampleConfig = `
+  ## The external plugin to run, and its arguments. It is started when
+  ## telegraf connects its outputs, and sent every batch of metrics.
+  command = ["/usr/bin/mywriter", "--config", "/etc/mywriter.conf"]
+
+  ## Environment variables added to the environment of the plugin.
+  # environment = ["LC_ALL=C"]
+
+  ## How long the plugin has to answer a write.
+  timeout = "5s"
+
+  ## A plugin that exits is restarted after restart_delay, doubling up to 1m
+  ## while it keeps exiting.
+  restart_delay = "1s"
+`
+
+type Execd struct {
+	Command      []string
+	Environment  []string
+	Timeout      internal.Duration
+	RestartDelay internal.Duration
+
+	process *external.Process
+	logger  telegraf.Logger
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		RestartDelay: internal.Duration{Duration: time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run an external output plugin, see the shim package to write one"
+}
+
+// SetLogger sets the logger the plugin logs with.
+func (e *Execd) SetLogger(logger telegraf.Logger) {
+	e.logger = logger
+}
+
+// Connect starts the external plugin.
+func (e *Execd) Connect() error {
+	e.process = &external.Process{
+		Command:      e.Command,
+		Environment:  e.Environment,
+		RestartDelay: e.RestartDelay.Duration,
+		Log:          e.logger,
+		Metric: func(line string) {
+			e.logger.Warnf("Ignoring metric written by an output plugin: %s", line)
+		},
+	}
+	return e.process.Start()
+}
+
+// Close stops the external plugin.
+func (e *Execd) Close() error {
+	if e.process != nil {
+		e.process.Stop()
+	}
+	return nil
+}
+
+// Write sends the metrics to the external plugin, and waits for it to answer
+// that it wrote them.
+func (e *Execd) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		lines = append(lines, m.String())
+	}
+	return e.process.Request(lines, external.Write, e.Timeout.Duration)
+}
+
+func init() {
+	outputs.Add("execd", func() telegraf.Output {
+		return NewExecd()
+	})
+}
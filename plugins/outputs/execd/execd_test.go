@@ -0,0 +1,49 @@
+package execd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plugin writes the metrics of each batch to the file in $OUT.
+const plugin = `
+while read line; do
+  case "$line" in
+    "#write "*)
+      echo "#ok ${line#"#write "}";;
+    *)
+      echo "$line" >> "$OUT";;
+  esac
+done`
+
+func TestWrite(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test that runs sh on Windows")
+	}
+	dir, err := ioutil.TempDir("", "execd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "metrics")
+
+	e := NewExecd()
+	e.Command = []string{"sh", "-c", plugin}
+	e.Environment = []string{"OUT=" + out}
+	e.SetLogger(testutil.Logger{Name: "outputs.execd"})
+	require.NoError(t, e.Connect())
+	defer e.Close()
+
+	metrics := testutil.MockMetrics()
+	require.NoError(t, e.Write(metrics))
+
+	written, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, metrics[0].String()+"\n", string(written))
+}
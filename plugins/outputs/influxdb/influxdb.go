@@ -28,6 +28,8 @@ type InfluxDB struct {
 	RetentionPolicy string
 	Timeout         internal.Duration
 	UDPPayload      int `toml:"udp_payload"`
+	// Uint64As is how unsigned integer fields are written, "int" or "float"
+	Uint64As string `toml:"uint64_as"`
 
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
@@ -65,6 +67,11 @@ var sampleConfig = `
   ## Set UDP payload size, defaults to InfluxDB UDP Client default (512 bytes)
   # udp_payload = 512
 
+  ## How to write unsigned integer fields, which InfluxDB can't store:
+  ##   "int"   - as integers, values above 9223372036854775807 are capped
+  ##   "float" - as floats, keeping large values but not their precision
+  # uint64_as = "int"
+
   ## Optional SSL Config
   # ssl_ca = "/etc/telegraf/ca.pem"
   # ssl_cert = "/etc/telegraf/cert.pem"
@@ -74,6 +81,13 @@ var sampleConfig = `
 `
 
 func (i *InfluxDB) Connect() error {
+	switch i.Uint64As {
+	case "", "int", "float":
+	default:
+		return fmt.Errorf("invalid uint64_as %q, must be \"int\" or \"float\"",
+			i.Uint64As)
+	}
+
 	var urls []string
 	for _, u := range i.URLs {
 		urls = append(urls, u)
@@ -188,6 +202,9 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 	}
 
 	for _, metric := range metrics {
+		if i.Uint64As == "float" {
+			metric = uint64ToFloat(metric)
+		}
 		bp.AddPoint(metric.Point())
 	}
 
@@ -215,6 +232,24 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 	return err
 }
 
+// uint64ToFloat returns the metric with its uint64 fields converted to float64.
+// The metric is copied first, as it is shared with the other outputs.
+func uint64ToFloat(metric telegraf.Metric) telegraf.Metric {
+	var c telegraf.Metric
+	for k, v := range metric.Fields() {
+		if u, ok := v.(uint64); ok {
+			if c == nil {
+				c = metric.Copy()
+			}
+			c.AddField(k, float64(u))
+		}
+	}
+	if c == nil {
+		return metric
+	}
+	return c
+}
+
 func init() {
 	outputs.Add("influxdb", func() telegraf.Output {
 		return &InfluxDB{
@@ -2,12 +2,16 @@ package influxdb
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,3 +43,54 @@ func TestHTTPInflux(t *testing.T) {
 	err = i.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+func TestHTTPInfluxUint64(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/write" {
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, `{"results":[{}]}`)
+	}))
+	defer ts.Close()
+
+	m := testutil.TestMetric(uint64(math.MaxUint64))
+
+	i := InfluxDB{
+		URLs: []string{ts.URL},
+	}
+	require.NoError(t, i.Connect())
+	require.NoError(t, i.Write([]telegraf.Metric{m}))
+	assert.Contains(t, string(body), "value=9223372036854775807i")
+
+	i = InfluxDB{
+		URLs:     []string{ts.URL},
+		Uint64As: "float",
+	}
+	require.NoError(t, i.Connect())
+	require.NoError(t, i.Write([]telegraf.Metric{m}))
+	assert.NotContains(t, string(body), "value=9223372036854775807i")
+
+	// the metric is shared with other outputs
+	assert.Equal(t, uint64(math.MaxUint64), m.Fields()["value"])
+
+	i = InfluxDB{
+		URLs:     []string{ts.URL},
+		Uint64As: "string",
+	}
+	assert.Error(t, i.Connect())
+}
+
+func TestUint64ToFloat(t *testing.T) {
+	m := testutil.TestMetric(uint64(math.MaxUint64))
+	c := uint64ToFloat(m)
+	assert.Equal(t, float64(math.MaxUint64), c.Fields()["value"])
+	assert.Equal(t, uint64(math.MaxUint64), m.Fields()["value"])
+
+	m = testutil.TestMetric(int64(1))
+	assert.True(t, m == uint64ToFloat(m))
+}
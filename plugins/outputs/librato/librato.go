@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 
 	"github.com/influxdata/telegraf"
@@ -48,6 +49,8 @@ var sampleConfig = `
 
 type LMetrics struct {
 	Gauges []*Gauge `json:"gauges"`
+	// Counters are sent like gauges, with whole values
+	Counters []*Gauge `json:"counters,omitempty"`
 }
 
 type Gauge struct {
@@ -86,6 +89,15 @@ func (l *Librato) Write(metrics []telegraf.Metric) error {
 	for _, m := range metrics {
 		if gauges, err := l.buildGauges(m); err == nil {
 			for _, gauge := range gauges {
+				if m.Type() == telegraf.Counter {
+					// librato counters only take integers
+					gauge.Value = math.Trunc(gauge.Value)
+					lmetrics.Counters = append(lmetrics.Counters, gauge)
+					if l.Debug {
+						log.Printf("[DEBUG] Got a counter: %v\n", gauge)
+					}
+					continue
+				}
 				tempGauges = append(tempGauges, gauge)
 				metricCounter++
 				if l.Debug {
@@ -200,6 +212,8 @@ func (g *Gauge) setValue(v interface{}) error {
 		g.Value = float64(int32(d))
 	case int64:
 		g.Value = float64(int64(d))
+	case uint64:
+		g.Value = float64(d)
 	case float32:
 		g.Value = float64(d)
 	case float64:
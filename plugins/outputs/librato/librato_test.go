@@ -137,6 +137,15 @@ func TestBuildGauge(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			testutil.TestMetric(uint64(18446744073709551615), "test7"),
+			&Gauge{
+				Name:        "value1.test7",
+				MeasureTime: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC).Unix(),
+				Value:       18446744073709551615.0,
+			},
+			nil,
+		},
 		{
 			testutil.TestMetric("11234.5", "test7"),
 			nil,
@@ -225,3 +234,31 @@ func TestBuildGaugeWithSource(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteCounters(t *testing.T) {
+	var body LMetrics
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	l := NewLibrato(ts.URL)
+	l.ApiUser = fakeUser
+	l.ApiToken = fakeToken
+	require.NoError(t, l.Connect())
+
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	counter, err := telegraf.NewCounterMetric("test1",
+		map[string]string{"tag1": "value1"},
+		map[string]interface{}{"value": 10.5}, now)
+	require.NoError(t, err)
+	err = l.Write([]telegraf.Metric{counter, testutil.TestMetric(1.0, "test2")})
+	require.NoError(t, err)
+
+	require.Len(t, body.Counters, 1)
+	require.Equal(t, "value1.test1", body.Counters[0].Name)
+	require.Equal(t, 10.0, body.Counters[0].Value)
+	require.Len(t, body.Gauges, 1)
+	require.Equal(t, "value1.test2", body.Gauges[0].Name)
+}
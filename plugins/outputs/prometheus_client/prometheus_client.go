@@ -3,9 +3,14 @@ package prometheus_client
 import (
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -25,8 +30,31 @@ var (
 )
 
 type PrometheusClient struct {
-	Listen  string
-	metrics map[string]*prometheus.UntypedVec
+	Listen string
+
+	sync.Mutex
+	// families are the latest samples of each metric name
+	families map[string]*family
+	listener net.Listener
+}
+
+// family is the samples of a metric name, which all have the same type.
+type family struct {
+	valueType telegraf.ValueType
+	// samples are keyed by their labels
+	samples map[string]*sample
+}
+
+type sample struct {
+	labels prometheus.Labels
+	value  float64
+
+	// count, sum, quantiles and buckets are the value of summaries and
+	// histograms.
+	count     uint64
+	sum       float64
+	quantiles map[float64]float64
+	buckets   map[float64]uint64
 }
 
 var sampleConfig = `
@@ -38,20 +66,33 @@ func (p *PrometheusClient) Start() error {
 	if p.Listen == "" {
 		p.Listen = "localhost:9126"
 	}
+	p.families = make(map[string]*family)
 
-	http.Handle("/metrics", prometheus.Handler())
-	server := &http.Server{
-		Addr: p.Listen,
+	if err := prometheus.Register(p); err != nil {
+		return err
 	}
+	listener, err := net.Listen("tcp", p.Listen)
+	if err != nil {
+		prometheus.Unregister(p)
+		return err
+	}
+	p.listener = listener
 
-	p.metrics = make(map[string]*prometheus.UntypedVec)
-	go server.ListenAndServe()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler())
+	server := &http.Server{
+		Addr:    p.Listen,
+		Handler: mux,
+	}
+	go server.Serve(listener)
 	return nil
 }
 
 func (p *PrometheusClient) Stop() {
-	// TODO: Use a listener for http.Server that counts active connections
-	//       that can be stopped and closed gracefully
+	prometheus.Unregister(p)
+	if p.listener != nil {
+		p.listener.Close()
+	}
 }
 
 func (p *PrometheusClient) Connect() error {
@@ -72,16 +113,59 @@ func (p *PrometheusClient) Description() string {
 	return "Configuration for the Prometheus client to spawn"
 }
 
+// Describe implements prometheus.Collector. The metrics are only known once
+// they are written, but a collector has to describe at least one of them to be
+// registered.
+func (p *PrometheusClient) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.NewGauge(prometheus.GaugeOpts{Name: "Dummy", Help: "Dummy"}).Describe(ch)
+}
+
+// Collect implements prometheus.Collector, sending the latest sample of each
+// series.
+func (p *PrometheusClient) Collect(ch chan<- prometheus.Metric) {
+	p.Lock()
+	defer p.Unlock()
+
+	for name, fam := range p.families {
+		for _, s := range fam.samples {
+			desc := prometheus.NewDesc(name, "Telegraf collected metric", nil, s.labels)
+			var metric prometheus.Metric
+			var err error
+			switch fam.valueType {
+			case telegraf.Summary:
+				metric, err = prometheus.NewConstSummary(desc, s.count, s.sum, s.quantiles)
+			case telegraf.Histogram:
+				metric, err = prometheus.NewConstHistogram(desc, s.count, s.sum, s.buckets)
+			case telegraf.Counter:
+				metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, s.value)
+			case telegraf.Gauge:
+				metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, s.value)
+			default:
+				metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, s.value)
+			}
+			if err != nil {
+				log.Printf("ERROR creating metric in Prometheus output, "+
+					"key: %s, labels: %v,\nerr: %s\n",
+					name, s.labels, err.Error())
+				continue
+			}
+			ch <- metric
+		}
+	}
+}
+
 func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
 
+	p.Lock()
+	defer p.Unlock()
+
 	for _, point := range metrics {
 		key := point.Name()
 		key = sanitizedChars.Replace(key)
 
-		var labels []string
 		l := prometheus.Labels{}
 		for k, v := range point.Tags() {
 			k = sanitizedChars.Replace(k)
@@ -91,23 +175,29 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 			if !labelName.MatchString(k) {
 				continue
 			}
-			labels = append(labels, k)
 			l[k] = v
 		}
 
-		for n, val := range point.Fields() {
-			// Ignore string and bool fields.
-			switch val.(type) {
-			case string:
+		// Summaries and histograms are made of all of their fields
+		if point.Type() == telegraf.Summary || point.Type() == telegraf.Histogram {
+			if !metricName.MatchString(key) {
 				continue
-			case bool:
+			}
+			p.addSample(key, point.Type(), newDistribution(point, l))
+			continue
+		}
+
+		for n, val := range point.Fields() {
+			value, ok := floatValue(val)
+			if !ok {
+				// Ignore string and bool fields.
 				continue
 			}
 
 			// sanitize the measurement name
 			n = sanitizedChars.Replace(n)
 			var mname string
-			if n == "value" {
+			if n == "value" || isTypeField(point.Type(), n) {
 				mname = key
 			} else {
 				mname = fmt.Sprintf("%s_%s", key, n)
@@ -118,46 +208,95 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				continue
 			}
 
-			// Create a new metric if it hasn't been created yet.
-			if _, ok := p.metrics[mname]; !ok {
-				p.metrics[mname] = prometheus.NewUntypedVec(
-					prometheus.UntypedOpts{
-						Name: mname,
-						Help: "Telegraf collected metric",
-					},
-					labels,
-				)
-				if err := prometheus.Register(p.metrics[mname]); err != nil {
-					log.Printf("prometheus_client: Metric failed to register with prometheus, %s", err)
-					continue
-				}
-			}
+			p.addSample(mname, point.Type(), &sample{labels: l, value: value})
+		}
+	}
+	return nil
+}
 
-			switch val := val.(type) {
-			case int64:
-				m, err := p.metrics[mname].GetMetricWith(l)
-				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
-						mname, l, err.Error())
-					continue
-				}
-				m.Set(float64(val))
-			case float64:
-				m, err := p.metrics[mname].GetMetricWith(l)
-				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
-						mname, l, err.Error())
-					continue
-				}
-				m.Set(val)
-			default:
+// addSample sets the latest sample of a series. A metric name has a single
+// type, so samples of another type replace the whole family.
+func (p *PrometheusClient) addSample(
+	name string,
+	valueType telegraf.ValueType,
+	s *sample,
+) {
+	fam, ok := p.families[name]
+	if !ok || fam.valueType != valueType {
+		fam = &family{
+			valueType: valueType,
+			samples:   make(map[string]*sample),
+		}
+		p.families[name] = fam
+	}
+	fam.samples[labelsKey(s.labels)] = s
+}
+
+// newDistribution returns the sample of a summary or histogram. Their fields
+// are the quantiles or bucket bounds, and "count" and "sum".
+func newDistribution(point telegraf.Metric, l prometheus.Labels) *sample {
+	s := &sample{
+		labels:    l,
+		quantiles: make(map[float64]float64),
+		buckets:   make(map[float64]uint64),
+	}
+	for n, val := range point.Fields() {
+		value, ok := floatValue(val)
+		if !ok {
+			continue
+		}
+		switch n {
+		case "count":
+			s.count = uint64(value)
+		case "sum":
+			s.sum = value
+		default:
+			bound, err := strconv.ParseFloat(n, 64)
+			if err != nil {
 				continue
 			}
+			if point.Type() == telegraf.Summary {
+				s.quantiles[bound] = value
+			} else if !math.IsInf(bound, 1) {
+				// the +Inf bucket is the count
+				s.buckets[bound] = uint64(value)
+			}
 		}
 	}
-	return nil
+	return s
+}
+
+// isTypeField returns true if the field is named after the type of its
+// metric, like the "counter" and "gauge" fields of the prometheus input.
+// These are exposed under the name of the metric.
+func isTypeField(valueType telegraf.ValueType, field string) bool {
+	switch valueType {
+	case telegraf.Counter, telegraf.Gauge:
+		return field == valueType.String()
+	}
+	return false
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// labelsKey returns a string identifying a set of labels.
+func labelsKey(l prometheus.Labels) string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+strconv.Quote(v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
 }
 
 func init() {
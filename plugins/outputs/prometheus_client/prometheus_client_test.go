@@ -88,3 +88,66 @@ func TestPrometheusWritePointEmptyTag(t *testing.T) {
 			map[string]interface{}{"value": e.value})
 	}
 }
+
+func TestPrometheusWritePointTyped(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	pClient := &PrometheusClient{Listen: "localhost:9128"}
+	err := pClient.Start()
+	time.Sleep(time.Millisecond * 200)
+	require.NoError(t, err)
+	defer pClient.Stop()
+
+	p := &prometheus.Prometheus{
+		Urls: []string{"http://localhost:9128/metrics"},
+	}
+	tags := map[string]string{"testtag": "testvalue"}
+	counter, _ := telegraf.NewCounterMetric(
+		"test_counter",
+		tags,
+		map[string]interface{}{"counter": 5.0})
+	gauge, _ := telegraf.NewGaugeMetric(
+		"test_gauge",
+		tags,
+		map[string]interface{}{"value": 1.0})
+	histogram, _ := telegraf.NewHistogramMetric(
+		"test_histogram",
+		tags,
+		map[string]interface{}{
+			"0.5":   1.0,
+			"1":     3.0,
+			"+Inf":  4.0,
+			"count": 4.0,
+			"sum":   6.5,
+		})
+	require.NoError(t, pClient.Write([]telegraf.Metric{counter, gauge, histogram}))
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+
+	expected := []struct {
+		name      string
+		valueType telegraf.ValueType
+		fields    map[string]interface{}
+	}{
+		{"test_counter", telegraf.Counter,
+			map[string]interface{}{"counter": 5.0}},
+		{"test_gauge", telegraf.Gauge,
+			map[string]interface{}{"gauge": 1.0}},
+		{"test_histogram", telegraf.Histogram,
+			map[string]interface{}{
+				"0.5":   1.0,
+				"1":     3.0,
+				"+Inf":  4.0,
+				"count": 4.0,
+				"sum":   6.5,
+			}},
+	}
+	for _, e := range expected {
+		m, ok := acc.Get(e.name)
+		require.True(t, ok, e.name)
+		require.Equal(t, e.valueType, m.Type, e.name)
+		require.Equal(t, e.fields, m.Fields, e.name)
+	}
+}
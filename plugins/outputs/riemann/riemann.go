@@ -2,6 +2,7 @@ package riemann
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strings"
@@ -105,9 +106,16 @@ func buildEvents(p telegraf.Metric, s string) []*raidman.Event {
 			Service: serviceName(s, p.Name(), p.Tags(), fieldName),
 		}
 
-		switch value.(type) {
+		switch v := value.(type) {
 		case string:
-			event.State = value.(string)
+			event.State = v
+		case uint64:
+			// riemann metrics are signed, larger values are sent as floats
+			if v > math.MaxInt64 {
+				event.Metric = float64(v)
+			} else {
+				event.Metric = int64(v)
+			}
 		default:
 			event.Metric = value
 		}
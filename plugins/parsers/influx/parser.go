@@ -2,11 +2,13 @@ package influx
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
-
-	"github.com/influxdata/influxdb/models"
 )
 
 // InfluxParser is an object for Parsing incoming metrics.
@@ -21,24 +23,25 @@ type InfluxParser struct {
 // a non-nil error will be returned in addition to the metrics that parsed
 // successfully.
 func (p *InfluxParser) Parse(buf []byte) ([]telegraf.Metric, error) {
-	// parse even if the buffer begins with a newline
-	buf = bytes.TrimPrefix(buf, []byte("\n"))
-	points, err := models.ParsePoints(buf)
-	metrics := make([]telegraf.Metric, len(points))
-	for i, point := range points {
-		tags := point.Tags()
-		for k, v := range p.DefaultTags {
-			// Only set tags not in parsed metric
-			if _, ok := tags[k]; !ok {
-				tags[k] = v
-			}
+	now := time.Now().UTC()
+	var metrics []telegraf.Metric
+	var errs []string
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		metric, err := p.parseLine(line, now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to parse '%s': %s", line, err))
+			continue
 		}
-		// Ignore error here because it's impossible that a model.Point
-		// wouldn't parse into client.Point properly
-		metrics[i], _ = telegraf.NewMetric(point.Name(), tags,
-			point.Fields(), point.Time())
+		metrics = append(metrics, metric)
 	}
-	return metrics, err
+	if len(errs) > 0 {
+		return metrics, errors.New(strings.Join(errs, "\n"))
+	}
+	return metrics, nil
 }
 
 func (p *InfluxParser) ParseLine(line string) (telegraf.Metric, error) {
@@ -59,3 +62,155 @@ func (p *InfluxParser) ParseLine(line string) (telegraf.Metric, error) {
 func (p *InfluxParser) SetDefaultTags(tags map[string]string) {
 	p.DefaultTags = tags
 }
+
+// parseLine parses a line of line protocol:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Lines without a timestamp get the time now.
+func (p *InfluxParser) parseLine(line []byte, now time.Time) (telegraf.Metric, error) {
+	name, i := scanToken(line, 0, ", ")
+	if name == "" {
+		return nil, fmt.Errorf("missing measurement")
+	}
+
+	tags := make(map[string]string)
+	for i < len(line) && line[i] == ',' {
+		var key, value string
+		key, i = scanToken(line, i+1, "=, ")
+		if key == "" {
+			return nil, fmt.Errorf("missing tag key")
+		}
+		if i >= len(line) || line[i] != '=' {
+			return nil, fmt.Errorf("missing tag value")
+		}
+		value, i = scanToken(line, i+1, "=, ")
+		if value == "" || (i < len(line) && line[i] == '=') {
+			return nil, fmt.Errorf("missing tag value")
+		}
+		tags[key] = value
+	}
+	for k, v := range p.DefaultTags {
+		// Only set tags not in parsed metric
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	i = skipSpaces(line, i)
+	fields := make(map[string]interface{})
+	for {
+		var key string
+		key, i = scanToken(line, i, "=, ")
+		if key == "" {
+			return nil, fmt.Errorf("missing field key")
+		}
+		if i >= len(line) || line[i] != '=' {
+			return nil, fmt.Errorf("missing field value")
+		}
+		var value interface{}
+		var err error
+		value, i, err = scanFieldValue(line, i+1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %s: %s", key, err)
+		}
+		fields[key] = value
+		if i >= len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+
+	t := now
+	i = skipSpaces(line, i)
+	if i < len(line) {
+		ts, err := strconv.ParseInt(string(line[i:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %s", line[i:])
+		}
+		t = time.Unix(0, ts).UTC()
+	}
+
+	return telegraf.NewMetric(name, tags, fields, t)
+}
+
+// scanToken returns the unescaped token starting at i, which ends at one of the
+// unescaped stop characters or at the end of the line, and the position of
+// its end.
+func scanToken(line []byte, i int, stop string) (string, int) {
+	var buf []byte
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) && strings.IndexByte(stop+"=", line[i+1]) >= 0 {
+			buf = append(buf, line[i+1])
+			i++
+			continue
+		}
+		if strings.IndexByte(stop, c) >= 0 {
+			break
+		}
+		buf = append(buf, c)
+	}
+	return string(buf), i
+}
+
+// scanFieldValue returns the value of the field starting at i and the position
+// of its end. Integers end with 'i', unsigned integers with 'u'.
+func scanFieldValue(line []byte, i int) (interface{}, int, error) {
+	if i < len(line) && line[i] == '"' {
+		var buf []byte
+		for i++; i < len(line); i++ {
+			c := line[i]
+			if c == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+				buf = append(buf, line[i+1])
+				i++
+				continue
+			}
+			if c == '"' {
+				return string(buf), i + 1, nil
+			}
+			buf = append(buf, c)
+		}
+		return nil, i, fmt.Errorf("unterminated string")
+	}
+
+	start := i
+	for i < len(line) && line[i] != ',' && line[i] != ' ' {
+		i++
+	}
+	s := string(line[start:i])
+	switch {
+	case s == "":
+		return nil, i, fmt.Errorf("missing value")
+	case strings.HasSuffix(s, "i"):
+		v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return nil, i, fmt.Errorf("invalid integer %s", s)
+		}
+		return v, i, nil
+	case strings.HasSuffix(s, "u"):
+		v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return nil, i, fmt.Errorf("invalid unsigned integer %s", s)
+		}
+		return v, i, nil
+	}
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return true, i, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, i, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, i, fmt.Errorf("invalid number %s", s)
+	}
+	return v, i, nil
+}
+
+func skipSpaces(line []byte, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
@@ -192,3 +192,43 @@ func TestParseInvalidInflux(t *testing.T) {
 	_, err = parser.ParseLine(invalidInflux2)
 	assert.Error(t, err)
 }
+
+func TestParseFieldTypes(t *testing.T) {
+	parser := InfluxParser{}
+
+	metrics, err := parser.Parse([]byte(`net,interface=eth0 ` +
+		`bytes_recv=18446744073709551615u,errors=-3i,up=true,` +
+		`name="eth \"0\"",speed=1.5 1257894000000000000`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"bytes_recv": uint64(18446744073709551615),
+		"errors":     int64(-3),
+		"up":         true,
+		"name":       `eth "0"`,
+		"speed":      float64(1.5),
+	}, metrics[0].Fields())
+	assert.Equal(t, exptime, metrics[0].Time())
+
+	_, err = parser.Parse([]byte("net bytes_recv=-1u"))
+	assert.Error(t, err)
+	_, err = parser.Parse([]byte("net bytes_recv=18446744073709551616u"))
+	assert.Error(t, err)
+}
+
+func TestParseEscapes(t *testing.T) {
+	parser := InfluxParser{}
+
+	metrics, err := parser.Parse([]byte(
+		`cpu\ usage\,total,host\ name=local\,host,a\=b=c usage\ idle=99`))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "cpu usage,total", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"host name": "local,host",
+		"a=b":       "c",
+	}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"usage idle": float64(99),
+	}, metrics[0].Fields())
+}
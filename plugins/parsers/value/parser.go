@@ -39,8 +39,13 @@ func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		return nil, err
 	}
 
+	// every metric needs its own tags, they can be modified
+	tags := make(map[string]string, len(v.DefaultTags))
+	for k, val := range v.DefaultTags {
+		tags[k] = val
+	}
 	fields := map[string]interface{}{"value": value}
-	metric, err := telegraf.NewMetric(v.MetricName, v.DefaultTags,
+	metric, err := telegraf.NewMetric(v.MetricName, tags,
 		fields, time.Now().UTC())
 	if err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package graphite
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/influxdata/telegraf"
@@ -29,7 +30,14 @@ func (s *GraphiteSerializer) Serialize(metric telegraf.Metric) ([]string, error)
 
 	for fieldName, value := range metric.Fields() {
 		// Convert value to string
-		valueS := fmt.Sprintf("%#v", value)
+		var valueS string
+		switch v := value.(type) {
+		case uint64:
+			// %#v would print it in hexadecimal
+			valueS = strconv.FormatUint(v, 10)
+		default:
+			valueS = fmt.Sprintf("%#v", value)
+		}
 		point := fmt.Sprintf("%s %s %d",
 			// insert "field" section of template
 			InsertField(bucket, fieldName),
@@ -83,6 +83,27 @@ func TestSerializeMetricNoHost(t *testing.T) {
 	assert.Equal(t, expS, mS)
 }
 
+func TestSerializeMetricUint(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"cpu": "cpu0",
+	}
+	fields := map[string]interface{}{
+		"bytes_recv": uint64(18446744073709551615),
+	}
+	m, err := telegraf.NewMetric("net", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := GraphiteSerializer{}
+	mS, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []string{
+		fmt.Sprintf("cpu0.net.bytes_recv 18446744073709551615 %d", now.Unix()),
+	}
+	assert.Equal(t, expS, mS)
+}
+
 func TestSerializeMetricHost(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
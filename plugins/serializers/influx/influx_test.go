@@ -48,6 +48,25 @@ func TestSerializeMetricInt(t *testing.T) {
 	assert.Equal(t, expS, mS)
 }
 
+func TestSerializeMetricUint(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"cpu": "cpu0",
+	}
+	fields := map[string]interface{}{
+		"bytes_recv": uint64(18446744073709551615),
+	}
+	m, err := telegraf.NewMetric("net", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := InfluxSerializer{}
+	mS, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []string{fmt.Sprintf("net,cpu=cpu0 bytes_recv=18446744073709551615u %d", now.UnixNano())}
+	assert.Equal(t, expS, mS)
+}
+
 func TestSerializeMetricString(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
@@ -47,6 +47,25 @@ func TestSerializeMetricInt(t *testing.T) {
 	assert.Equal(t, expS, mS)
 }
 
+func TestSerializeMetricUint(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"cpu": "cpu0",
+	}
+	fields := map[string]interface{}{
+		"bytes_recv": uint64(18446744073709551615),
+	}
+	m, err := telegraf.NewMetric("net", tags, fields, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{}
+	mS, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []string{fmt.Sprintf("{\"fields\":{\"bytes_recv\":18446744073709551615},\"name\":\"net\",\"tags\":{\"cpu\":\"cpu0\"},\"timestamp\":%d}", now.Unix())}
+	assert.Equal(t, expS, mS)
+}
+
 func TestSerializeMetricString(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
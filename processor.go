@@ -0,0 +1,13 @@
+package telegraf
+
+type Processor interface {
+	// SampleConfig returns the default configuration of the Processor
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Processor
+	Description() string
+
+	// Apply the filter to the given metric. The metrics are not shared yet,
+	// so they can be modified in place.
+	Apply(in ...Metric) []Metric
+}
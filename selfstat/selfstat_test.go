@@ -0,0 +1,159 @@
+package selfstat
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCleanup resets the global registry for test cleanup.
+func testCleanup() {
+	registry = &rgstry{
+		stats: make(map[uint64]map[string]Stat),
+	}
+}
+
+func TestRegisterAndIncrAndSet(t *testing.T) {
+	defer testCleanup()
+	s1 := Register("test", "test_field1", map[string]string{"test": "foo"})
+	s2 := Register("test", "test_field2", map[string]string{"test": "foo"})
+	assert.Equal(t, int64(0), s1.Get())
+
+	s1.Incr(10)
+	s1.Incr(5)
+	assert.Equal(t, int64(15), s1.Get())
+
+	s1.Set(12)
+	assert.Equal(t, int64(12), s1.Get())
+
+	s1.Incr(-2)
+	assert.Equal(t, int64(10), s1.Get())
+
+	s2.Set(101)
+	assert.Equal(t, int64(101), s2.Get())
+
+	// make sure that the same field returns the same metric
+	// this one should be the same as s2.
+	foo := Register("test", "test_field2", map[string]string{"test": "foo"})
+	assert.Equal(t, int64(101), foo.Get())
+
+	// check that tags are consistent
+	assert.Equal(t, map[string]string{"test": "foo"}, foo.Tags())
+	assert.Equal(t, "internal_test", foo.Name())
+	assert.Equal(t, "test_field2", foo.FieldName())
+}
+
+func TestRegisterTimingAndIncrAndSet(t *testing.T) {
+	defer testCleanup()
+	s1 := RegisterTiming("test", "test_field1_ns", map[string]string{"test": "foo"})
+	assert.Equal(t, int64(0), s1.Get())
+
+	s1.Incr(10)
+	s1.Incr(20)
+	assert.Equal(t, int64(15), s1.Get())
+
+	s1.Set(30)
+	assert.Equal(t, int64(20), s1.Get())
+
+	// the average is reset after collection, keeping the last value until
+	// a new timing arrives
+	Metrics()
+	assert.Equal(t, int64(20), s1.Get())
+	s1.Incr(100)
+	assert.Equal(t, int64(100), s1.Get())
+}
+
+func TestStatKeyConsistency(t *testing.T) {
+	s := &stat{
+		measurement: "internal_stat",
+		field:       "myfield",
+		tags: map[string]string{
+			"foo":   "bar",
+			"bar":   "baz",
+			"whose": "first",
+		},
+		key: key("internal_stat", map[string]string{
+			"foo":   "bar",
+			"bar":   "baz",
+			"whose": "first",
+		}),
+	}
+	k := s.Key()
+	for i := 0; i < 5000; i++ {
+		// assert that the Key() func doesn't change anything.
+		assert.Equal(t, k, s.Key())
+
+		// assert that two identical measurements always produce the same key.
+		tmp := &stat{
+			measurement: "internal_stat",
+			field:       "myfield",
+			key: key("internal_stat", map[string]string{
+				"foo":   "bar",
+				"bar":   "baz",
+				"whose": "first",
+			}),
+		}
+		assert.Equal(t, k, tmp.Key())
+	}
+}
+
+func TestRegisterMetricsAndVerify(t *testing.T) {
+	defer testCleanup()
+
+	// register two metrics with the same key
+	s1 := RegisterTiming("test_timing", "test_field1_ns", map[string]string{"test": "foo"})
+	s2 := RegisterTiming("test_timing", "test_field2_ns", map[string]string{"test": "foo"})
+	s1.Incr(10)
+	s2.Incr(15)
+	assert.Len(t, Metrics(), 1)
+
+	// register two more metrics with different keys
+	s3 := RegisterTiming("test_timing", "test_field1_ns", map[string]string{"test": "bar"})
+	s4 := RegisterTiming("test_timing", "test_field2_ns", map[string]string{"test": "baz"})
+	s3.Incr(10)
+	s4.Incr(15)
+	assert.Len(t, Metrics(), 3)
+
+	// register some non-timing metrics
+	s5 := Register("test", "test_field1", map[string]string{"test": "bar"})
+	s6 := Register("test", "test_field2", map[string]string{"test": "baz"})
+	Register("test", "test_field3", map[string]string{"test": "baz"})
+	s5.Incr(10)
+	s5.Incr(18)
+	s6.Incr(15)
+	assert.Len(t, Metrics(), 5)
+
+	for _, m := range Metrics() {
+		if m.Name() == "internal_test" && m.Tags()["test"] == "baz" {
+			assert.Equal(t, map[string]interface{}{
+				"test_field2": int64(15),
+				"test_field3": int64(0),
+			}, m.Fields())
+		}
+		if m.Name() == "internal_test_timing" && m.Tags()["test"] == "foo" {
+			assert.Equal(t, map[string]interface{}{
+				"test_field1_ns": int64(10),
+				"test_field2_ns": int64(15),
+			}, m.Fields())
+		}
+	}
+}
+
+func TestConcurrentIncr(t *testing.T) {
+	defer testCleanup()
+	s := Register("test", "concurrent", map[string]string{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Incr(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), s.Get())
+}
@@ -0,0 +1,71 @@
+package selfstat
+
+import (
+	"sync"
+)
+
+type timingStat struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	key         uint64
+
+	mu    sync.Mutex
+	v     int64
+	prev  int64
+	count int64
+}
+
+func (s *timingStat) Incr(v int64) {
+	s.mu.Lock()
+	s.v += v
+	s.count++
+	s.mu.Unlock()
+}
+
+func (s *timingStat) Set(v int64) {
+	s.Incr(v)
+}
+
+// Get returns the average of the timings received since the last reset. If
+// no timing was received since then, the previous average is returned.
+func (s *timingStat) Get() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return s.prev
+	}
+	return s.v / s.count
+}
+
+func (s *timingStat) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count > 0 {
+		s.prev = s.v / s.count
+	}
+	s.v = 0
+	s.count = 0
+}
+
+func (s *timingStat) Name() string {
+	return s.measurement
+}
+
+func (s *timingStat) FieldName() string {
+	return s.field
+}
+
+// Tags returns a copy of the timingStat's tags.
+// NOTE this allocates a new map every time it is called.
+func (s *timingStat) Tags() map[string]string {
+	m := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *timingStat) Key() uint64 {
+	return s.key
+}
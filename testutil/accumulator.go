@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,6 +18,7 @@ type Metric struct {
 	Tags        map[string]string
 	Fields      map[string]interface{}
 	Time        time.Time
+	Type        telegraf.ValueType
 }
 
 func (p *Metric) String() string {
@@ -28,7 +30,11 @@ type Accumulator struct {
 	sync.Mutex
 
 	Metrics []*Metric
+	Errors  []error
 	debug   bool
+
+	// delivered is where the deliveries of tracked metrics are reported
+	delivered chan telegraf.DeliveryInfo
 }
 
 // Add adds a measurement point to the accumulator
@@ -48,6 +54,56 @@ func (a *Accumulator) AddFields(
 	fields map[string]interface{},
 	tags map[string]string,
 	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Untyped, timestamp...)
+}
+
+// AddCounter adds a counter measurement point.
+func (a *Accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Counter, timestamp...)
+}
+
+// AddGauge adds a gauge measurement point.
+func (a *Accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Gauge, timestamp...)
+}
+
+// AddSummary adds a summary measurement point.
+func (a *Accumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Summary, timestamp...)
+}
+
+// AddHistogram adds a histogram measurement point.
+func (a *Accumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Histogram, timestamp...)
+}
+
+func (a *Accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	timestamp ...time.Time,
 ) {
 	a.Lock()
 	defer a.Unlock()
@@ -79,11 +135,56 @@ func (a *Accumulator) AddFields(
 		Fields:      fields,
 		Tags:        tags,
 		Time:        t,
+		Type:        mType,
 	}
 
 	a.Metrics = append(a.Metrics, p)
 }
 
+// AddError appends the given error to Accumulator.Errors.
+func (a *Accumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	a.Lock()
+	a.Errors = append(a.Errors, err)
+	a.Unlock()
+}
+
+// WithTracking returns the accumulator itself, which reports every group of
+// metrics added with AddTrackingMetricGroup as delivered right away.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	a.Lock()
+	defer a.Unlock()
+	a.delivered = make(chan telegraf.DeliveryInfo, maxTracked)
+	return a
+}
+
+// AddTrackingMetricGroup adds the metrics, and reports them delivered.
+func (a *Accumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	for _, m := range group {
+		a.addFields(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time())
+	}
+	_, id := telegraf.NewTrackingMetricGroup(nil, func(info telegraf.DeliveryInfo) {
+		a.deliveries() <- info
+	})
+	return id
+}
+
+// Delivered returns the channel the deliveries are reported on.
+func (a *Accumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.deliveries()
+}
+
+func (a *Accumulator) deliveries() chan telegraf.DeliveryInfo {
+	a.Lock()
+	defer a.Unlock()
+	if a.delivered == nil {
+		a.delivered = make(chan telegraf.DeliveryInfo, 1000)
+	}
+	return a.delivered
+}
+
 func (a *Accumulator) Debug() bool {
 	// stub for implementing Accumulator interface.
 	return a.debug
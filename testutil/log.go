@@ -0,0 +1,27 @@
+package testutil
+
+import (
+	"log"
+)
+
+// Logger is a telegraf.Logger for the tests of plugins, which writes every
+// message with the standard logger, prefixed with its level and Name.
+type Logger struct {
+	Name string
+}
+
+func (l Logger) Errorf(format string, args ...interface{}) {
+	log.Printf("E! ["+l.Name+"] "+format, args...)
+}
+
+func (l Logger) Warnf(format string, args ...interface{}) {
+	log.Printf("W! ["+l.Name+"] "+format, args...)
+}
+
+func (l Logger) Infof(format string, args ...interface{}) {
+	log.Printf("I! ["+l.Name+"] "+format, args...)
+}
+
+func (l Logger) Debugf(format string, args ...interface{}) {
+	log.Printf("D! ["+l.Name+"] "+format, args...)
+}
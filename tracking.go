@@ -0,0 +1,138 @@
+package telegraf
+
+import "sync/atomic"
+
+// TrackingID identifies a group of metrics added with
+// TrackingAccumulator.AddTrackingMetricGroup.
+type TrackingID uint64
+
+// DeliveryInfo tells an input what became of a group of tracked metrics.
+type DeliveryInfo interface {
+	// ID returns the id of the group
+	ID() TrackingID
+
+	// Delivered is true if all the metrics of the group were written by every
+	// output they were added to, or filtered out. It is false if any of them
+	// was lost, ie, because an output dropped it after failed writes.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an accumulator that tells the input when the metrics
+// it added are done with, so that queue consumers can acknowledge a message
+// only once the metrics parsed from it are written.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds the metrics, like AddFields, and returns
+	// the id their delivery is reported with on Delivered. A group whose
+	// metrics are all filtered out is reported right away.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns the channel deliveries are reported on. It has room
+	// for maxTracked deliveries, so an input must read it, and keep at most
+	// maxTracked groups undelivered, or the outputs will block.
+	Delivered() <-chan DeliveryInfo
+}
+
+// lastTrackingID is the id of the last group of tracked metrics.
+var lastTrackingID uint64
+
+// NewTrackingMetricGroup returns the metrics of group tracked as one group, and
+// its id. notify is called with its delivery once every tracked metric, and
+// every holder added with Hold, is done with. If group is empty, it is called
+// right away.
+func NewTrackingMetricGroup(
+	group []Metric,
+	notify func(DeliveryInfo),
+) ([]Metric, TrackingID) {
+	d := &trackingData{
+		id:     TrackingID(atomic.AddUint64(&lastTrackingID, 1)),
+		refs:   int32(len(group)),
+		notify: notify,
+	}
+	if len(group) == 0 {
+		notify(d)
+		return group, d.id
+	}
+
+	tracked := make([]Metric, len(group))
+	for i, m := range group {
+		tracked[i] = &trackingMetric{Metric: m, d: d}
+	}
+	return tracked, d.id
+}
+
+// Hold adds n holders of the metric, if it is tracked, each of which must call
+// Accept, Reject or Drop once done with it. The agent uses it to give the
+// metric to every output.
+func Hold(m Metric, n int) {
+	if tm, ok := m.(*trackingMetric); ok {
+		atomic.AddInt32(&tm.d.refs, int32(n))
+	}
+}
+
+// Inherit adds the metrics that are not tracked to the group of m, if m is
+// tracked, so that the group is only reported once they are done with too. The
+// agent uses it for the metrics a processor returns in place of a tracked
+// metric. It returns the metrics, which are modified in place.
+func Inherit(m Metric, metrics []Metric) []Metric {
+	tm, ok := m.(*trackingMetric)
+	if !ok {
+		return metrics
+	}
+	for i, other := range metrics {
+		if _, ok := other.(*trackingMetric); !ok {
+			metrics[i] = &trackingMetric{Metric: other, d: tm.d}
+		}
+	}
+	return metrics
+}
+
+// trackingData is shared by the tracked metrics of a group, it counts how many
+// holders of them are not done yet.
+type trackingData struct {
+	id       TrackingID
+	refs     int32
+	rejected int32
+	notify   func(DeliveryInfo)
+}
+
+func (d *trackingData) ID() TrackingID {
+	return d.id
+}
+
+func (d *trackingData) Delivered() bool {
+	return atomic.LoadInt32(&d.rejected) == 0
+}
+
+// release is called by a holder done with a metric of the group.
+func (d *trackingData) release(rejected bool) {
+	if rejected {
+		atomic.StoreInt32(&d.rejected, 1)
+	}
+	if atomic.AddInt32(&d.refs, -1) == 0 {
+		d.notify(d)
+	}
+}
+
+// trackingMetric is a metric of a tracked group.
+type trackingMetric struct {
+	Metric
+	d *trackingData
+}
+
+func (m *trackingMetric) Copy() Metric {
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+func (m *trackingMetric) Accept() {
+	m.d.release(false)
+}
+
+func (m *trackingMetric) Reject() {
+	m.d.release(true)
+}
+
+func (m *trackingMetric) Drop() {
+	m.d.release(false)
+}
@@ -0,0 +1,80 @@
+package telegraf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTrackingGroup(t *testing.T, n int) ([]Metric, TrackingID, chan DeliveryInfo) {
+	var group []Metric
+	for i := 0; i < n; i++ {
+		m, err := NewMetric("cpu", nil, map[string]interface{}{"value": i}, time.Now())
+		require.NoError(t, err)
+		group = append(group, m)
+	}
+	delivered := make(chan DeliveryInfo, 1)
+	metrics, id := NewTrackingMetricGroup(group,
+		func(info DeliveryInfo) { delivered <- info })
+	return metrics, id, delivered
+}
+
+func TestTrackingMetricGroup(t *testing.T) {
+	metrics, id, delivered := newTrackingGroup(t, 2)
+	require.Len(t, metrics, 2)
+
+	// the first metric goes to two outputs, one of which writes a copy
+	Hold(metrics[0], 2)
+	metrics[0].Drop()
+	metrics[0].Copy().Accept()
+	metrics[0].Drop()
+	assert.Len(t, delivered, 0)
+
+	metrics[1].Accept()
+	require.Len(t, delivered, 1)
+	info := <-delivered
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
+func TestTrackingMetricGroupRejected(t *testing.T) {
+	metrics, _, delivered := newTrackingGroup(t, 2)
+
+	metrics[0].Reject()
+	assert.Len(t, delivered, 0)
+	metrics[1].Accept()
+	require.Len(t, delivered, 1)
+	assert.False(t, (<-delivered).Delivered())
+}
+
+func TestTrackingMetricGroupEmpty(t *testing.T) {
+	_, id, delivered := newTrackingGroup(t, 0)
+
+	require.Len(t, delivered, 1)
+	info := <-delivered
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
+func TestInherit(t *testing.T) {
+	metrics, _, delivered := newTrackingGroup(t, 1)
+	replacement, err := NewMetric("mem", nil, map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+
+	// a processor replaced the metric, which goes to one output
+	inherited := Inherit(metrics[0], []Metric{replacement})
+	require.Len(t, inherited, 1)
+	assert.Equal(t, "mem", inherited[0].Name())
+	Hold(inherited[0], 1)
+	metrics[0].Drop()
+	assert.Len(t, delivered, 0)
+
+	inherited[0].Reject()
+	require.Len(t, delivered, 1)
+	assert.False(t, (<-delivered).Delivered())
+
+	// metrics that are not tracked pass on nothing
+	assert.Equal(t, []Metric{replacement}, Inherit(replacement, []Metric{replacement}))
+}